@@ -1,5 +1,7 @@
 import { app, BrowserWindow } from 'electron';
 import { createMainWindow } from './window';
+import { log, cycleLogLevel } from '../lib/logger';
+import { reloadAppSettings } from '../settings';
 
 export function registerAppLifecycle() {
   app.on('window-all-closed', () => {
@@ -13,4 +15,39 @@ export function registerAppLifecycle() {
       createMainWindow();
     }
   });
+
+  registerSignalHandlers();
+}
+
+/**
+ * Signals the app handles besides SIGINT/SIGTERM, which keep their default (quit) behavior.
+ * SIGUSR1 is left alone because Node reserves it for starting the inspector.
+ */
+export const SIGNAL_ACTIONS: Readonly<Record<string, string>> = {
+  SIGHUP: 'reload settings from disk',
+  SIGUSR2: 'cycle the log level',
+};
+
+/** Reported to the renderer (app:getCapabilities); Windows has no POSIX signals. */
+export function signalCapabilities(): Record<string, string> {
+  return process.platform === 'win32' ? {} : { ...SIGNAL_ACTIONS };
+}
+
+function registerSignalHandlers() {
+  if (process.platform === 'win32') return;
+
+  process.on('SIGHUP', () => {
+    try {
+      reloadAppSettings();
+      BrowserWindow.getAllWindows().forEach((w) => w.webContents.send('settings:reloaded'));
+      log.info('SIGHUP: settings reloaded from disk');
+    } catch (error) {
+      log.error('SIGHUP: failed to reload settings', error);
+    }
+  });
+
+  process.on('SIGUSR2', () => {
+    const level = cycleLogLevel();
+    log.info(`SIGUSR2: log level is now ${level}`);
+  });
 }
@@ -1,8 +1,34 @@
-import { ipcMain, BrowserWindow } from 'electron';
-import { agentService } from '../services/AgentService';
+import { ipcMain, BrowserWindow, dialog } from 'electron';
+import { copyFile } from 'fs/promises';
+import path from 'path';
+import { agentService, type AgentRestartPolicy } from '../services/AgentService';
 import { codexService } from '../services/CodexService';
+import { artifactWatcher } from '../services/ArtifactWatcher';
+import { agentLogStore } from '../services/AgentLogStore';
+import { agentMetrics } from '../services/AgentMetrics';
+import { fanOutService, type FanOutRequest } from '../services/FanOutService';
+import { discoverProviders } from '../services/ProviderDiscovery';
+import { broadcastCritical } from '../services/DeadLetterStore';
+import { eventLog } from '../services/EventLog';
+import type { KillOptions } from '../lib/processKill';
+import type { ResourceLimits } from '../lib/resourceLimits';
+import { getAppSettings } from '../settings';
+
+/** Record a run that ended in error or timed out; stderr chunks along the way are not failures. */
+function recordAgentFailure(data: any) {
+  const reason =
+    data?.error ?? (data?.exitCode != null ? `exit code ${data.exitCode}` : 'unknown error');
+  eventLog.record({
+    kind: 'agent-failed',
+    severity: 'error',
+    message: `${data?.providerId ?? 'agent'} run failed: ${reason}`,
+    scope: { workspaceId: data?.workspaceId },
+  });
+}
 
 export function registerAgentIpc() {
+  agentMetrics.start();
+
   // Installation check
   ipcMain.handle('agent:check-installation', async (_e, providerId: 'codex' | 'claude') => {
     try {
@@ -13,6 +39,15 @@ export function registerAgentIpc() {
     }
   });
 
+  // Known agent CLIs found on PATH, for the provider picker
+  ipcMain.handle('agent:discover-providers', async () => {
+    try {
+      return { success: true, providers: await discoverProviders() };
+    } catch (e: any) {
+      return { success: false, error: e?.message || String(e) };
+    }
+  });
+
   // Installation instructions
   ipcMain.handle(
     'agent:get-installation-instructions',
@@ -32,16 +67,27 @@ export function registerAgentIpc() {
     async (
       _e,
       args: {
-        providerId: 'codex' | 'claude';
+        providerId?: 'codex' | 'claude';
         workspaceId: string;
         worktreePath: string;
         message: string;
         conversationId?: string;
+        envProfile?: string;
+        agentId?: string;
+        restartPolicy?: AgentRestartPolicy;
+        usePty?: boolean;
+        resourceLimits?: ResourceLimits;
+        queue?: boolean;
+        maxRuntimeMs?: number;
+        secrets?: Record<string, string>;
+        preset?: string;
+        requireApproval?: boolean;
       }
     ) => {
       try {
-        await agentService.startStream(args);
-        return { success: true };
+        artifactWatcher.watch(args.worktreePath, args.workspaceId);
+        const { messageId, queued } = await agentService.startStream(args);
+        return { success: true, messageId, queued };
       } catch (e: any) {
         return { success: false, error: e?.message || String(e) };
       }
@@ -51,9 +97,22 @@ export function registerAgentIpc() {
   // Stop streaming
   ipcMain.handle(
     'agent:stop-stream',
-    async (_e, args: { providerId: 'codex' | 'claude'; workspaceId: string }) => {
+    async (
+      _e,
+      args: {
+        providerId: 'codex' | 'claude';
+        workspaceId: string;
+        kill?: KillOptions;
+        agentId?: string;
+      }
+    ) => {
       try {
-        const ok = await agentService.stopStream(args.providerId, args.workspaceId);
+        const ok = await agentService.stopStream(
+          args.providerId,
+          args.workspaceId,
+          args.kill,
+          args.agentId
+        );
         return { success: ok };
       } catch (e: any) {
         return { success: false, error: e?.message || String(e) };
@@ -61,6 +120,185 @@ export function registerAgentIpc() {
     }
   );
 
+  // Cancel the current turn without ending the session
+  ipcMain.handle(
+    'agent:interrupt',
+    async (_e, args: { providerId: 'codex' | 'claude'; workspaceId: string; agentId?: string }) => {
+      try {
+        const interrupted = await agentService.interrupt(
+          args.providerId,
+          args.workspaceId,
+          args.agentId
+        );
+        return { success: true, interrupted };
+      } catch (e: any) {
+        return { success: false, error: e?.message || String(e) };
+      }
+    }
+  );
+
+  // Let a tool call held by an approval-mode run proceed, or deny it
+  ipcMain.handle(
+    'agent:approve-action',
+    async (_e, args: { approvalId: string; approved: boolean; message?: string }) => {
+      const found = agentService.approveAction(args.approvalId, !!args.approved, args.message);
+      if (!found) return { success: false, error: 'No pending approval with that id' };
+      return { success: true };
+    }
+  );
+
+  // Backfill output a renderer missed, e.g. after attaching to a run already in progress
+  ipcMain.handle(
+    'agent:get-logs',
+    async (
+      _e,
+      args: {
+        providerId: 'codex' | 'claude';
+        workspaceId: string;
+        offset?: number;
+        limit?: number;
+        agentId?: string;
+      }
+    ) => {
+      const page = agentService.getLogs(
+        args.providerId,
+        args.workspaceId,
+        args.offset,
+        args.limit,
+        args.agentId
+      );
+      if (!page) return { success: false, error: 'No output recorded for this agent' };
+      return { success: true, ...page };
+    }
+  );
+
+  // Windows that attached at least once; each is detached everywhere when it goes away
+  const trackedClients = new Set<number>();
+
+  // Follow a session (which keeps running regardless) and replay its buffered output
+  ipcMain.handle(
+    'agent:attach',
+    async (
+      e,
+      args: {
+        providerId: 'codex' | 'claude';
+        workspaceId: string;
+        fromSeq?: number;
+        agentId?: string;
+      }
+    ) => {
+      try {
+        const clientId = e.sender.id;
+        const res = agentService.attach(
+          args.providerId,
+          args.workspaceId,
+          clientId,
+          args.fromSeq,
+          args.agentId
+        );
+        if (!trackedClients.has(clientId)) {
+          trackedClients.add(clientId);
+          e.sender.once('destroyed', () => {
+            trackedClients.delete(clientId);
+            agentService.detachClient(clientId);
+          });
+        }
+        return { success: true, ...res };
+      } catch (err: any) {
+        return { success: false, error: err?.message || String(err) };
+      }
+    }
+  );
+
+  ipcMain.handle(
+    'agent:detach',
+    async (e, args: { providerId: 'codex' | 'claude'; workspaceId: string; agentId?: string }) => {
+      const attachedClients = agentService.detach(
+        args.providerId,
+        args.workspaceId,
+        e.sender.id,
+        args.agentId
+      );
+      return { success: true, attachedClients };
+    }
+  );
+
+  // Same prompt in several new worktrees; progress follows as agent:fan-out-progress
+  ipcMain.handle('agent:fan-out', async (_e, args: FanOutRequest) => {
+    try {
+      return { success: true, batch: fanOutService.start(args) };
+    } catch (e: any) {
+      return { success: false, error: e?.message || String(e) };
+    }
+  });
+
+  ipcMain.handle('agent:fan-out-status', async (_e, args: { batchId: string }) => {
+    const batch = fanOutService.get(args?.batchId);
+    if (!batch) return { success: false, error: 'Unknown fan-out batch' };
+    return { success: true, batch };
+  });
+
+  // On-disk output logs (current and rotated files) for post-mortems
+  ipcMain.handle(
+    'agent:list-log-files',
+    async (_e, args?: { providerId?: 'codex' | 'claude'; workspaceId?: string }) => {
+      try {
+        return { success: true, files: agentLogStore.list(args ?? {}) };
+      } catch (e: any) {
+        return { success: false, error: e?.message || String(e) };
+      }
+    }
+  );
+
+  ipcMain.handle('agent:export-log-file', async (e, args: { id: string }) => {
+    try {
+      const source = agentLogStore.resolve(args?.id);
+      if (!source) return { success: false, error: 'Unknown log file' };
+      const win = BrowserWindow.fromWebContents(e.sender);
+      const options = {
+        title: 'Save Agent Log',
+        defaultPath: args.id.split(path.sep).join('-'),
+      };
+      const result = win
+        ? await dialog.showSaveDialog(win, options)
+        : await dialog.showSaveDialog(options);
+      if (result.canceled || !result.filePath) return { success: false, error: 'Cancelled' };
+      await copyFile(source, result.filePath);
+      return { success: true, path: result.filePath };
+    } catch (err: any) {
+      return { success: false, error: err?.message || String(err) };
+    }
+  });
+
+  // Outcome and diff summary of a session's latest finished run
+  ipcMain.handle(
+    'agent:get-result',
+    async (_e, args: { providerId: 'codex' | 'claude'; workspaceId: string; agentId?: string }) => {
+      const result = agentService.getResult(args.providerId, args.workspaceId, args.agentId);
+      if (!result) return { success: false, error: 'No finished run for this agent' };
+      return { success: true, result };
+    }
+  );
+
+  // Current agent metrics in Prometheus text format (also served over HTTP when enabled)
+  ipcMain.handle('agent:metrics', async () => {
+    return { success: true, text: agentMetrics.registry.render() };
+  });
+
+  // Sessions per workspace, including named agents running side by side
+  ipcMain.handle('agent:list', async (_e, args?: { workspaceId?: string }) => {
+    return { success: true, agents: agentService.listAgents(args?.workspaceId) };
+  });
+
+  ipcMain.handle('agent:list-presets', async () => {
+    const presets = getAppSettings().agentPresets.presets.map((p) => ({
+      name: p.name,
+      providerId: p.providerId,
+      model: p.model || undefined,
+    }));
+    return { success: true, presets };
+  });
+
   // Bridge Codex native events to generic agent events so renderer can listen once
   codexService.on('codex:output', (data: any) => {
     const windows = BrowserWindow.getAllWindows();
@@ -68,17 +306,21 @@ export function registerAgentIpc() {
       w.webContents.send('agent:stream-output', { providerId: 'codex', ...data })
     );
   });
+  // Completions and terminal errors are critical: dead-letter them if no window can receive
+  // them. stderr chunks are ordinary output and go out without acks.
+  const forwardStreamError = (data: any) => {
+    if (data?.stream === 'stderr') {
+      const windows = BrowserWindow.getAllWindows();
+      windows.forEach((w) => w.webContents.send('agent:stream-error', data));
+    } else {
+      broadcastCritical('agent:stream-error', data);
+    }
+  };
   codexService.on('codex:error', (data: any) => {
-    const windows = BrowserWindow.getAllWindows();
-    windows.forEach((w) =>
-      w.webContents.send('agent:stream-error', { providerId: 'codex', ...data })
-    );
+    forwardStreamError({ providerId: 'codex', ...data });
   });
   codexService.on('codex:complete', (data: any) => {
-    const windows = BrowserWindow.getAllWindows();
-    windows.forEach((w) =>
-      w.webContents.send('agent:stream-complete', { providerId: 'codex', ...data })
-    );
+    broadcastCritical('agent:stream-complete', { providerId: 'codex', ...data });
   });
 
   // Forward AgentService events (Claude et al.)
@@ -86,13 +328,58 @@ export function registerAgentIpc() {
     const windows = BrowserWindow.getAllWindows();
     windows.forEach((w) => w.webContents.send('agent:stream-output', data));
   });
-  agentService.on('agent:error', (data: any) => {
+  agentService.on('agent:error', forwardStreamError);
+  agentService.on('agent:complete', (data: any) => {
+    broadcastCritical('agent:stream-complete', data);
+  });
+  agentService.on('agent:restarting', (data: any) => {
     const windows = BrowserWindow.getAllWindows();
-    windows.forEach((w) => w.webContents.send('agent:stream-error', data));
+    windows.forEach((w) => w.webContents.send('agent:restarting', data));
   });
-  agentService.on('agent:complete', (data: any) => {
+  agentService.on('agent:message-status', (data: any) => {
+    const windows = BrowserWindow.getAllWindows();
+    windows.forEach((w) => w.webContents.send('agent:message-status', data));
+  });
+  agentService.on('agent:timed-out', (data: any) => {
+    const windows = BrowserWindow.getAllWindows();
+    windows.forEach((w) => w.webContents.send('agent:timed-out', data));
+  });
+  agentService.on('agent:result', (data: any) => {
+    const windows = BrowserWindow.getAllWindows();
+    windows.forEach((w) => w.webContents.send('agent:result', data));
+  });
+  // Acknowledged like other critical events, but not worth a dead letter without a window
+  agentService.on('agent:status', (data: any) => {
+    if (data?.status === 'error' || data?.status === 'timed-out') recordAgentFailure(data);
+    broadcastCritical('agent:status', data, { deadLetter: false });
+  });
+  fanOutService.on('fanout:progress', (batch: any) => {
+    const windows = BrowserWindow.getAllWindows();
+    windows.forEach((w) => w.webContents.send('agent:fan-out-progress', batch));
+  });
+  // A run is blocked until someone answers, so requests are dead-lettered without a window
+  agentService.on('agent:approval-required', (data: any) => {
+    broadcastCritical('agent:approval-required', data);
+  });
+  agentService.on('agent:approval-resolved', (data: any) => {
+    const windows = BrowserWindow.getAllWindows();
+    windows.forEach((w) => w.webContents.send('agent:approval-resolved', data));
+  });
+  agentService.on('agent:interrupted', (data: any) => {
+    const windows = BrowserWindow.getAllWindows();
+    windows.forEach((w) => w.webContents.send('agent:interrupted', data));
+  });
+  agentService.on('agent:stall', (data: any) => {
+    const windows = BrowserWindow.getAllWindows();
+    windows.forEach((w) => w.webContents.send('agent:stall', data));
+  });
+  agentService.on('agent:event', (data: any) => {
+    const windows = BrowserWindow.getAllWindows();
+    windows.forEach((w) => w.webContents.send('agent:event', data));
+  });
+  agentService.on('agent:guardrail', (data: any) => {
     const windows = BrowserWindow.getAllWindows();
-    windows.forEach((w) => w.webContents.send('agent:stream-complete', data));
+    windows.forEach((w) => w.webContents.send('agent:guardrail', data));
   });
 
   // console.log('✅ Agent IPC handlers registered');
@@ -4,6 +4,8 @@ import { readFileSync } from 'fs';
 import { join } from 'path';
 import { ensureProjectPrepared } from '../services/ProjectPrep';
 import { getAppSettings } from '../settings';
+import { setPreferredLocale } from '../lib/errorCatalog';
+import { signalCapabilities } from '../app/lifecycle';
 
 export function registerAppIpc() {
   // Open external links in default browser
@@ -196,4 +198,12 @@ export function registerAppIpc() {
   });
   ipcMain.handle('app:getElectronVersion', () => process.versions.electron);
   ipcMain.handle('app:getPlatform', () => process.platform);
+  // Runtime controls outside the UI, e.g. which signals reload settings or cycle log level
+  ipcMain.handle('app:getCapabilities', () => ({ signals: signalCapabilities() }));
+
+  // Renderer language preferences (Accept-Language form) used to localize error messages
+  ipcMain.handle('app:setLocale', (_event, acceptLanguage: string | null) => {
+    setPreferredLocale(typeof acceptLanguage === 'string' ? acceptLanguage : null);
+    return { success: true };
+  });
 }
@@ -0,0 +1,34 @@
+import { ipcMain } from 'electron';
+import { acknowledgeDelivery, deadLetterStore } from '../services/DeadLetterStore';
+
+export function registerDeadLetterIpc() {
+  // A renderer handled a critical event; stop retrying it
+  ipcMain.on('critical:ack', (event, deliveryId: string) => {
+    acknowledgeDelivery(event.sender.id, String(deliveryId));
+  });
+
+  ipcMain.handle('deadletter:list', async () => {
+    try {
+      return { success: true, letters: deadLetterStore.list() };
+    } catch (e: any) {
+      return { success: false, error: e?.message || String(e) };
+    }
+  });
+
+  // Re-emit dead letters to open windows; all of them when no ids are given
+  ipcMain.handle('deadletter:redeliver', async (_event, args?: { ids?: string[] }) => {
+    try {
+      return { success: true, ...deadLetterStore.redeliver(args?.ids) };
+    } catch (e: any) {
+      return { success: false, error: e?.message || String(e) };
+    }
+  });
+
+  ipcMain.handle('deadletter:discard', async (_event, args?: { ids?: string[] }) => {
+    try {
+      return { success: true, discarded: deadLetterStore.discard(args?.ids) };
+    } catch (e: any) {
+      return { success: false, error: e?.message || String(e) };
+    }
+  });
+}
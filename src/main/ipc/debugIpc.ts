@@ -1,8 +1,30 @@
 import { ipcMain } from 'electron';
 import { dirname } from 'path';
 import * as fs from 'fs';
+import { eventLog, ServerEventKind } from '../services/EventLog';
 
 export function registerDebugIpc() {
+  // Recent significant main-process events, optionally scoped to a workspace/worktree/terminal
+  ipcMain.handle(
+    'debug:recent-events',
+    async (
+      _,
+      args: {
+        limit?: number;
+        workspaceId?: string;
+        worktreePath?: string;
+        ptyId?: string;
+        kinds?: ServerEventKind[];
+      } = {}
+    ) => {
+      try {
+        return { success: true, events: eventLog.recent(args || {}) };
+      } catch (error) {
+        return { success: false, error: error instanceof Error ? error.message : 'Unknown error' };
+      }
+    }
+  );
+
   ipcMain.handle(
     'debug:append-log',
     async (_, filePath: string, content: string, options: { reset?: boolean } = {}) => {
@@ -1,15 +1,54 @@
 import { ipcMain } from 'electron';
 import { log } from '../lib/logger';
-import { exec } from 'child_process';
+import { exec, execFile } from 'child_process';
+import { copyFile, mkdtemp, rm } from 'fs/promises';
+import os from 'os';
+import path from 'path';
 import { promisify } from 'util';
 import {
   getStatus as gitGetStatus,
   getFileDiff as gitGetFileDiff,
   stageFile as gitStageFile,
   revertFile as gitRevertFile,
+  generateCommitMessage as gitGenerateCommitMessage,
 } from '../services/GitService';
 
 const execAsync = promisify(exec);
+const execFileAsync = promisify(execFile);
+
+async function stageWorkspaceChanges(workspacePath: string, env?: NodeJS.ProcessEnv) {
+  await execAsync('git add -A', { cwd: workspacePath, env });
+  // Never stage plan mode artifacts
+  try {
+    await execAsync('git reset -q .emdash || true', { cwd: workspacePath, env });
+  } catch {}
+  try {
+    await execAsync('git reset -q PLANNING.md || true', { cwd: workspacePath, env });
+  } catch {}
+  try {
+    await execAsync('git reset -q planning.md || true', { cwd: workspacePath, env });
+  } catch {}
+}
+
+/**
+ * Generate a message for what the commit would include by staging into a throwaway copy of
+ * the index, so the user's own index is untouched if they reject the proposal.
+ */
+async function proposeCommitMessage(workspacePath: string): Promise<string | null> {
+  const { stdout } = await execAsync('git rev-parse --git-path index', { cwd: workspacePath });
+  const index = path.resolve(workspacePath, stdout.trim());
+  const tmpDir = await mkdtemp(path.join(os.tmpdir(), 'emdash-index-'));
+  try {
+    const tmpIndex = path.join(tmpDir, 'index');
+    // A repository without commits may not have an index yet
+    await copyFile(index, tmpIndex).catch(() => {});
+    const env = { ...process.env, GIT_INDEX_FILE: tmpIndex };
+    await stageWorkspaceChanges(workspacePath, env);
+    return await gitGenerateCommitMessage(workspacePath, env);
+  } finally {
+    await rm(tmpDir, { recursive: true, force: true }).catch(() => {});
+  }
+}
 
 export function registerGitIpc() {
   // Git: Status (moved from Codex IPC)
@@ -307,30 +346,55 @@ current branch '${currentBranch}' ahead of base '${baseRef}'.`,
         commitMessage?: string;
         createBranchIfOnDefault?: boolean;
         branchPrefix?: string;
+        autoCommitGenerated?: boolean;
       }
     ) => {
       const {
         workspacePath,
-        commitMessage = 'chore: apply workspace changes',
+        commitMessage: requestedMessage,
         createBranchIfOnDefault = true,
         branchPrefix = 'orch',
+        autoCommitGenerated = false,
       } = (args ||
         ({} as {
           workspacePath: string;
           commitMessage?: string;
           createBranchIfOnDefault?: boolean;
           branchPrefix?: string;
+          autoCommitGenerated?: boolean;
         })) as {
         workspacePath: string;
         commitMessage?: string;
         createBranchIfOnDefault?: boolean;
         branchPrefix?: string;
+        autoCommitGenerated?: boolean;
       };
 
       try {
         // Ensure we're in a git repo
         await execAsync('git rev-parse --is-inside-work-tree', { cwd: workspacePath });
 
+        let commitMessage = (requestedMessage || '').trim();
+        if (!commitMessage) {
+          // Empty message: ask the configured generator for one based on the staged diff
+          let generated: string | null = null;
+          try {
+            if (autoCommitGenerated) {
+              await stageWorkspaceChanges(workspacePath);
+              generated = await gitGenerateCommitMessage(workspacePath);
+            } else {
+              generated = await proposeCommitMessage(workspacePath);
+            }
+          } catch (genErr) {
+            log.warn('Commit message generation failed:', genErr as string);
+          }
+          if (generated && !autoCommitGenerated) {
+            // Hand the proposal back for confirmation; nothing is committed or pushed yet
+            return { success: true, proposedMessage: generated, committed: false };
+          }
+          commitMessage = generated || 'chore: apply workspace changes';
+        }
+
         // Determine current branch
         const { stdout: currentBranchOut } = await execAsync('git branch --show-current', {
           cwd: workspacePath,
@@ -370,23 +434,12 @@ current branch '${currentBranch}' ahead of base '${baseRef}'.`,
         try {
           const { stdout: st } = await execAsync('git status --porcelain', { cwd: workspacePath });
           if (st && st.trim().length > 0) {
-            await execAsync('git add -A', { cwd: workspacePath });
-            // Never stage plan mode artifacts
-            try {
-              await execAsync('git reset -q .emdash || true', { cwd: workspacePath });
-            } catch {}
-            try {
-              await execAsync('git reset -q PLANNING.md || true', { cwd: workspacePath });
-            } catch {}
+            await stageWorkspaceChanges(workspacePath);
             try {
-              await execAsync('git reset -q planning.md || true', { cwd: workspacePath });
-            } catch {}
-            try {
-              await execAsync(`git commit -m ${JSON.stringify(commitMessage)}`, {
-                cwd: workspacePath,
-              });
-            } catch (commitErr) {
-              const msg = commitErr as string;
+              // No shell: generated messages routinely contain backticks and $(...)
+              await execFileAsync('git', ['commit', '-m', commitMessage], { cwd: workspacePath });
+            } catch (commitErr: any) {
+              const msg = String(commitErr?.stdout || '') + String(commitErr);
               if (!/nothing to commit/i.test(msg)) throw commitErr;
             }
           }
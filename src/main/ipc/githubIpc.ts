@@ -6,16 +6,13 @@ import { exec } from 'child_process';
 import { promisify } from 'util';
 import * as path from 'path';
 import * as fs from 'fs';
+import { slugify as slugifyWith } from '../lib/branchNaming';
+import { getAppSettings } from '../settings';
 
 const execAsync = promisify(exec);
 const githubService = new GitHubService();
 
-const slugify = (name: string) =>
-  name
-    .toLowerCase()
-    .replace(/[^a-z0-9-]/g, '-')
-    .replace(/-+/g, '-')
-    .replace(/^-|-$/g, '');
+const slugify = (name: string) => slugifyWith(name, getAppSettings().repository.slugStrategy);
 
 export function registerGithubIpc() {
   ipcMain.handle('github:connect', async (_, projectPath: string) => {
@@ -18,6 +18,9 @@ import { registerJiraIpc } from './jiraIpc';
 import { registerPlanLockIpc } from '../services/planLockIpc';
 import { registerSettingsIpc } from './settingsIpc';
 import { registerContainerIpc } from './containerIpc';
+import { registerDeadLetterIpc } from './deadLetterIpc';
+import { registerSecretsIpc } from './secretsIpc';
+import { registerNotificationIpc } from './notificationIpc';
 
 export function registerAllIpc() {
   // Core app/utility IPC
@@ -26,6 +29,9 @@ export function registerAllIpc() {
   registerTelemetryIpc();
   registerUpdateIpc();
   registerSettingsIpc();
+  registerDeadLetterIpc();
+  registerSecretsIpc();
+  registerNotificationIpc();
 
   // Domain IPC
   registerProjectIpc();
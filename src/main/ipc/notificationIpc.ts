@@ -0,0 +1,15 @@
+import { ipcMain } from 'electron';
+import { notificationService } from '../services/NotificationService';
+
+export function registerNotificationIpc() {
+  notificationService.start();
+
+  // Notifications sent before this window was listening, most recent first
+  ipcMain.handle('notifications:recent', async (_event, args?: { limit?: number }) => {
+    try {
+      return { success: true, notifications: notificationService.recent(args?.limit) };
+    } catch (e: any) {
+      return { success: false, error: e?.message || String(e) };
+    }
+  });
+}
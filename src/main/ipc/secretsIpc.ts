@@ -0,0 +1,31 @@
+import { ipcMain } from 'electron';
+import { secretStore } from '../services/SecretStore';
+
+// Values go in but never come back out; agents reference secrets by name
+export function registerSecretsIpc() {
+  ipcMain.handle('secrets:list', async () => {
+    try {
+      return { success: true, names: await secretStore.listNames() };
+    } catch (e: any) {
+      return { success: false, error: e?.message || String(e) };
+    }
+  });
+
+  ipcMain.handle('secrets:set', async (_e, args: { name: string; value: string }) => {
+    try {
+      await secretStore.set(args?.name, args?.value);
+      return { success: true };
+    } catch (e: any) {
+      return { success: false, error: e?.message || String(e) };
+    }
+  });
+
+  ipcMain.handle('secrets:delete', async (_e, args: { name: string }) => {
+    try {
+      const deleted = await secretStore.delete(args?.name);
+      return { success: true, deleted };
+    } catch (e: any) {
+      return { success: false, error: e?.message || String(e) };
+    }
+  });
+}
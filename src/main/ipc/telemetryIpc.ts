@@ -4,6 +4,7 @@ import {
   isTelemetryEnabled,
   getTelemetryStatus,
   setTelemetryEnabledViaUser,
+  getTelemetryPayloadPreview,
 } from '../telemetry';
 
 export function registerTelemetryIpc() {
@@ -31,6 +32,15 @@ export function registerTelemetryIpc() {
     }
   });
 
+  // Transparency: show the exact (sanitized) payloads telemetry sends
+  ipcMain.handle('telemetry:show-payload', async () => {
+    try {
+      return { success: true, status: getTelemetryStatus(), ...getTelemetryPayloadPreview() };
+    } catch (e: any) {
+      return { success: false, error: e?.message || 'preview_failed' };
+    }
+  });
+
   ipcMain.handle('telemetry:set-enabled', async (_event, enabled: boolean) => {
     try {
       setTelemetryEnabledViaUser(Boolean(enabled));
@@ -0,0 +1,129 @@
+import { execFile } from 'child_process';
+import { promisify } from 'util';
+import os from 'os';
+import crypto from 'crypto';
+
+const execFileAsync = promisify(execFile);
+
+export type SlugStrategy = (input: string) => string;
+
+const collapse = (s: string) => s.replace(/-+/g, '-').replace(/^-|-$/g, '');
+
+/**
+ * Built-in slug strategies:
+ * - ascii: lowercase a-z0-9 only (the historical behaviour; non-ASCII is dropped)
+ * - transliterate: strip accents first so "Café Überblick" becomes "cafe-uberblick"
+ * - unicode: keep letters and digits from any script, e.g. "修复-登录"
+ */
+const strategies = new Map<string, SlugStrategy>([
+  ['ascii', (s) => collapse(s.toLowerCase().replace(/[^a-z0-9-]/g, '-'))],
+  [
+    'transliterate',
+    (s) =>
+      collapse(
+        s
+          .normalize('NFKD')
+          .replace(/\p{M}+/gu, '')
+          .toLowerCase()
+          .replace(/[^a-z0-9-]/g, '-')
+      ),
+  ],
+  [
+    'unicode',
+    (s) =>
+      collapse(
+        s
+          .normalize('NFKC')
+          .toLowerCase()
+          .replace(/[^\p{L}\p{N}-]/gu, '-')
+      ),
+  ],
+]);
+
+export function registerSlugStrategy(name: string, strategy: SlugStrategy) {
+  strategies.set(name, strategy);
+}
+
+export function listSlugStrategies(): string[] {
+  return Array.from(strategies.keys());
+}
+
+export function slugify(input: string, strategy = 'ascii'): string {
+  const fn = strategies.get(strategy) ?? strategies.get('ascii')!;
+  return fn(input);
+}
+
+export interface BranchTemplateVars {
+  slug: string;
+  timestamp: string;
+  user?: string;
+  ticket?: string;
+  date?: string;
+  shortid?: string;
+}
+
+export const BRANCH_TEMPLATE_VARS: Array<keyof BranchTemplateVars> = [
+  'slug',
+  'timestamp',
+  'user',
+  'ticket',
+  'date',
+  'shortid',
+];
+
+/**
+ * Pull a ticket key such as "ABC-123" or "#456" out of a workspace name.
+ */
+export function extractTicket(name: string): string | undefined {
+  const jira = name.match(/\b([A-Z][A-Z0-9]+-\d+)\b/);
+  if (jira) return jira[1];
+  const issue = name.match(/#(\d+)\b/);
+  return issue ? issue[1] : undefined;
+}
+
+/**
+ * Resolve the author handle used for `{user}`: git user.name, then the OS account name.
+ */
+export async function resolveBranchUser(cwd: string, strategy = 'ascii'): Promise<string> {
+  try {
+    const { stdout } = await execFileAsync('git', ['config', 'user.name'], { cwd });
+    const slug = slugify(stdout.trim(), strategy);
+    if (slug) return slug;
+  } catch {}
+  try {
+    return slugify(os.userInfo().username, strategy) || 'user';
+  } catch {
+    return 'user';
+  }
+}
+
+/**
+ * Substitute `{var}` placeholders. Unknown placeholders and variables without a value are
+ * removed, and separators left dangling by empty values are tidied up.
+ */
+export function renderBranchTemplate(template: string, vars: BranchTemplateVars): string {
+  const values: Record<string, string | undefined> = {
+    date: new Date().toISOString().slice(0, 10).replace(/-/g, ''),
+    shortid: crypto.randomBytes(3).toString('hex'),
+    ...vars,
+  };
+  return template
+    .replace(/\{(\w+)\}/g, (_m, key: string) => values[key] ?? '')
+    .replace(/\/{2,}/g, '/')
+    .replace(/\/[-_.]+/g, '/')
+    .replace(/[-_.]+\//g, '/')
+    .replace(/-{2,}/g, '-');
+}
+
+/**
+ * Validate a branch name with `git check-ref-format --branch`.
+ */
+export async function isValidBranchName(name: string, cwd?: string): Promise<boolean> {
+  if (!name) return false;
+  try {
+    await execFileAsync('git', ['check-ref-format', '--branch', name], { cwd });
+    return true;
+  } catch {
+    return false;
+  }
+}
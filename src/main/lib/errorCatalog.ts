@@ -0,0 +1,163 @@
+import { app } from 'electron';
+
+/**
+ * Stable codes for user-facing errors. Renderers can branch on the code; the message is
+ * localized per request while logs and thrown errors keep the English text.
+ */
+export type ErrorCode =
+  | 'WORKTREE_EXISTS'
+  | 'WORKTREE_NOT_FOUND'
+  | 'WORKTREE_DIRTY'
+  | 'PROJECT_DIRTY'
+  | 'BRANCH_NOT_MERGED'
+  | 'INVALID_BRANCH_NAME'
+  | 'INVALID_REBASE_PLAN'
+  | 'UNKNOWN';
+
+type Params = Record<string, string | number | undefined>;
+type Catalog = Record<ErrorCode, string>;
+
+const CATALOGS: Record<string, Catalog> = {
+  en: {
+    WORKTREE_EXISTS: 'A worktree already exists at {path}.',
+    WORKTREE_NOT_FOUND: 'Worktree not found.',
+    WORKTREE_DIRTY: 'The worktree has uncommitted changes. Commit or stash them first.',
+    PROJECT_DIRTY: 'The project checkout has uncommitted changes. Commit or stash them first.',
+    BRANCH_NOT_MERGED: 'Branch {branch} is not merged into {base}.',
+    INVALID_BRANCH_NAME: 'Branch name "{branch}" is not a valid Git branch name.',
+    INVALID_REBASE_PLAN: 'The rebase plan is invalid: {detail}',
+    UNKNOWN: 'Something went wrong.',
+  },
+  de: {
+    WORKTREE_EXISTS: 'Unter {path} existiert bereits ein Worktree.',
+    WORKTREE_NOT_FOUND: 'Worktree nicht gefunden.',
+    WORKTREE_DIRTY:
+      'Der Worktree enthält nicht committete Änderungen. Bitte zuerst committen oder stashen.',
+    PROJECT_DIRTY:
+      'Das Projekt enthält nicht committete Änderungen. Bitte zuerst committen oder stashen.',
+    BRANCH_NOT_MERGED: 'Branch {branch} ist nicht in {base} gemergt.',
+    INVALID_BRANCH_NAME: '„{branch}“ ist kein gültiger Git-Branchname.',
+    INVALID_REBASE_PLAN: 'Der Rebase-Plan ist ungültig: {detail}',
+    UNKNOWN: 'Etwas ist schiefgelaufen.',
+  },
+  es: {
+    WORKTREE_EXISTS: 'Ya existe un worktree en {path}.',
+    WORKTREE_NOT_FOUND: 'No se encontró el worktree.',
+    WORKTREE_DIRTY:
+      'El worktree tiene cambios sin confirmar. Haz commit o stash de ellos primero.',
+    PROJECT_DIRTY: 'El proyecto tiene cambios sin confirmar. Haz commit o stash de ellos primero.',
+    BRANCH_NOT_MERGED: 'La rama {branch} no está fusionada en {base}.',
+    INVALID_BRANCH_NAME: '"{branch}" no es un nombre de rama de Git válido.',
+    INVALID_REBASE_PLAN: 'El plan de rebase no es válido: {detail}',
+    UNKNOWN: 'Algo salió mal.',
+  },
+  fr: {
+    WORKTREE_EXISTS: 'Un worktree existe déjà à l’emplacement {path}.',
+    WORKTREE_NOT_FOUND: 'Worktree introuvable.',
+    WORKTREE_DIRTY:
+      'Le worktree contient des modifications non commitées. Commitez-les ou remisez-les d’abord.',
+    PROJECT_DIRTY:
+      'Le projet contient des modifications non commitées. Commitez-les ou remisez-les d’abord.',
+    BRANCH_NOT_MERGED: 'La branche {branch} n’est pas fusionnée dans {base}.',
+    INVALID_BRANCH_NAME: '« {branch} » n’est pas un nom de branche Git valide.',
+    INVALID_REBASE_PLAN: 'Le plan de rebase est invalide : {detail}',
+    UNKNOWN: 'Une erreur est survenue.',
+  },
+  ja: {
+    WORKTREE_EXISTS: '{path} には既にワークツリーが存在します。',
+    WORKTREE_NOT_FOUND: 'ワークツリーが見つかりません。',
+    WORKTREE_DIRTY:
+      'ワークツリーに未コミットの変更があります。先にコミットまたはスタッシュしてください。',
+    PROJECT_DIRTY:
+      'プロジェクトに未コミットの変更があります。先にコミットまたはスタッシュしてください。',
+    BRANCH_NOT_MERGED: 'ブランチ {branch} は {base} にマージされていません。',
+    INVALID_BRANCH_NAME: '「{branch}」は有効な Git ブランチ名ではありません。',
+    INVALID_REBASE_PLAN: 'リベース計画が無効です: {detail}',
+    UNKNOWN: '問題が発生しました。',
+  },
+  zh: {
+    WORKTREE_EXISTS: '{path} 处已存在工作树。',
+    WORKTREE_NOT_FOUND: '未找到工作树。',
+    WORKTREE_DIRTY: '工作树中有未提交的更改，请先提交或暂存。',
+    PROJECT_DIRTY: '项目中有未提交的更改，请先提交或暂存。',
+    BRANCH_NOT_MERGED: '分支 {branch} 尚未合并到 {base}。',
+    INVALID_BRANCH_NAME: '“{branch}”不是有效的 Git 分支名。',
+    INVALID_REBASE_PLAN: '变基计划无效：{detail}',
+    UNKNOWN: '出现了问题。',
+  },
+};
+
+let preferredLocale: string | null = null;
+
+function format(template: string, params: Params = {}): string {
+  return template.replace(/\{(\w+)\}/g, (m, key: string) =>
+    params[key] !== undefined ? String(params[key]) : m
+  );
+}
+
+/**
+ * Error carrying a stable code and the params needed to localize it. `message` is English.
+ */
+export class AppError extends Error {
+  readonly code: ErrorCode;
+  readonly params: Params;
+
+  constructor(code: ErrorCode, params: Params = {}) {
+    super(format(CATALOGS.en[code], params));
+    this.name = 'AppError';
+    this.code = code;
+    this.params = params;
+  }
+}
+
+/**
+ * Set the renderer's preferred languages, in Accept-Language form (e.g. "de-DE,de;q=0.9,en").
+ */
+export function setPreferredLocale(locale: string | null) {
+  preferredLocale = locale && locale.trim() ? locale.trim() : null;
+}
+
+/**
+ * Pick the best supported catalog for an Accept-Language style list, falling back to the
+ * preferred locale, the OS locale and finally English.
+ */
+export function resolveLocale(acceptLanguage?: string | null): string {
+  let systemLocale = '';
+  try {
+    systemLocale = app.getLocale();
+  } catch {}
+  const candidates = [acceptLanguage, preferredLocale, systemLocale]
+    .filter((v): v is string => !!v)
+    .flatMap((list) =>
+      list
+        .split(',')
+        .map((part) => {
+          const [tag, q] = part.trim().split(';q=');
+          return { tag: tag.toLowerCase(), q: q === undefined ? 1 : Number(q) || 0 };
+        })
+        .filter((c) => c.tag && c.q > 0)
+        .sort((a, b) => b.q - a.q)
+    );
+  for (const { tag } of candidates) {
+    const lang = tag.split(/[-_]/)[0];
+    if (CATALOGS[lang]) return lang;
+  }
+  return 'en';
+}
+
+export function localizeError(code: ErrorCode, params: Params = {}, locale?: string): string {
+  const catalog = CATALOGS[resolveLocale(locale)] ?? CATALOGS.en;
+  return format(catalog[code] ?? CATALOGS.en[code], params);
+}
+
+/**
+ * Shape an error for an IPC response: `error` is localized for coded errors and the original
+ * (English) text otherwise, `code` is always set.
+ */
+export function toUserError(error: unknown, locale?: string): { error: string; code: ErrorCode } {
+  if (error instanceof AppError) {
+    return { error: localizeError(error.code, error.params, locale), code: error.code };
+  }
+  const message = error instanceof Error ? error.message : String(error);
+  return { error: message, code: 'UNKNOWN' };
+}
@@ -12,7 +12,18 @@ function enabled(target: Level, current: Level): boolean {
   return order[target] >= order[current];
 }
 
-const current = envLevel();
+let current = envLevel();
+
+const levels: Level[] = ['debug', 'info', 'warn', 'error'];
+
+/**
+ * Step to the next log level (debug -> info -> warn -> error -> debug).
+ * Returns the new level.
+ */
+export function cycleLogLevel(): Level {
+  current = levels[(levels.indexOf(current) + 1) % levels.length];
+  return current;
+}
 
 export const log = {
   debug: (...args: any[]) => {
@@ -0,0 +1,155 @@
+/**
+ * Minimal Prometheus text-format (0.0.4) metrics: labelled counters, gauges and histograms.
+ * Enough for the handful of series the app exports without pulling in prom-client.
+ */
+
+type Labels = Record<string, string>;
+
+const DEFAULT_BUCKETS = [1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600];
+
+function labelKey(labels: Labels): string {
+  return Object.keys(labels)
+    .sort()
+    .map((k) => `${k}="${escapeLabel(labels[k])}"`)
+    .join(',');
+}
+
+function escapeLabel(value: string): string {
+  return String(value).replace(/\\/g, '\\\\').replace(/\n/g, '\\n').replace(/"/g, '\\"');
+}
+
+function series(name: string, key: string, extra = ''): string {
+  const inner = [key, extra].filter(Boolean).join(',');
+  return inner ? `${name}{${inner}}` : name;
+}
+
+function formatNumber(n: number): string {
+  if (n === Infinity) return '+Inf';
+  return Number.isInteger(n) ? String(n) : String(Number(n.toPrecision(12)));
+}
+
+interface Metric {
+  readonly name: string;
+  render(): string;
+}
+
+export class Counter implements Metric {
+  readonly name: string;
+  private readonly help: string;
+  private values = new Map<string, number>();
+
+  constructor(name: string, help: string) {
+    this.name = name;
+    this.help = help;
+  }
+
+  inc(labels: Labels = {}, by = 1) {
+    if (!(by >= 0)) return;
+    const key = labelKey(labels);
+    this.values.set(key, (this.values.get(key) ?? 0) + by);
+  }
+
+  render(): string {
+    const lines = [`# HELP ${this.name} ${this.help}`, `# TYPE ${this.name} counter`];
+    for (const [key, value] of this.values) {
+      lines.push(`${series(this.name, key)} ${formatNumber(value)}`);
+    }
+    return lines.join('\n');
+  }
+}
+
+export class Gauge implements Metric {
+  readonly name: string;
+  private readonly help: string;
+  private values = new Map<string, number>();
+
+  constructor(name: string, help: string) {
+    this.name = name;
+    this.help = help;
+  }
+
+  set(labels: Labels, value: number) {
+    if (!Number.isFinite(value)) return;
+    this.values.set(labelKey(labels), value);
+  }
+
+  render(): string {
+    const lines = [`# HELP ${this.name} ${this.help}`, `# TYPE ${this.name} gauge`];
+    for (const [key, value] of this.values) {
+      lines.push(`${series(this.name, key)} ${formatNumber(value)}`);
+    }
+    return lines.join('\n');
+  }
+}
+
+export class Histogram implements Metric {
+  readonly name: string;
+  private readonly help: string;
+  private readonly buckets: number[];
+  private values = new Map<string, { counts: number[]; sum: number; count: number }>();
+
+  constructor(name: string, help: string, buckets: number[] = DEFAULT_BUCKETS) {
+    this.name = name;
+    this.help = help;
+    this.buckets = buckets;
+  }
+
+  observe(labels: Labels, value: number) {
+    if (!Number.isFinite(value)) return;
+    const key = labelKey(labels);
+    let entry = this.values.get(key);
+    if (!entry) {
+      entry = { counts: this.buckets.map(() => 0), sum: 0, count: 0 };
+      this.values.set(key, entry);
+    }
+    this.buckets.forEach((le, i) => {
+      if (value <= le) entry!.counts[i]++;
+    });
+    entry.sum += value;
+    entry.count++;
+  }
+
+  render(): string {
+    const lines = [`# HELP ${this.name} ${this.help}`, `# TYPE ${this.name} histogram`];
+    for (const [key, entry] of this.values) {
+      this.buckets.forEach((le, i) => {
+        const bucket = series(`${this.name}_bucket`, key, `le="${formatNumber(le)}"`);
+        lines.push(`${bucket} ${entry.counts[i]}`);
+      });
+      lines.push(`${series(`${this.name}_bucket`, key, 'le="+Inf"')} ${entry.count}`);
+      lines.push(`${series(`${this.name}_sum`, key)} ${formatNumber(entry.sum)}`);
+      lines.push(`${series(`${this.name}_count`, key)} ${entry.count}`);
+    }
+    return lines.join('\n');
+  }
+}
+
+export class MetricsRegistry {
+  private metrics: Metric[] = [];
+
+  counter(name: string, help: string): Counter {
+    const metric = new Counter(name, help);
+    this.metrics.push(metric);
+    return metric;
+  }
+
+  gauge(name: string, help: string): Gauge {
+    const metric = new Gauge(name, help);
+    this.metrics.push(metric);
+    return metric;
+  }
+
+  histogram(name: string, help: string, buckets?: number[]): Histogram {
+    const metric = new Histogram(name, help, buckets);
+    this.metrics.push(metric);
+    return metric;
+  }
+
+  /** Exposition text for every registered metric, ready to serve as text/plain. */
+  render(): string {
+    return this.metrics.map((m) => m.render()).join('\n') + '\n';
+  }
+}
+
+// Served by the metrics endpoint; services register their series on it
+export const metricsRegistry = new MetricsRegistry();
@@ -0,0 +1,23 @@
+// OSC 0 (icon name + title) and OSC 2 (title), terminated by BEL or ST (ESC \)
+const TITLE_RE = /\x1b\]([02]);([^\x07\x1b]*)(?:\x07|\x1b\\)/g;
+const MAX_PENDING = 1024;
+
+/**
+ * Scan terminal output for title sequences. `pending` is the unterminated tail returned by
+ * the previous call so sequences split across chunks are still recognised.
+ */
+export function scanOscTitles(
+  pending: string,
+  chunk: string
+): { titles: string[]; pending: string } {
+  const text = pending + chunk;
+  const titles: string[] = [];
+  let end = 0;
+  for (const match of text.matchAll(TITLE_RE)) {
+    titles.push(match[2]);
+    end = match.index! + match[0].length;
+  }
+  const open = text.lastIndexOf('\x1b]');
+  const rest = open >= end ? text.slice(open) : '';
+  return { titles, pending: rest.length > MAX_PENDING ? '' : rest };
+}
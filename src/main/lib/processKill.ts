@@ -0,0 +1,61 @@
+import { getAppSettings } from '../settings';
+
+export const KILL_SIGNALS = ['SIGTERM', 'SIGINT', 'SIGHUP', 'SIGQUIT', 'SIGKILL'] as const;
+export type KillSignal = (typeof KILL_SIGNALS)[number];
+
+export interface KillOptions {
+  signal?: KillSignal;
+  graceMs?: number; // escalate to SIGKILL if still running after this long; 0 disables
+  processGroup?: boolean; // signal the process group the process leads (POSIX only)
+}
+
+interface KillTarget {
+  pid?: number;
+  kill: (signal?: string) => void;
+  exited: () => boolean;
+}
+
+/**
+ * Merge per-request options over settings.processKill. `defaultSignal` applies when neither
+ * names a signal, so each session type keeps its historical first signal.
+ */
+export function resolveKillOptions(
+  defaultSignal: KillSignal,
+  overrides: KillOptions = {}
+): Required<KillOptions> {
+  const configured = getAppSettings().processKill;
+  const pick = (s: unknown) => (KILL_SIGNALS.includes(s as KillSignal) ? (s as KillSignal) : null);
+  const graceMs = Number(overrides.graceMs ?? configured.graceMs);
+  return {
+    signal: pick(overrides.signal) ?? pick(configured.signal) ?? defaultSignal,
+    graceMs: Number.isFinite(graceMs) && graceMs > 0 ? graceMs : 0,
+    processGroup: Boolean(overrides.processGroup ?? configured.processGroup),
+  };
+}
+
+/**
+ * Send the initial signal and, after the grace period, SIGKILL if the process is still alive.
+ */
+export function terminateProcess(target: KillTarget, options: Required<KillOptions>): void {
+  const send = (signal: KillSignal) => {
+    if (options.processGroup && target.pid && process.platform !== 'win32') {
+      try {
+        process.kill(-target.pid, signal);
+        return;
+      } catch {
+        // Not a group leader (or already gone); fall back to the process itself
+      }
+    }
+    target.kill(signal);
+  };
+
+  send(options.signal);
+  if (options.signal === 'SIGKILL' || options.graceMs <= 0) return;
+  const timer = setTimeout(() => {
+    if (target.exited()) return;
+    try {
+      send('SIGKILL');
+    } catch {}
+  }, options.graceMs);
+  timer.unref?.();
+}
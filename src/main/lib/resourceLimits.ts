@@ -0,0 +1,156 @@
+import { execFile } from 'child_process';
+import { readFile } from 'fs/promises';
+
+export interface ResourceLimits {
+  maxMemoryMb?: number; // resident memory of the agent process
+  maxCpuPercent?: number; // percent of one core, e.g. 200 allows two cores
+}
+
+const SAMPLE_INTERVAL_MS = 2000;
+// Consecutive over-limit CPU samples before the process is killed; memory kills immediately
+const CPU_STRIKES = 3;
+// USER_HZ; 100 on every mainstream Linux build
+const CLOCK_TICKS = 100;
+
+/**
+ * Drop non-positive or non-numeric limits. Returns null when nothing is left to enforce.
+ */
+export function normalizeResourceLimits(input?: ResourceLimits | null): ResourceLimits | null {
+  if (!input || typeof input !== 'object') return null;
+  const out: ResourceLimits = {};
+  const mem = Math.floor(Number(input.maxMemoryMb));
+  if (Number.isFinite(mem) && mem > 0) out.maxMemoryMb = mem;
+  const cpu = Number(input.maxCpuPercent);
+  if (Number.isFinite(cpu) && cpu > 0) out.maxCpuPercent = cpu;
+  return out.maxMemoryMb || out.maxCpuPercent ? out : null;
+}
+
+let scopeProbe: Promise<boolean> | null = null;
+
+function canUseSystemdScope(): Promise<boolean> {
+  if (process.platform !== 'linux') return Promise.resolve(false);
+  if (!scopeProbe) {
+    scopeProbe = new Promise((resolve) => {
+      execFile('systemd-run', ['--user', '--scope', '--quiet', 'true'], { timeout: 5000 }, (err) =>
+        resolve(!err)
+      );
+    });
+  }
+  return scopeProbe;
+}
+
+/**
+ * Run the command inside a transient cgroup v2 scope (`systemd-run --user --scope`) so the
+ * kernel caps memory and throttles CPU for the whole process tree. systemd-run execs the
+ * command in place, so the pid is unchanged. Without a user systemd instance the command
+ * is returned as-is and only the sampler in `watchResourceUsage` applies.
+ */
+export async function wrapWithCgroup(
+  command: string,
+  args: string[],
+  limits: ResourceLimits
+): Promise<{ command: string; args: string[]; cgroup: boolean }> {
+  if (!(await canUseSystemdScope())) return { command, args, cgroup: false };
+  const props: string[] = [];
+  if (limits.maxMemoryMb) props.push('-p', `MemoryMax=${limits.maxMemoryMb}M`);
+  if (limits.maxCpuPercent) props.push('-p', `CPUQuota=${Math.round(limits.maxCpuPercent)}%`);
+  return {
+    command: 'systemd-run',
+    args: ['--user', '--scope', '--quiet', ...props, '--', command, ...args],
+    cgroup: true,
+  };
+}
+
+interface Sample {
+  rssMb: number;
+  cpuPercent: number | null; // null until two samples exist
+}
+
+async function sampleLinux(pid: number, prev: { ticks: number; at: number } | null) {
+  const [stat, status] = await Promise.all([
+    readFile(`/proc/${pid}/stat`, 'utf8'),
+    readFile(`/proc/${pid}/status`, 'utf8'),
+  ]);
+  // Fields after the parenthesised command name: state is [0], utime [11], stime [12]
+  const fields = stat.slice(stat.lastIndexOf(')') + 2).split(' ');
+  const ticks = Number(fields[11]) + Number(fields[12]);
+  const rssKb = Number(/VmRSS:\s+(\d+)/.exec(status)?.[1] ?? 0);
+  const at = Date.now();
+  let cpuPercent: number | null = null;
+  if (prev && at > prev.at) {
+    cpuPercent = ((ticks - prev.ticks) / CLOCK_TICKS / ((at - prev.at) / 1000)) * 100;
+  }
+  return { sample: { rssMb: rssKb / 1024, cpuPercent }, mark: { ticks, at } };
+}
+
+function samplePs(pid: number): Promise<Sample> {
+  return new Promise((resolve, reject) => {
+    execFile('ps', ['-o', 'rss=,%cpu=', '-p', String(pid)], { timeout: 5000 }, (err, stdout) => {
+      if (err) return reject(err);
+      const [rss, cpu] = stdout.trim().split(/\s+/).map(Number);
+      if (!Number.isFinite(rss)) return reject(new Error('process not found'));
+      resolve({ rssMb: rss / 1024, cpuPercent: Number.isFinite(cpu) ? cpu : null });
+    });
+  });
+}
+
+/**
+ * Poll the process's memory and CPU and call `onExceeded` once when a limit is crossed.
+ * Only the process itself is measured, not its children. Returns a function that stops polling.
+ * No-op on Windows.
+ */
+export function watchResourceUsage(
+  pid: number,
+  limits: ResourceLimits,
+  onExceeded: (reason: string) => void
+): () => void {
+  if (process.platform === 'win32') return () => {};
+  let stopped = false;
+  let busy = false;
+  let strikes = 0;
+  let mark: { ticks: number; at: number } | null = null;
+
+  const check = (sample: Sample) => {
+    if (limits.maxMemoryMb && sample.rssMb > limits.maxMemoryMb) {
+      return `memory limit exceeded: ${Math.round(sample.rssMb)} MB > ${limits.maxMemoryMb} MB`;
+    }
+    if (limits.maxCpuPercent && sample.cpuPercent !== null) {
+      strikes = sample.cpuPercent > limits.maxCpuPercent ? strikes + 1 : 0;
+      if (strikes >= CPU_STRIKES) {
+        return `CPU limit exceeded: ${Math.round(sample.cpuPercent)}% > ${limits.maxCpuPercent}%`;
+      }
+    }
+    return null;
+  };
+
+  const timer = setInterval(async () => {
+    if (stopped || busy) return;
+    busy = true;
+    try {
+      let sample: Sample;
+      if (process.platform === 'linux') {
+        const res = await sampleLinux(pid, mark);
+        mark = res.mark;
+        sample = res.sample;
+      } else {
+        sample = await samplePs(pid);
+      }
+      const reason = stopped ? null : check(sample);
+      if (reason) {
+        stop();
+        onExceeded(reason);
+      }
+    } catch {
+      // Process is gone or unreadable; the exit handler takes care of cleanup
+    } finally {
+      busy = false;
+    }
+  }, SAMPLE_INTERVAL_MS);
+  timer.unref?.();
+
+  function stop() {
+    stopped = true;
+    clearInterval(timer);
+  }
+  return stop;
+}
@@ -0,0 +1,151 @@
+import { existsSync, readdirSync, rmdirSync, statSync, unlinkSync } from 'fs';
+import { mkdir, open, rename, rm, stat, type FileHandle } from 'fs/promises';
+import path from 'path';
+
+export interface RotationOptions {
+  maxBytes: number; // rotate once the current file would grow past this
+  maxFiles: number; // rotated files kept besides the current one
+}
+
+/** `stream.log` -> `stream.1.log`, `stream.2.log`, ... (index 0 is the file itself). */
+export function rotatedPath(file: string, index: number): string {
+  if (index === 0) return file;
+  const ext = path.extname(file);
+  return `${file.slice(0, file.length - ext.length)}.${index}${ext}`;
+}
+
+/**
+ * Append-only log file that rolls over by size. Writes are queued and flushed
+ * asynchronously, one batch at a time, so streamed output never blocks the main process and
+ * a rotation never races a pending write. `end` resolves once everything queued is on disk.
+ */
+export class RotatingLog {
+  readonly file: string;
+  private readonly options: RotationOptions;
+  private handle: FileHandle | null = null;
+  private size = 0;
+  private queue: Buffer[] = [];
+  private flushing: Promise<void> | null = null;
+  private readonly ready: Promise<void>;
+  destroyed = false;
+
+  constructor(file: string, options: RotationOptions) {
+    this.file = file;
+    this.options = options;
+    this.ready = mkdir(path.dirname(file), { recursive: true })
+      .then(() => this.open())
+      .catch(() => {});
+  }
+
+  write(text: string) {
+    if (this.destroyed || !text) return;
+    this.queue.push(Buffer.from(text, 'utf8'));
+    if (!this.flushing) {
+      this.flushing = this.flush().finally(() => {
+        this.flushing = null;
+      });
+    }
+  }
+
+  async end(): Promise<void> {
+    if (this.destroyed) return;
+    this.destroyed = true;
+    await this.ready;
+    await this.flushing;
+    await this.close();
+  }
+
+  private async flush() {
+    await this.ready;
+    while (this.queue.length > 0) {
+      const chunks = this.queue.splice(0);
+      let batch: Buffer[] = [];
+      let batchBytes = 0;
+      for (const chunk of chunks) {
+        const pending = this.size + batchBytes;
+        if (pending > 0 && pending + chunk.length > this.options.maxBytes) {
+          await this.append(batch);
+          batch = [];
+          batchBytes = 0;
+          await this.rotate();
+        }
+        batch.push(chunk);
+        batchBytes += chunk.length;
+      }
+      await this.append(batch);
+    }
+  }
+
+  private async append(batch: Buffer[]) {
+    if (batch.length === 0 || !this.handle) return;
+    const data = batch.length === 1 ? batch[0] : Buffer.concat(batch);
+    try {
+      await this.handle.write(data);
+      this.size += data.length;
+    } catch {
+      // A full or vanished disk must not take the agent run down with it
+    }
+  }
+
+  private async open() {
+    this.handle = await open(this.file, 'a');
+    this.size = (await this.handle.stat()).size;
+  }
+
+  private async close() {
+    const handle = this.handle;
+    this.handle = null;
+    try {
+      await handle?.close();
+    } catch {}
+  }
+
+  private async rotate() {
+    try {
+      await this.close();
+      const keep = Math.max(0, this.options.maxFiles);
+      if (keep > 0) await rm(rotatedPath(this.file, keep), { force: true });
+      for (let i = keep - 1; i >= 1; i--) {
+        const from = rotatedPath(this.file, i);
+        if (await exists(from)) await rename(from, rotatedPath(this.file, i + 1));
+      }
+      if (keep > 0) await rename(this.file, rotatedPath(this.file, 1));
+      else await rm(this.file, { force: true });
+      await this.open();
+    } catch {
+      // Keep appending to whatever is open rather than losing output
+      if (!this.handle) await this.open().catch(() => {});
+    }
+  }
+}
+
+async function exists(file: string): Promise<boolean> {
+  try {
+    await stat(file);
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+/**
+ * Delete `.log` files under `dir` last written before `olderThanMs` ago, then any
+ * directories left empty. Returns the number of files removed.
+ */
+export function pruneLogFiles(dir: string, olderThanMs: number, now = Date.now()): number {
+  if (!existsSync(dir)) return 0;
+  let removed = 0;
+  for (const entry of readdirSync(dir, { withFileTypes: true })) {
+    const full = path.join(dir, entry.name);
+    try {
+      if (entry.isDirectory()) {
+        removed += pruneLogFiles(full, olderThanMs, now);
+        if (readdirSync(full).length === 0) rmdirSync(full);
+      } else if (entry.name.endsWith('.log') && now - statSync(full).mtimeMs > olderThanMs) {
+        unlinkSync(full);
+        removed++;
+      }
+    } catch {}
+  }
+  return removed;
+}
@@ -0,0 +1,25 @@
+/**
+ * Token bucket that refills at `rate` tokens/sec up to `capacity` (one second's worth by
+ * default). `take` either spends the whole amount or nothing.
+ */
+export class TokenBucket {
+  private readonly rate: number;
+  private readonly capacity: number;
+  private tokens: number;
+  private last: number;
+
+  constructor(rate: number, capacity = rate, now = Date.now()) {
+    this.rate = rate;
+    this.capacity = capacity;
+    this.tokens = capacity;
+    this.last = now;
+  }
+
+  take(amount = 1, now = Date.now()): boolean {
+    this.tokens = Math.min(this.capacity, this.tokens + ((now - this.last) / 1000) * this.rate);
+    this.last = now;
+    if (amount > this.tokens) return false;
+    this.tokens -= amount;
+    return true;
+  }
+}
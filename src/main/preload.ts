@@ -1,5 +1,19 @@
 import { contextBridge, ipcRenderer } from 'electron';
 import type { TerminalSnapshotPayload } from './types/terminalSnapshot';
+import type { KillOptions } from './lib/processKill';
+import type { AgentEvent } from './services/AgentEventParsers';
+import type { FanOutBatch } from './services/FanOutService';
+import type { AgentRunResult } from './services/AgentService';
+import type { AppNotification } from './services/NotificationService';
+import type { PtyCloseReason, PtyErrorCode } from './services/ptyIpc';
+
+type PtyExitInfo = { exitCode: number; signal?: number; reason?: PtyCloseReason };
+
+// Critical events carry a deliveryId; acknowledging it once a listener has handled the event
+// stops the main process from retrying (see DeadLetterStore)
+function ackCritical(data: any) {
+  if (typeof data?.deliveryId === 'string') ipcRenderer.send('critical:ack', data.deliveryId);
+}
 
 // Expose protected methods that allow the renderer process to use
 // the ipcRenderer without exposing the entire object
@@ -8,6 +22,8 @@ contextBridge.exposeInMainWorld('electronAPI', {
   getAppVersion: () => ipcRenderer.invoke('app:getAppVersion'),
   getElectronVersion: () => ipcRenderer.invoke('app:getElectronVersion'),
   getPlatform: () => ipcRenderer.invoke('app:getPlatform'),
+  getCapabilities: () => ipcRenderer.invoke('app:getCapabilities'),
+  setLocale: (acceptLanguage: string | null) => ipcRenderer.invoke('app:setLocale', acceptLanguage),
   // Updater
   checkForUpdates: () => ipcRenderer.invoke('update:check'),
   downloadUpdate: () => ipcRenderer.invoke('update:download'),
@@ -42,18 +58,39 @@ contextBridge.exposeInMainWorld('electronAPI', {
     id: string;
     cwd?: string;
     shell?: string;
+    command?: string;
+    args?: string[];
     env?: Record<string, string>;
     cols?: number;
     rows?: number;
+    readOnly?: boolean;
+    envProfile?: string;
+    labels?: Record<string, string>;
+    restartOnExit?: boolean;
+    maxOutputBytesPerSec?: number;
+    resumeFrom?: number;
   }) => ipcRenderer.invoke('pty:start', opts),
   ptyInput: (args: { id: string; data: string }) => ipcRenderer.send('pty:input', args),
   ptyResize: (args: { id: string; cols: number; rows: number }) =>
     ipcRenderer.send('pty:resize', args),
-  ptyKill: (id: string) => ipcRenderer.send('pty:kill', { id }),
+  ptyKill: (id: string, kill?: KillOptions) => ipcRenderer.send('pty:kill', { id, kill }),
+  ptyDetach: (id: string) => ipcRenderer.send('pty:detach', { id }),
+  ptyPause: (id: string) => ipcRenderer.send('pty:pause', { id }),
+  ptyResume: (id: string) => ipcRenderer.send('pty:resume', { id }),
+  ptyStats: (args?: { id?: string }) => ipcRenderer.invoke('pty:stats', args ?? {}),
+  ptyList: (args?: { labels?: Record<string, string> }) =>
+    ipcRenderer.invoke('pty:list', args ?? {}),
+  ptySetController: (args: { id: string; controller: boolean }) =>
+    ipcRenderer.invoke('pty:set-controller', args),
+  ptySignal: (args: {
+    id: string;
+    signal: 'SIGINT' | 'SIGTSTP' | 'SIGQUIT' | 'SIGHUP' | 'SIGWINCH';
+  }) => ipcRenderer.invoke('pty:signal', args),
 
-  onPtyData: (id: string, listener: (data: string) => void) => {
+  onPtyData: (id: string, listener: (data: string, seq?: number) => void) => {
     const channel = `pty:data:${id}`;
-    const wrapped = (_: Electron.IpcRendererEvent, data: string) => listener(data);
+    const wrapped = (_: Electron.IpcRendererEvent, data: string, seq?: number) =>
+      listener(data, seq);
     ipcRenderer.on(channel, wrapped);
     return () => ipcRenderer.removeListener(channel, wrapped);
   },
@@ -61,10 +98,40 @@ contextBridge.exposeInMainWorld('electronAPI', {
   ptySaveSnapshot: (args: { id: string; payload: TerminalSnapshotPayload }) =>
     ipcRenderer.invoke('pty:snapshot:save', args),
   ptyClearSnapshot: (args: { id: string }) => ipcRenderer.invoke('pty:snapshot:clear', args),
-  onPtyExit: (id: string, listener: (info: { exitCode: number; signal?: number }) => void) => {
+  ptyGetTranscript: (args: { id: string; format?: 'ansi' | 'plain' }) =>
+    ipcRenderer.invoke('pty:transcript:get', args),
+  onPtyExit: (id: string, listener: (info: PtyExitInfo) => void) => {
     const channel = `pty:exit:${id}`;
-    const wrapped = (_: Electron.IpcRendererEvent, info: { exitCode: number; signal?: number }) =>
+    const wrapped = (_: Electron.IpcRendererEvent, info: PtyExitInfo) => {
       listener(info);
+      ackCritical(info);
+    };
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  onPtyRestarted: (
+    id: string,
+    listener: (info: { exitCode: number; signal?: number; restarts: number }) => void
+  ) => {
+    const channel = `pty:restarted:${id}`;
+    const wrapped = (
+      _: Electron.IpcRendererEvent,
+      info: { exitCode: number; signal?: number; restarts: number }
+    ) => listener(info);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  onPtyTitle: (id: string, listener: (title: string) => void) => {
+    const channel = `pty:title:${id}`;
+    const wrapped = (_: Electron.IpcRendererEvent, title: string) => listener(title);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  onPtyInputLimited: (
+    listener: (data: { id: string; code: 'rate-limited'; reason: 'messages' | 'bytes' }) => void
+  ) => {
+    const channel = 'pty:input-limited';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
     ipcRenderer.on(channel, wrapped);
     return () => ipcRenderer.removeListener(channel, wrapped);
   },
@@ -80,20 +147,136 @@ contextBridge.exposeInMainWorld('electronAPI', {
   updateSettings: (settings: any) => ipcRenderer.invoke('settings:update', settings),
 
   // Worktree management
-  worktreeCreate: (args: { projectPath: string; workspaceName: string; projectId: string }) =>
-    ipcRenderer.invoke('worktree:create', args),
-  worktreeList: (args: { projectPath: string }) => ipcRenderer.invoke('worktree:list', args),
+  worktreeCreate: (args: {
+    projectPath: string;
+    workspaceName: string;
+    projectId: string;
+    copyFiles?: string[];
+    ticket?: string;
+  }) => ipcRenderer.invoke('worktree:create', args),
+  worktreeCreateBatch: (args: {
+    projectPath: string;
+    workspaceNames: string[];
+    projectId: string;
+    concurrency?: number;
+    copyFiles?: string[];
+  }) => ipcRenderer.invoke('worktree:create-batch', args),
+  worktreeList: (args: {
+    projectPath?: string;
+    projectId?: string;
+    name?: string;
+    branch?: string;
+    includeStatus?: boolean;
+    pageSize?: number;
+    pageToken?: string;
+  }) => ipcRenderer.invoke('worktree:list', args),
   worktreeRemove: (args: {
     projectPath: string;
     worktreeId: string;
     worktreePath?: string;
     branch?: string;
+    deleteBranch?: boolean;
+    force?: boolean;
   }) => ipcRenderer.invoke('worktree:remove', args),
   worktreeStatus: (args: { worktreePath: string }) => ipcRenderer.invoke('worktree:status', args),
   worktreeMerge: (args: { projectPath: string; worktreeId: string }) =>
     ipcRenderer.invoke('worktree:merge', args),
+  worktreeMergeToBase: (args: {
+    projectPath: string;
+    worktreeId?: string;
+    worktreePath?: string;
+    branch?: string;
+    baseBranch?: string;
+    strategy?: 'fast-forward' | 'merge' | 'squash';
+    commitMessage?: string;
+  }) => ipcRenderer.invoke('worktree:merge-to-base', args),
+  worktreeApproveMerge: (args: {
+    projectPath: string;
+    worktreeId?: string;
+    worktreePath?: string;
+    branch?: string;
+  }) => ipcRenderer.invoke('worktree:approve-merge', args),
+  worktreeSync: (args: {
+    worktreePath: string;
+    baseBranch?: string;
+    strategy?: 'rebase' | 'merge';
+    remote?: string;
+  }) => ipcRenderer.invoke('worktree:sync', args),
+  onWorktreeSyncProgress: (
+    listener: (data: {
+      worktreePath: string;
+      step: 'fetch' | 'rebase' | 'merge' | 'done';
+      message: string;
+    }) => void
+  ) => {
+    const channel = 'worktree:sync-progress';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  worktreeRebasePlanGet: (args: { worktreePath: string; base?: string }) =>
+    ipcRenderer.invoke('worktree:rebase-plan:get', args),
+  worktreeRebasePlanExecute: (args: { worktreePath: string; plan: any }) =>
+    ipcRenderer.invoke('worktree:rebase-plan:execute', args),
+  worktreeGcDryRun: (args?: { staleAfterDays?: number }) =>
+    ipcRenderer.invoke('worktree:gc:dry-run', args),
+  worktreeGcSweep: () => ipcRenderer.invoke('worktree:gc:sweep'),
+  onWorktreeGcAudit: (listener: (event: any) => void) => {
+    const channel = 'worktree:gc-audit';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  onWorkspaceArtifactDetected: (listener: (event: any) => void) => {
+    const channel = 'workspace:artifact-detected';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  worktreePreviewBranchName: (args: {
+    projectPath: string;
+    workspaceName: string;
+    template?: string;
+  }) => ipcRenderer.invoke('worktree:preview-branch-name', args),
   worktreeGet: (args: { worktreeId: string }) => ipcRenderer.invoke('worktree:get', args),
   worktreeGetAll: () => ipcRenderer.invoke('worktree:getAll'),
+  onWorktreeEvent: (
+    listener: (event: {
+      type: 'created' | 'removed' | 'dirty-changed';
+      payload: any;
+    }) => void
+  ) => {
+    const pairs: Array<[string, 'created' | 'removed' | 'dirty-changed']> = [
+      ['worktree:created', 'created'],
+      ['worktree:removed', 'removed'],
+      ['worktree:dirty-changed', 'dirty-changed'],
+    ];
+    const handlers: Array<() => void> = [];
+    for (const [channel, type] of pairs) {
+      const wrapped = (_: Electron.IpcRendererEvent, payload: any) => listener({ type, payload });
+      ipcRenderer.on(channel, wrapped);
+      handlers.push(() => ipcRenderer.removeListener(channel, wrapped));
+    }
+    return () => handlers.forEach((off) => off());
+  },
+  onWorktreeMergeGate: (
+    listener: (data: {
+      worktreePath?: string;
+      branch: string;
+      gate: {
+        id: string;
+        kind: 'command' | 'approval';
+        status: 'pending' | 'running' | 'passed' | 'failed';
+        output?: string;
+        durationMs?: number;
+      };
+    }) => void
+  ) => {
+    const channel = 'worktree:merge-gate';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
 
   // Filesystem helpers
   fsList: (root: string, opts?: { includeDirs?: boolean; maxEntries?: number }) =>
@@ -122,6 +305,7 @@ contextBridge.exposeInMainWorld('electronAPI', {
     commitMessage?: string;
     createBranchIfOnDefault?: boolean;
     branchPrefix?: string;
+    autoCommitGenerated?: boolean;
   }) => ipcRenderer.invoke('git:commit-and-push', args),
   createPullRequest: (args: {
     workspacePath: string;
@@ -156,6 +340,20 @@ contextBridge.exposeInMainWorld('electronAPI', {
     ipcRenderer.invoke('telemetry:capture', { event, properties }),
   getTelemetryStatus: () => ipcRenderer.invoke('telemetry:get-status'),
   setTelemetryEnabled: (enabled: boolean) => ipcRenderer.invoke('telemetry:set-enabled', enabled),
+  showTelemetryPayload: () => ipcRenderer.invoke('telemetry:show-payload'),
+  // Dead letters (critical events no window could receive)
+  deadLetterList: () => ipcRenderer.invoke('deadletter:list'),
+  deadLetterRedeliver: (args?: { ids?: string[] }) =>
+    ipcRenderer.invoke('deadletter:redeliver', args),
+  deadLetterDiscard: (args?: { ids?: string[] }) => ipcRenderer.invoke('deadletter:discard', args),
+  notificationsRecent: (args?: { limit?: number }) =>
+    ipcRenderer.invoke('notifications:recent', args),
+  onAppNotification: (listener: (notification: AppNotification) => void) => {
+    const channel = 'app:notification';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
   connectToGitHub: (projectPath: string) => ipcRenderer.invoke('github:connect', projectPath),
   onRunEvent: (callback: (event: any) => void) => {
     ipcRenderer.on('run:event', (_, event) => callback(event));
@@ -227,6 +425,13 @@ contextBridge.exposeInMainWorld('electronAPI', {
   // Debug helpers
   debugAppendLog: (filePath: string, content: string, options?: { reset?: boolean }) =>
     ipcRenderer.invoke('debug:append-log', filePath, content, options ?? {}),
+  debugRecentEvents: (args?: {
+    limit?: number;
+    workspaceId?: string;
+    worktreePath?: string;
+    ptyId?: string;
+    kinds?: string[];
+  }) => ipcRenderer.invoke('debug:recent-events', args ?? {}),
 
   // Codex integration
   codexCheckInstallation: () => ipcRenderer.invoke('codex:check-installation'),
@@ -236,7 +441,8 @@ contextBridge.exposeInMainWorld('electronAPI', {
     ipcRenderer.invoke('codex:send-message', workspaceId, message),
   codexSendMessageStream: (workspaceId: string, message: string, conversationId?: string) =>
     ipcRenderer.invoke('codex:send-message-stream', workspaceId, message, conversationId),
-  codexStopStream: (workspaceId: string) => ipcRenderer.invoke('codex:stop-stream', workspaceId),
+  codexStopStream: (workspaceId: string, kill?: KillOptions) =>
+    ipcRenderer.invoke('codex:stop-stream', workspaceId, kill),
   codexGetStreamTail: (workspaceId: string) =>
     ipcRenderer.invoke('codex:get-stream-tail', workspaceId),
   codexGetAgentStatus: (workspaceId: string) =>
@@ -270,6 +476,8 @@ contextBridge.exposeInMainWorld('electronAPI', {
       output: string;
       agentId: string;
       conversationId?: string;
+      seq?: number;
+      stream?: 'stdout';
     }) => void
   ) => {
     const wrapped = (
@@ -285,6 +493,8 @@ contextBridge.exposeInMainWorld('electronAPI', {
       error: string;
       agentId: string;
       conversationId?: string;
+      seq?: number;
+      stream?: 'stderr';
     }) => void
   ) => {
     const wrapped = (
@@ -313,17 +523,66 @@ contextBridge.exposeInMainWorld('electronAPI', {
   // Generic agent integration (multi-provider)
   agentCheckInstallation: (providerId: 'codex' | 'claude') =>
     ipcRenderer.invoke('agent:check-installation', providerId),
+  agentDiscoverProviders: () => ipcRenderer.invoke('agent:discover-providers'),
   agentGetInstallationInstructions: (providerId: 'codex' | 'claude') =>
     ipcRenderer.invoke('agent:get-installation-instructions', providerId),
   agentSendMessageStream: (args: {
-    providerId: 'codex' | 'claude';
+    providerId?: 'codex' | 'claude';
     workspaceId: string;
     worktreePath: string;
     message: string;
     conversationId?: string;
+    envProfile?: string;
+    agentId?: string;
+    restartPolicy?: {
+      mode: 'never' | 'on-failure';
+      maxRetries?: number;
+      backoffMs?: number;
+    };
+    usePty?: boolean;
+    resourceLimits?: { maxMemoryMb?: number; maxCpuPercent?: number };
+    queue?: boolean;
+    maxRuntimeMs?: number;
+    secrets?: Record<string, string>;
+    preset?: string;
+    requireApproval?: boolean;
   }) => ipcRenderer.invoke('agent:send-message-stream', args),
-  agentStopStream: (args: { providerId: 'codex' | 'claude'; workspaceId: string }) =>
-    ipcRenderer.invoke('agent:stop-stream', args),
+  agentStopStream: (args: {
+    providerId: 'codex' | 'claude';
+    workspaceId: string;
+    kill?: KillOptions;
+    agentId?: string;
+  }) => ipcRenderer.invoke('agent:stop-stream', args),
+  agentInterrupt: (args: {
+    providerId: 'codex' | 'claude';
+    workspaceId: string;
+    agentId?: string;
+  }) => ipcRenderer.invoke('agent:interrupt', args),
+  agentGetLogs: (args: {
+    providerId: 'codex' | 'claude';
+    workspaceId: string;
+    offset?: number;
+    limit?: number;
+    agentId?: string;
+  }) => ipcRenderer.invoke('agent:get-logs', args),
+  agentAttach: (args: {
+    providerId: 'codex' | 'claude';
+    workspaceId: string;
+    fromSeq?: number;
+    agentId?: string;
+  }) => ipcRenderer.invoke('agent:attach', args),
+  agentDetach: (args: { providerId: 'codex' | 'claude'; workspaceId: string; agentId?: string }) =>
+    ipcRenderer.invoke('agent:detach', args),
+  agentList: (args?: { workspaceId?: string }) => ipcRenderer.invoke('agent:list', args ?? {}),
+  agentListPresets: () => ipcRenderer.invoke('agent:list-presets'),
+  agentApproveAction: (args: { approvalId: string; approved: boolean; message?: string }) =>
+    ipcRenderer.invoke('agent:approve-action', args),
+  agentListLogFiles: (args?: { providerId?: 'codex' | 'claude'; workspaceId?: string }) =>
+    ipcRenderer.invoke('agent:list-log-files', args ?? {}),
+  agentExportLogFile: (args: { id: string }) => ipcRenderer.invoke('agent:export-log-file', args),
+  secretsList: () => ipcRenderer.invoke('secrets:list'),
+  secretsSet: (args: { name: string; value: string }) => ipcRenderer.invoke('secrets:set', args),
+  secretsDelete: (args: { name: string }) => ipcRenderer.invoke('secrets:delete', args),
   onAgentStreamOutput: (
     listener: (data: {
       providerId: 'codex' | 'claude';
@@ -332,6 +591,8 @@ contextBridge.exposeInMainWorld('electronAPI', {
       error?: string;
       agentId?: string;
       conversationId?: string;
+      seq?: number;
+      stream?: 'stdout';
     }) => void
   ) => {
     const channel = 'agent:stream-output';
@@ -340,10 +601,19 @@ contextBridge.exposeInMainWorld('electronAPI', {
     return () => ipcRenderer.removeListener(channel, wrapped);
   },
   onAgentStreamError: (
-    listener: (data: { providerId: 'codex' | 'claude'; workspaceId: string; error: string }) => void
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      error: string;
+      seq?: number;
+      stream?: 'stderr';
+    }) => void
   ) => {
     const channel = 'agent:stream-error';
-    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => {
+      listener(data);
+      ackCritical(data);
+    };
     ipcRenderer.on(channel, wrapped);
     return () => ipcRenderer.removeListener(channel, wrapped);
   },
@@ -355,6 +625,202 @@ contextBridge.exposeInMainWorld('electronAPI', {
     }) => void
   ) => {
     const channel = 'agent:stream-complete';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => {
+      listener(data);
+      ackCritical(data);
+    };
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  onAgentGuardrail: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      level: 'warn' | 'stop';
+      stats: { files: number; insertions: number; deletions: number };
+      limits: { warnLines: number; warnFiles: number; stopLines: number; stopFiles: number };
+    }) => void
+  ) => {
+    const channel = 'agent:guardrail';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  onAgentRestarting: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      attempt: number;
+      maxRetries: number;
+      delayMs: number;
+    }) => void
+  ) => {
+    const channel = 'agent:restarting';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  onAgentEvent: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      event: AgentEvent;
+    }) => void
+  ) => {
+    const channel = 'agent:event';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  onAgentMessageStatus: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      messageId: string;
+      status: 'queued' | 'delivered' | 'dropped';
+      position?: number;
+      reason?: 'concurrency'; // waiting for a slot rather than for the session
+      error?: string;
+    }) => void
+  ) => {
+    const channel = 'agent:message-status';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  onAgentStall: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      stalled: boolean;
+      silentMs: number;
+    }) => void
+  ) => {
+    const channel = 'agent:stall';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  onAgentTimedOut: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      maxRuntimeMs: number;
+    }) => void
+  ) => {
+    const channel = 'agent:timed-out';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  onAgentInterrupted: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+    }) => void
+  ) => {
+    const channel = 'agent:interrupted';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  onAgentStatus: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      status: 'starting' | 'running' | 'stalled' | 'stopping' | 'stopped' | 'error' | 'timed-out';
+      previous?:
+        | 'starting'
+        | 'running'
+        | 'stalled'
+        | 'stopping'
+        | 'stopped'
+        | 'error'
+        | 'timed-out';
+      exitCode?: number | null;
+      error?: string;
+      silentMs?: number; // with 'stalled'
+      reason?: string; // with 'stopping'
+    }) => void
+  ) => {
+    const channel = 'agent:status';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => {
+      listener(data);
+      ackCritical(data);
+    };
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  agentFanOut: (args: {
+    projectPath: string;
+    projectId: string;
+    prompt: string;
+    targets: Array<{ workspaceName: string; providerId?: 'codex' | 'claude'; preset?: string }>;
+    concurrency?: number;
+  }) => ipcRenderer.invoke('agent:fan-out', args),
+  agentFanOutStatus: (args: { batchId: string }) =>
+    ipcRenderer.invoke('agent:fan-out-status', args),
+  onAgentApprovalRequired: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      approvalId: string;
+      toolName: string;
+      input: unknown;
+    }) => void
+  ) => {
+    const channel = 'agent:approval-required';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => {
+      listener(data);
+      ackCritical(data);
+    };
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  onAgentApprovalResolved: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      approvalId: string;
+      approved: boolean;
+    }) => void
+  ) => {
+    const channel = 'agent:approval-resolved';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  agentGetResult: (args: {
+    providerId: 'codex' | 'claude';
+    workspaceId: string;
+    agentId?: string;
+  }) => ipcRenderer.invoke('agent:get-result', args),
+  agentMetrics: () => ipcRenderer.invoke('agent:metrics'),
+  onAgentResult: (
+    listener: (
+      data: AgentRunResult & {
+        providerId: 'codex' | 'claude';
+        workspaceId: string;
+        agentId?: string;
+      }
+    ) => void
+  ) => {
+    const channel = 'agent:result';
+    const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
+    ipcRenderer.on(channel, wrapped);
+    return () => ipcRenderer.removeListener(channel, wrapped);
+  },
+  onAgentFanOutProgress: (listener: (batch: FanOutBatch) => void) => {
+    const channel = 'agent:fan-out-progress';
     const wrapped = (_: Electron.IpcRendererEvent, data: any) => listener(data);
     ipcRenderer.on(channel, wrapped);
     return () => ipcRenderer.removeListener(channel, wrapped);
@@ -366,6 +832,8 @@ export interface ElectronAPI {
   // App info
   getVersion: () => Promise<string>;
   getPlatform: () => Promise<string>;
+  getCapabilities: () => Promise<{ signals: Record<string, string> }>;
+  setLocale: (acceptLanguage: string | null) => Promise<{ success: boolean }>;
   // Updater
   checkForUpdates: () => Promise<{ success: boolean; result?: any; error?: string }>;
   downloadUpdate: () => Promise<{ success: boolean; error?: string }>;
@@ -378,14 +846,117 @@ export interface ElectronAPI {
     id: string;
     cwd?: string;
     shell?: string;
+    command?: string;
+    args?: string[];
     env?: Record<string, string>;
     cols?: number;
     rows?: number;
-  }) => Promise<{ ok: boolean; error?: string }>;
+    readOnly?: boolean;
+    envProfile?: string;
+    labels?: Record<string, string>;
+    restartOnExit?: boolean;
+    maxOutputBytesPerSec?: number;
+    resumeFrom?: number;
+  }) => Promise<{ ok: boolean; truncated?: boolean; code?: PtyErrorCode; error?: string }>;
   ptyInput: (args: { id: string; data: string }) => void;
   ptyResize: (args: { id: string; cols: number; rows: number }) => void;
-  ptyKill: (id: string) => void;
-  onPtyData: (id: string, listener: (data: string) => void) => () => void;
+  ptyKill: (id: string, kill?: KillOptions) => void;
+  ptyDetach: (id: string) => void;
+  ptyPause: (id: string) => void;
+  ptyResume: (id: string) => void;
+  ptyList: (args?: { labels?: Record<string, string> }) => Promise<{
+    ok: boolean;
+    ptys?: Array<{
+      id: string;
+      cwd: string;
+      pid: number;
+      labels: Record<string, string>;
+      title?: string;
+    }>;
+    error?: string;
+  }>;
+  agentList: (args?: { workspaceId?: string }) => Promise<{
+    success: boolean;
+    agents?: Array<{
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      running: boolean;
+      restarts: number;
+      queued: number;
+      status?: 'starting' | 'running' | 'stalled' | 'stopping' | 'stopped' | 'error' | 'timed-out';
+      stalled: boolean;
+      timedOut: boolean;
+      remainingMs?: number;
+      attachedClients: number;
+      pendingApprovals: number;
+      startQueuePosition?: number;
+    }>;
+    error?: string;
+  }>;
+  agentListLogFiles: (args?: {
+    providerId?: 'codex' | 'claude';
+    workspaceId?: string;
+  }) => Promise<{
+    success: boolean;
+    files?: Array<{
+      id: string;
+      providerId: string;
+      workspaceId: string;
+      agentId?: string;
+      name: string;
+      size: number;
+      modifiedAt: string;
+    }>;
+    error?: string;
+  }>;
+  agentExportLogFile: (args: {
+    id: string;
+  }) => Promise<{ success: boolean; path?: string; error?: string }>;
+  agentApproveAction: (args: {
+    approvalId: string;
+    approved: boolean;
+    message?: string;
+  }) => Promise<{ success: boolean; error?: string }>;
+  agentListPresets: () => Promise<{
+    success: boolean;
+    presets?: Array<{ name: string; providerId: string; model?: string }>;
+    error?: string;
+  }>;
+  secretsList: () => Promise<{ success: boolean; names?: string[]; error?: string }>;
+  secretsSet: (args: {
+    name: string;
+    value: string;
+  }) => Promise<{ success: boolean; error?: string }>;
+  secretsDelete: (args: {
+    name: string;
+  }) => Promise<{ success: boolean; deleted?: boolean; error?: string }>;
+  ptyStats: (args?: { id?: string }) => Promise<{
+    ok: boolean;
+    stats?: Array<{
+      id: string;
+      pid: number;
+      bytesIn: number;
+      bytesOut: number;
+      framesIn: number;
+      framesOut: number;
+      droppedChunks: number;
+      startedAt: number;
+      exitedAt?: number;
+      uptimeMs: number;
+      clients: number;
+      paused: boolean;
+    }>;
+  }>;
+  ptySetController: (args: {
+    id: string;
+    controller: boolean;
+  }) => Promise<{ ok: boolean; error?: string }>;
+  ptySignal: (args: {
+    id: string;
+    signal: 'SIGINT' | 'SIGTSTP' | 'SIGQUIT' | 'SIGHUP' | 'SIGWINCH';
+  }) => Promise<{ ok: boolean; error?: string }>;
+  onPtyData: (id: string, listener: (data: string, seq?: number) => void) => () => void;
   ptyGetSnapshot: (args: { id: string }) => Promise<{
     ok: boolean;
     snapshot?: any;
@@ -396,23 +967,66 @@ export interface ElectronAPI {
     payload: TerminalSnapshotPayload;
   }) => Promise<{ ok: boolean; error?: string }>;
   ptyClearSnapshot: (args: { id: string }) => Promise<{ ok: boolean }>;
+  ptyGetTranscript: (args: { id: string; format?: 'ansi' | 'plain' }) => Promise<{
+    ok: boolean;
+    text?: string;
+    createdAt?: string;
+    error?: string;
+  }>;
   onPtyExit: (
     id: string,
-    listener: (info: { exitCode: number; signal?: number }) => void
+    listener: (info: PtyExitInfo) => void
+  ) => () => void;
+  onPtyRestarted: (
+    id: string,
+    listener: (info: { exitCode: number; signal?: number; restarts: number }) => void
+  ) => () => void;
+  onPtyTitle: (id: string, listener: (title: string) => void) => () => void;
+  onPtyInputLimited: (
+    listener: (data: { id: string; code: 'rate-limited'; reason: 'messages' | 'bytes' }) => void
   ) => () => void;
   // Worktree management
   worktreeCreate: (args: {
     projectPath: string;
     workspaceName: string;
     projectId: string;
+    copyFiles?: string[];
+    ticket?: string;
   }) => Promise<{ success: boolean; worktree?: any; error?: string }>;
-  worktreeList: (args: {
+  worktreeCreateBatch: (args: {
     projectPath: string;
-  }) => Promise<{ success: boolean; worktrees?: any[]; error?: string }>;
+    workspaceNames: string[];
+    projectId: string;
+    concurrency?: number;
+    copyFiles?: string[];
+  }) => Promise<{
+    success: boolean;
+    results?: Array<{
+      workspaceName: string;
+      success: boolean;
+      worktree?: any;
+      error?: string;
+    }>;
+    error?: string;
+  }>;
+  worktreeList: (args: {
+    projectPath?: string;
+    projectId?: string;
+    name?: string;
+    branch?: string;
+    includeStatus?: boolean;
+    pageSize?: number;
+    pageToken?: string;
+  }) => Promise<{
+    success: boolean;
+    worktrees?: any[];
+    nextPageToken?: string;
+    error?: string;
+  }>;
   worktreeRemove: (args: {
     projectPath: string;
     worktreeId: string;
-  }) => Promise<{ success: boolean; error?: string }>;
+  }) => Promise<{ success: boolean; branchDeleted?: boolean; error?: string }>;
   worktreeStatus: (args: {
     worktreePath: string;
   }) => Promise<{ success: boolean; status?: any; error?: string }>;
@@ -420,10 +1034,155 @@ export interface ElectronAPI {
     projectPath: string;
     worktreeId: string;
   }) => Promise<{ success: boolean; error?: string }>;
+  worktreeApproveMerge: (args: {
+    projectPath: string;
+    worktreeId?: string;
+    worktreePath?: string;
+    branch?: string;
+  }) => Promise<{ success: boolean; branch?: string; commit?: string; error?: string }>;
+  worktreeMergeToBase: (args: {
+    projectPath: string;
+    worktreeId?: string;
+    worktreePath?: string;
+    branch?: string;
+    baseBranch?: string;
+    strategy?: 'fast-forward' | 'merge' | 'squash';
+    commitMessage?: string;
+  }) => Promise<{
+    success: boolean;
+    result?: {
+      merged: boolean;
+      branch: string;
+      baseBranch: string;
+      strategy: 'fast-forward' | 'merge' | 'squash';
+      conflicts: string[];
+      gates: Array<{
+        id: string;
+        kind: 'command' | 'approval';
+        status: 'pending' | 'running' | 'passed' | 'failed';
+        output?: string;
+        durationMs?: number;
+      }>;
+      output?: string;
+    };
+    error?: string;
+  }>;
+  worktreeSync: (args: {
+    worktreePath: string;
+    baseBranch?: string;
+    strategy?: 'rebase' | 'merge';
+    remote?: string;
+  }) => Promise<{
+    success: boolean;
+    result?: {
+      synced: boolean;
+      strategy: 'rebase' | 'merge';
+      upstream: string;
+      conflicts: string[];
+      output?: string;
+    };
+    error?: string;
+  }>;
+  onWorktreeSyncProgress: (
+    listener: (data: {
+      worktreePath: string;
+      step: 'fetch' | 'rebase' | 'merge' | 'done';
+      message: string;
+    }) => void
+  ) => () => void;
+  worktreeRebasePlanGet: (args: { worktreePath: string; base?: string }) => Promise<{
+    success: boolean;
+    plan?: {
+      base: string;
+      entries: Array<{
+        action: 'pick' | 'reword' | 'squash' | 'fixup' | 'drop';
+        sha: string;
+        subject: string;
+        message?: string;
+      }>;
+    };
+    error?: string;
+  }>;
+  worktreeRebasePlanExecute: (args: {
+    worktreePath: string;
+    plan: {
+      base: string;
+      entries: Array<{
+        action: 'pick' | 'reword' | 'squash' | 'fixup' | 'drop';
+        sha: string;
+        subject: string;
+        message?: string;
+      }>;
+    };
+  }) => Promise<{
+    success: boolean;
+    result?: { success: boolean; conflicts: string[]; output?: string };
+    error?: string;
+  }>;
+  worktreeGcDryRun: (args?: { staleAfterDays?: number }) => Promise<{
+    success: boolean;
+    stale?: Array<{
+      workspaceId: string;
+      projectId: string;
+      projectPath: string;
+      name: string;
+      branch: string;
+      path: string;
+      lastActivityAt: string;
+      dirty: boolean;
+    }>;
+    error?: string;
+  }>;
+  worktreeGcSweep: () => Promise<{ success: boolean; events?: any[]; error?: string }>;
+  onWorktreeGcAudit: (
+    listener: (event: {
+      action: 'flagged' | 'removed' | 'skipped' | 'failed';
+      workspaceId: string;
+      path: string;
+      branch: string;
+      lastActivityAt: string;
+      reason?: string;
+      at: string;
+    }) => void
+  ) => () => void;
+  onWorkspaceArtifactDetected: (
+    listener: (event: {
+      worktreePath: string;
+      workspaceId?: string;
+      kind: 'plan' | 'test-report' | 'agent-result' | 'coverage';
+      path: string;
+      metadata: Record<string, unknown>;
+      detectedAt: string;
+    }) => void
+  ) => () => void;
+  worktreePreviewBranchName: (args: {
+    projectPath: string;
+    workspaceName: string;
+    template?: string;
+  }) => Promise<{ success: boolean; branch?: string; valid?: boolean; error?: string }>;
   worktreeGet: (args: {
     worktreeId: string;
   }) => Promise<{ success: boolean; worktree?: any; error?: string }>;
   worktreeGetAll: () => Promise<{ success: boolean; worktrees?: any[]; error?: string }>;
+  onWorktreeEvent: (
+    listener: (event: {
+      type: 'created' | 'removed' | 'dirty-changed';
+      payload: any;
+    }) => void
+  ) => () => void;
+  onWorktreeMergeGate: (
+    listener: (data: {
+      worktreePath?: string;
+      branch: string;
+      gate: {
+        id: string;
+        kind: 'command' | 'approval';
+        status: 'pending' | 'running' | 'passed' | 'failed';
+        output?: string;
+        durationMs?: number;
+      };
+    }) => void
+  ) => () => void;
 
   // Project management
   openProject: () => Promise<{ success: boolean; path?: string; error?: string }>;
@@ -456,7 +1215,15 @@ export interface ElectronAPI {
     commitMessage?: string;
     createBranchIfOnDefault?: boolean;
     branchPrefix?: string;
-  }) => Promise<{ success: boolean; branch?: string; output?: string; error?: string }>;
+    autoCommitGenerated?: boolean;
+  }) => Promise<{
+    success: boolean;
+    branch?: string;
+    output?: string;
+    proposedMessage?: string;
+    committed?: boolean;
+    error?: string;
+  }>;
   createPullRequest: (args: {
     workspacePath: string;
     title?: string;
@@ -470,6 +1237,10 @@ export interface ElectronAPI {
   connectToGitHub: (
     projectPath: string
   ) => Promise<{ success: boolean; repository?: string; branch?: string; error?: string }>;
+  notificationsRecent: (args?: {
+    limit?: number;
+  }) => Promise<{ success: boolean; notifications?: AppNotification[]; error?: string }>;
+  onAppNotification: (listener: (notification: AppNotification) => void) => () => void;
 
   // Filesystem helpers
   fsList: (
@@ -609,7 +1380,8 @@ export interface ElectronAPI {
     conversationId?: string
   ) => Promise<{ success: boolean; error?: string }>;
   codexStopStream: (
-    workspaceId: string
+    workspaceId: string,
+    kill?: KillOptions
   ) => Promise<{ success: boolean; stopped?: boolean; error?: string }>;
   codexGetStreamTail: (
     workspaceId: string
@@ -634,6 +1406,8 @@ export interface ElectronAPI {
       output: string;
       agentId: string;
       conversationId?: string;
+      seq?: number;
+      stream?: 'stdout';
     }) => void
   ) => () => void;
   onCodexStreamError: (
@@ -642,6 +1416,8 @@ export interface ElectronAPI {
       error: string;
       agentId: string;
       conversationId?: string;
+      seq?: number;
+      stream?: 'stderr';
     }) => void
   ) => () => void;
   onCodexStreamComplete: (
@@ -657,20 +1433,92 @@ export interface ElectronAPI {
   agentCheckInstallation: (
     providerId: 'codex' | 'claude'
   ) => Promise<{ success: boolean; isInstalled?: boolean; error?: string }>;
+  agentDiscoverProviders: () => Promise<{
+    success: boolean;
+    providers?: Array<{
+      name: string;
+      path: string;
+      version: string | null;
+      supported: boolean;
+      allowed: boolean;
+    }>;
+    error?: string;
+  }>;
   agentGetInstallationInstructions: (
     providerId: 'codex' | 'claude'
   ) => Promise<{ success: boolean; instructions?: string; error?: string }>;
   agentSendMessageStream: (args: {
-    providerId: 'codex' | 'claude';
+    providerId?: 'codex' | 'claude';
     workspaceId: string;
     worktreePath: string;
     message: string;
     conversationId?: string;
-  }) => Promise<{ success: boolean; error?: string }>;
+    envProfile?: string;
+    agentId?: string;
+    restartPolicy?: {
+      mode: 'never' | 'on-failure';
+      maxRetries?: number;
+      backoffMs?: number;
+    };
+    usePty?: boolean;
+    resourceLimits?: { maxMemoryMb?: number; maxCpuPercent?: number };
+    queue?: boolean;
+    maxRuntimeMs?: number;
+    secrets?: Record<string, string>;
+    preset?: string;
+    requireApproval?: boolean;
+  }) => Promise<{ success: boolean; error?: string; messageId?: string; queued?: boolean }>;
   agentStopStream: (args: {
     providerId: 'codex' | 'claude';
     workspaceId: string;
+    kill?: KillOptions;
+    agentId?: string;
   }) => Promise<{ success: boolean; error?: string }>;
+  agentInterrupt: (args: {
+    providerId: 'codex' | 'claude';
+    workspaceId: string;
+    agentId?: string;
+  }) => Promise<{ success: boolean; interrupted?: boolean; error?: string }>;
+  agentGetLogs: (args: {
+    providerId: 'codex' | 'claude';
+    workspaceId: string;
+    offset?: number;
+    limit?: number;
+    agentId?: string;
+  }) => Promise<{
+    success: boolean;
+    chunks?: Array<{
+      seq: number;
+      at: string;
+      stream: 'stdout' | 'stderr';
+      text: string;
+    }>;
+    firstSeq?: number;
+    nextOffset?: number;
+    done?: boolean;
+    error?: string;
+  }>;
+  agentAttach: (args: {
+    providerId: 'codex' | 'claude';
+    workspaceId: string;
+    fromSeq?: number;
+    agentId?: string;
+  }) => Promise<{
+    success: boolean;
+    replay?: {
+      chunks: Array<{ seq: number; at: string; stream: 'stdout' | 'stderr'; text: string }>;
+      firstSeq: number;
+      nextOffset: number;
+      done: boolean;
+    };
+    attachedClients?: number;
+    error?: string;
+  }>;
+  agentDetach: (args: {
+    providerId: 'codex' | 'claude';
+    workspaceId: string;
+    agentId?: string;
+  }) => Promise<{ success: boolean; attachedClients?: number; error?: string }>;
   onAgentStreamOutput: (
     listener: (data: {
       providerId: 'codex' | 'claude';
@@ -678,10 +1526,18 @@ export interface ElectronAPI {
       output?: string;
       agentId?: string;
       conversationId?: string;
+      seq?: number;
+      stream?: 'stdout';
     }) => void
   ) => () => void;
   onAgentStreamError: (
-    listener: (data: { providerId: 'codex' | 'claude'; workspaceId: string; error: string }) => void
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      error: string;
+      seq?: number;
+      stream?: 'stderr';
+    }) => void
   ) => () => void;
   onAgentStreamComplete: (
     listener: (data: {
@@ -690,6 +1546,134 @@ export interface ElectronAPI {
       exitCode: number;
     }) => void
   ) => () => void;
+  onAgentGuardrail: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      level: 'warn' | 'stop';
+      stats: { files: number; insertions: number; deletions: number };
+      limits: { warnLines: number; warnFiles: number; stopLines: number; stopFiles: number };
+    }) => void
+  ) => () => void;
+  onAgentRestarting: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      attempt: number;
+      maxRetries: number;
+      delayMs: number;
+    }) => void
+  ) => () => void;
+  onAgentEvent: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      event: AgentEvent;
+    }) => void
+  ) => () => void;
+  onAgentMessageStatus: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      messageId: string;
+      status: 'queued' | 'delivered' | 'dropped';
+      position?: number;
+      reason?: 'concurrency'; // waiting for a slot rather than for the session
+      error?: string;
+    }) => void
+  ) => () => void;
+  onAgentStall: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      stalled: boolean;
+      silentMs: number;
+    }) => void
+  ) => () => void;
+  onAgentTimedOut: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      maxRuntimeMs: number;
+    }) => void
+  ) => () => void;
+  onAgentInterrupted: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+    }) => void
+  ) => () => void;
+  onAgentStatus: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      status: 'starting' | 'running' | 'stalled' | 'stopping' | 'stopped' | 'error' | 'timed-out';
+      previous?:
+        | 'starting'
+        | 'running'
+        | 'stalled'
+        | 'stopping'
+        | 'stopped'
+        | 'error'
+        | 'timed-out';
+      exitCode?: number | null;
+      error?: string;
+      silentMs?: number; // with 'stalled'
+      reason?: string; // with 'stopping'
+    }) => void
+  ) => () => void;
+  agentFanOut: (args: {
+    projectPath: string;
+    projectId: string;
+    prompt: string;
+    targets: Array<{ workspaceName: string; providerId?: 'codex' | 'claude'; preset?: string }>;
+    concurrency?: number;
+  }) => Promise<{ success: boolean; batch?: FanOutBatch; error?: string }>;
+  agentFanOutStatus: (args: {
+    batchId: string;
+  }) => Promise<{ success: boolean; batch?: FanOutBatch; error?: string }>;
+  onAgentFanOutProgress: (listener: (batch: FanOutBatch) => void) => () => void;
+  agentGetResult: (args: {
+    providerId: 'codex' | 'claude';
+    workspaceId: string;
+    agentId?: string;
+  }) => Promise<{ success: boolean; result?: AgentRunResult; error?: string }>;
+  agentMetrics: () => Promise<{ success: boolean; text?: string; error?: string }>;
+  onAgentResult: (
+    listener: (
+      data: AgentRunResult & {
+        providerId: 'codex' | 'claude';
+        workspaceId: string;
+        agentId?: string;
+      }
+    ) => void
+  ) => () => void;
+  onAgentApprovalRequired: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      approvalId: string;
+      toolName: string;
+      input: unknown;
+    }) => void
+  ) => () => void;
+  onAgentApprovalResolved: (
+    listener: (data: {
+      providerId: 'codex' | 'claude';
+      workspaceId: string;
+      agentId?: string;
+      approvalId: string;
+      approved: boolean;
+    }) => void
+  ) => () => void;
 }
 
 declare global {
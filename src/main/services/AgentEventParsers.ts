@@ -0,0 +1,123 @@
+/**
+ * Typed events extracted from an agent CLI's JSON-lines output, broadcast as `agent:event`
+ * alongside the raw text chunks.
+ */
+export type AgentEvent =
+  | { type: 'assistant-text'; text: string; partial?: boolean }
+  | { type: 'tool-call'; id?: string; name: string; input?: unknown }
+  | { type: 'tool-result'; id?: string; content?: unknown; isError?: boolean }
+  | { type: 'usage'; inputTokens?: number; outputTokens?: number; costUsd?: number }
+  | { type: 'result'; text?: string; isError?: boolean };
+
+export type AgentEventParser = (message: any) => AgentEvent[];
+
+const num = (v: unknown) => (typeof v === 'number' && Number.isFinite(v) ? v : undefined);
+
+function usageEvent(usage: any, costUsd?: unknown): AgentEvent | null {
+  if (!usage && costUsd === undefined) return null;
+  return {
+    type: 'usage',
+    inputTokens: num(usage?.input_tokens),
+    outputTokens: num(usage?.output_tokens),
+    costUsd: num(costUsd),
+  };
+}
+
+/**
+ * Claude Code `--output-format stream-json` (also the shape the SDK yields).
+ */
+const claudeParser: AgentEventParser = (msg) => {
+  const events: AgentEvent[] = [];
+  if (msg?.type === 'stream_event') {
+    const text = msg.event?.delta?.text;
+    if (typeof text === 'string' && text) {
+      events.push({ type: 'assistant-text', text, partial: true });
+    }
+  } else if (msg?.type === 'assistant') {
+    const content = msg.message?.content;
+    for (const block of Array.isArray(content) ? content : []) {
+      if (block?.type === 'text' && block.text) {
+        events.push({ type: 'assistant-text', text: block.text });
+      } else if (block?.type === 'tool_use') {
+        events.push({ type: 'tool-call', id: block.id, name: block.name, input: block.input });
+      }
+    }
+    if (typeof content === 'string' && content) {
+      events.push({ type: 'assistant-text', text: content });
+    }
+  } else if (msg?.type === 'user') {
+    const content = msg.message?.content;
+    for (const block of Array.isArray(content) ? content : []) {
+      if (block?.type === 'tool_result') {
+        events.push({
+          type: 'tool-result',
+          id: block.tool_use_id,
+          content: block.content,
+          isError: !!block.is_error,
+        });
+      }
+    }
+  } else if (msg?.type === 'result') {
+    events.push({
+      type: 'result',
+      text: typeof msg.result === 'string' ? msg.result : undefined,
+      isError: !!msg.is_error,
+    });
+    const usage = usageEvent(msg.usage, msg.total_cost_usd);
+    if (usage) events.push(usage);
+  }
+  return events;
+};
+
+/**
+ * Codex JSONL (`codex exec --json`): item lifecycle events plus turn usage.
+ */
+const codexParser: AgentEventParser = (msg) => {
+  const events: AgentEvent[] = [];
+  const item = msg?.item;
+  if (msg?.type === 'item.completed' && item?.type === 'agent_message' && item.text) {
+    events.push({ type: 'assistant-text', text: item.text });
+  } else if (msg?.type === 'item.started' && item?.type === 'command_execution') {
+    events.push({
+      type: 'tool-call',
+      id: item.id,
+      name: 'shell',
+      input: { command: item.command },
+    });
+  } else if (msg?.type === 'item.completed' && item?.type === 'command_execution') {
+    events.push({
+      type: 'tool-result',
+      id: item.id,
+      content: item.aggregated_output,
+      isError: typeof item.exit_code === 'number' && item.exit_code !== 0,
+    });
+  } else if (msg?.type === 'turn.completed') {
+    const usage = usageEvent(msg.usage);
+    if (usage) events.push(usage);
+  } else if (msg?.type === 'turn.failed') {
+    events.push({ type: 'result', text: msg.error?.message, isError: true });
+  }
+  return events;
+};
+
+const parsers = new Map<string, AgentEventParser>([
+  ['claude', claudeParser],
+  ['codex', codexParser],
+]);
+
+export function registerAgentEventParser(providerId: string, parser: AgentEventParser) {
+  parsers.set(providerId, parser);
+}
+
+/**
+ * Convert one decoded JSON message into typed events; unknown providers yield none.
+ */
+export function parseAgentEvents(providerId: string, message: unknown): AgentEvent[] {
+  const parser = parsers.get(providerId);
+  if (!parser || !message || typeof message !== 'object') return [];
+  try {
+    return parser(message);
+  } catch {
+    return [];
+  }
+}
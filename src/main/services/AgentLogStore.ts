@@ -0,0 +1,120 @@
+import { app } from 'electron';
+import { existsSync, readdirSync, statSync } from 'fs';
+import path from 'path';
+import { log } from '../lib/logger';
+import { pruneLogFiles, RotatingLog } from '../lib/rotatingLog';
+import { getAppSettings } from '../settings';
+
+// Only these subdirectories are ever pruned, so a shared log directory is left alone
+const PROVIDER_DIRS = ['codex', 'claude'];
+const PRUNE_INTERVAL_MS = 60 * 60 * 1000;
+
+export interface AgentLogFile {
+  id: string; // path relative to the log directory; pass back to export
+  providerId: string;
+  workspaceId: string;
+  agentId?: string;
+  name: string; // stream.log, stream.1.log, ...
+  size: number;
+  modifiedAt: string;
+}
+
+/**
+ * Raw per-session agent output on disk, laid out as
+ * `<dir>/<provider>/<workspace>[/<agent>]/stream.log` with size-based rotation
+ * (settings.agentLogs). Unlike the in-memory output buffer, this survives restarts.
+ */
+export class AgentLogStore {
+  private lastPrune = 0;
+
+  baseDir(): string {
+    const configured = getAppSettings().agentLogs.directory;
+    return configured || path.join(app.getPath('userData'), 'logs', 'agent');
+  }
+
+  /** Open the session's current log for appending; earlier runs stay above it. */
+  open(providerId: string, workspaceId: string, agentId?: string): RotatingLog {
+    const { maxFileMb, maxFiles } = getAppSettings().agentLogs;
+    this.maybePrune();
+    const parts = [providerId, workspaceId, ...(agentId ? [agentId] : [])];
+    return new RotatingLog(path.join(this.baseDir(), ...parts, 'stream.log'), {
+      maxBytes: Math.round(maxFileMb * 1024 * 1024),
+      maxFiles,
+    });
+  }
+
+  list(filter: { providerId?: string; workspaceId?: string } = {}): AgentLogFile[] {
+    const base = this.baseDir();
+    const out: AgentLogFile[] = [];
+    for (const providerId of PROVIDER_DIRS) {
+      if (filter.providerId && filter.providerId !== providerId) continue;
+      const providerDir = path.join(base, providerId);
+      for (const workspaceId of this.subdirs(providerDir)) {
+        if (filter.workspaceId && filter.workspaceId !== workspaceId) continue;
+        const wsDir = path.join(providerDir, workspaceId);
+        this.collect(out, wsDir, { providerId, workspaceId });
+        for (const agentId of this.subdirs(wsDir)) {
+          this.collect(out, path.join(wsDir, agentId), { providerId, workspaceId, agentId });
+        }
+      }
+    }
+    return out.sort((a, b) => b.modifiedAt.localeCompare(a.modifiedAt));
+  }
+
+  /** Absolute path of a listed log, or null when the id escapes the log directory. */
+  resolve(id: string): string | null {
+    const base = path.resolve(this.baseDir());
+    const full = path.resolve(base, String(id ?? ''));
+    if (!full.startsWith(base + path.sep) || !full.endsWith('.log')) return null;
+    return existsSync(full) ? full : null;
+  }
+
+  private collect(
+    out: AgentLogFile[],
+    dir: string,
+    tag: { providerId: string; workspaceId: string; agentId?: string }
+  ) {
+    let names: string[] = [];
+    try {
+      names = readdirSync(dir).filter((n) => n.endsWith('.log'));
+    } catch {
+      return;
+    }
+    for (const name of names) {
+      try {
+        const st = statSync(path.join(dir, name));
+        out.push({
+          id: path.relative(this.baseDir(), path.join(dir, name)),
+          ...tag,
+          name,
+          size: st.size,
+          modifiedAt: st.mtime.toISOString(),
+        });
+      } catch {}
+    }
+  }
+
+  private subdirs(dir: string): string[] {
+    try {
+      return readdirSync(dir, { withFileTypes: true })
+        .filter((d) => d.isDirectory())
+        .map((d) => d.name);
+    } catch {
+      return [];
+    }
+  }
+
+  private maybePrune() {
+    const { retentionDays } = getAppSettings().agentLogs;
+    const now = Date.now();
+    if (!retentionDays || now - this.lastPrune < PRUNE_INTERVAL_MS) return;
+    this.lastPrune = now;
+    const maxAgeMs = retentionDays * 24 * 60 * 60 * 1000;
+    for (const providerId of PROVIDER_DIRS) {
+      const removed = pruneLogFiles(path.join(this.baseDir(), providerId), maxAgeMs, now);
+      if (removed > 0) log.info(`Pruned ${removed} agent log file(s) for ${providerId}`);
+    }
+  }
+}
+
+export const agentLogStore = new AgentLogStore();
@@ -0,0 +1,111 @@
+import http from 'http';
+import { log } from '../lib/logger';
+import { metricsRegistry } from '../lib/metrics';
+import { getAppSettings } from '../settings';
+import { agentService } from './AgentService';
+import { codexService } from './CodexService';
+
+/**
+ * Agent health metrics labelled by provider, fed from AgentService events and exposed in
+ * Prometheus text format on `127.0.0.1:<settings.metrics.port>/metrics` when enabled.
+ */
+export class AgentMetrics {
+  readonly registry = metricsRegistry;
+  private readonly starts = this.registry.counter(
+    'emdash_agent_starts_total',
+    'Agent runs started.'
+  );
+  private readonly failures = this.registry.counter(
+    'emdash_agent_failures_total',
+    'Agent runs that ended in an error.'
+  );
+  private readonly duration = this.registry.histogram(
+    'emdash_agent_run_duration_seconds',
+    'Wall time of finished agent runs.'
+  );
+  private readonly outputBytes = this.registry.counter(
+    'emdash_agent_output_bytes_total',
+    'Bytes of agent output, by stream.'
+  );
+  private readonly tokens = this.registry.counter(
+    'emdash_agent_tokens_total',
+    'Tokens reported by agent usage events, by type.'
+  );
+  private readonly cost = this.registry.counter(
+    'emdash_agent_cost_usd_total',
+    'Cost reported by agent usage events, in USD.'
+  );
+  // Start time of the run currently tracked for each session
+  private startedAt = new Map<string, number>();
+  private server: http.Server | null = null;
+  private started = false;
+
+  start() {
+    if (this.started) return;
+    this.started = true;
+
+    agentService.on('agent:status', (data: any) => {
+      const provider = String(data?.providerId ?? 'unknown');
+      const k = `${provider}:${data?.workspaceId}:${data?.agentId ?? ''}`;
+      if (data?.status === 'starting') {
+        this.starts.inc({ provider });
+        this.startedAt.set(k, Date.now());
+      } else if (['stopped', 'error', 'timed-out'].includes(data?.status)) {
+        if (data.status !== 'stopped') this.failures.inc({ provider });
+        const began = this.startedAt.get(k);
+        this.startedAt.delete(k);
+        if (began !== undefined) {
+          this.duration.observe({ provider, outcome: data.status }, (Date.now() - began) / 1000);
+        }
+      }
+    });
+    agentService.on('agent:output', (data: any) => {
+      this.countOutput(data?.providerId, 'stdout', data?.output);
+    });
+    agentService.on('agent:error', (data: any) => {
+      if (data?.stream === 'stderr') this.countOutput(data.providerId, 'stderr', data.error);
+    });
+    // Codex output is bridged to the renderer without passing through agent:output
+    codexService.on('codex:output', (data: any) => {
+      this.countOutput('codex', 'stdout', data?.output);
+    });
+    codexService.on('codex:error', (data: any) => {
+      if (data?.stream === 'stderr') this.countOutput('codex', 'stderr', data.error);
+    });
+    agentService.on('agent:event', (data: any) => {
+      const event = data?.event;
+      if (event?.type !== 'usage') return;
+      const provider = String(data.providerId ?? 'unknown');
+      if (event.inputTokens) this.tokens.inc({ provider, type: 'input' }, event.inputTokens);
+      if (event.outputTokens) this.tokens.inc({ provider, type: 'output' }, event.outputTokens);
+      if (event.costUsd) this.cost.inc({ provider }, event.costUsd);
+    });
+
+    const { enabled, port } = getAppSettings().metrics;
+    if (enabled) this.listen(port);
+  }
+
+  private countOutput(providerId: unknown, stream: string, text: unknown) {
+    if (typeof text !== 'string' || !text) return;
+    const provider = String(providerId ?? 'unknown');
+    this.outputBytes.inc({ provider, stream }, Buffer.byteLength(text));
+  }
+
+  private listen(port: number) {
+    this.server = http.createServer((req, res) => {
+      if (req.method !== 'GET' || (req.url ?? '').split('?')[0] !== '/metrics') {
+        res.writeHead(404).end();
+        return;
+      }
+      res.writeHead(200, { 'Content-Type': 'text/plain; version=0.0.4; charset=utf-8' });
+      res.end(this.registry.render());
+    });
+    this.server.on('error', (error) => {
+      log.warn(`Metrics endpoint unavailable on port ${port}:`, error);
+    });
+    this.server.listen(port, '127.0.0.1');
+    this.server.unref();
+  }
+}
+
+export const agentMetrics = new AgentMetrics();
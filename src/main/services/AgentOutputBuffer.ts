@@ -0,0 +1,65 @@
+const MAX_CHUNKS = 5000;
+const MAX_BYTES = 2 * 1024 * 1024;
+
+export type AgentOutputStream = 'stdout' | 'stderr';
+
+export interface AgentOutputChunk {
+  seq: number; // monotonically increasing per session, continuing across runs
+  at: string;
+  stream: AgentOutputStream;
+  text: string;
+}
+
+export interface AgentLogsPage {
+  chunks: AgentOutputChunk[];
+  // First seq still held; anything below it was evicted to respect the bounds
+  firstSeq: number;
+  nextOffset: number; // pass back as `offset` to continue
+  done: boolean; // no more chunks currently buffered past nextOffset
+}
+
+/**
+ * Bounded in-memory record of an agent run's output so a renderer that attaches late
+ * (or reloads) can backfill what it missed. Oldest chunks are evicted first.
+ */
+export class AgentOutputBuffer {
+  private chunks: AgentOutputChunk[] = [];
+  private bytes = 0;
+  private nextSeq: number;
+
+  constructor(startSeq = 0) {
+    this.nextSeq = startSeq;
+  }
+
+  /** Seq the next chunk will get; a new run's buffer starts here to keep seqs increasing. */
+  nextSequence(): number {
+    return this.nextSeq;
+  }
+
+  /**
+   * Record a chunk and return its seq. Producers that number chunks themselves pass `seq`
+   * so buffered and live frames agree; it never moves backwards.
+   */
+  append(text: string, stream: AgentOutputStream = 'stdout', seq?: number): number | undefined {
+    if (!text) return undefined;
+    const assigned = seq !== undefined && seq >= this.nextSeq ? seq : this.nextSeq;
+    this.nextSeq = assigned + 1;
+    this.chunks.push({ seq: assigned, at: new Date().toISOString(), stream, text });
+    this.bytes += Buffer.byteLength(text);
+    while (this.chunks.length > MAX_CHUNKS || (this.bytes > MAX_BYTES && this.chunks.length > 1)) {
+      const dropped = this.chunks.shift()!;
+      this.bytes -= Buffer.byteLength(dropped.text);
+    }
+    return assigned;
+  }
+
+  read(offset = 0, limit = 500): AgentLogsPage {
+    const firstSeq = this.chunks.length ? this.chunks[0].seq : this.nextSeq;
+    const from = Math.max(offset, firstSeq);
+    let start = this.chunks.findIndex((c) => c.seq >= from);
+    if (start < 0) start = this.chunks.length;
+    const page = this.chunks.slice(start, start + Math.max(1, Math.min(limit, MAX_CHUNKS)));
+    const nextOffset = page.length ? page[page.length - 1].seq + 1 : from;
+    return { chunks: page, firstSeq, nextOffset, done: nextOffset >= this.nextSeq };
+  }
+}
@@ -0,0 +1,32 @@
+import { readFileSync } from 'fs';
+import { getAppSettings, type AgentPresetConfig } from '../settings';
+
+export function findAgentPreset(name?: string | null): AgentPresetConfig | null {
+  const wanted = typeof name === 'string' ? name.trim() : '';
+  if (!wanted) return null;
+  const preset = getAppSettings().agentPresets.presets.find((p) => p.name === wanted);
+  if (!preset) throw new Error(`Unknown agent preset: ${wanted}`);
+  return preset;
+}
+
+/**
+ * CLI args a preset adds to a run: its own args, then the model and system prompt flags
+ * for the preset's provider. The prompt file is read on every launch so edits apply.
+ */
+export function presetCliArgs(preset: AgentPresetConfig): string[] {
+  const args = [...preset.args];
+  if (preset.model) args.push('--model', preset.model);
+  if (preset.systemPromptFile) {
+    if (preset.providerId !== 'claude') {
+      throw new Error(`Preset ${preset.name}: system prompt files are only supported for Claude`);
+    }
+    let prompt: string;
+    try {
+      prompt = readFileSync(preset.systemPromptFile, 'utf8');
+    } catch (e: any) {
+      throw new Error(`Preset ${preset.name}: cannot read system prompt file: ${e?.message || e}`);
+    }
+    args.push('--append-system-prompt', prompt);
+  }
+  return args;
+}
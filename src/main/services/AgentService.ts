@@ -1,14 +1,44 @@
 import { EventEmitter } from 'events';
+import crypto from 'crypto';
 import { ChildProcess, spawn, execFile } from 'child_process';
 import { promisify } from 'util';
-import { app } from 'electron';
-import path from 'path';
-import { existsSync, mkdirSync, createWriteStream, WriteStream } from 'fs';
 import { codexService } from './CodexService';
+import { databaseService } from './DatabaseService';
+import { scratchService } from './ScratchService';
+import { dependencyCacheService } from './DependencyCacheService';
+import { startPty } from './ptyManager';
+import { resolveEnvProfile } from './EnvProfiles';
+import { secretStore } from './SecretStore';
+import { claudeMcpArgs, claudeMcpServers, mcpServersFor } from './McpConfig';
+import { findAgentPreset, presetCliArgs } from './AgentPresets';
+import { AgentOutputBuffer, type AgentLogsPage } from './AgentOutputBuffer';
+import { agentLogStore } from './AgentLogStore';
+import { summarizeDiff, type DiffSummary } from './DiffSummary';
+import { parseAgentEvents, type AgentEvent } from './AgentEventParsers';
+import { assertRequiredEnv, resolveProvider, type ProviderId } from './ProviderRegistry';
+import { getAppSettings } from '../settings';
+import {
+  normalizeResourceLimits,
+  watchResourceUsage,
+  wrapWithCgroup,
+  type ResourceLimits,
+} from '../lib/resourceLimits';
+import { resolveKillOptions, terminateProcess, type KillOptions } from '../lib/processKill';
+import type { RotatingLog } from '../lib/rotatingLog';
+import {
+  evaluateDiffGuardrails,
+  getBaselineRef,
+  guardrailsEnabled,
+  measureDiff,
+  GuardrailLevel,
+} from './DiffGuardrails';
 
 const execFileAsync = promisify(execFile);
 
-export type ProviderId = 'codex' | 'claude';
+const GUARDRAIL_CHECK_INTERVAL_MS = 5000;
+const STALL_CHECK_INTERVAL_MS = 5000;
+
+export type { ProviderId };
 
 export interface AgentStartOptions {
   providerId: ProviderId;
@@ -16,38 +46,603 @@ export interface AgentStartOptions {
   worktreePath: string;
   message: string;
   conversationId?: string;
+  envProfile?: string; // name of a settings env profile layered over the agent's env
+  // Names one of several concurrent sessions in the same workspace (e.g. 'coder', 'reviewer').
+  // Runs without an agentId keep the one-agent-per-workspace behaviour.
+  agentId?: string;
+  restartPolicy?: AgentRestartPolicy;
+  // Run the CLI under a pseudo-terminal instead of pipes (Claude CLI only)
+  usePty?: boolean;
+  // Kill and report the run if it goes over these (Claude CLI only)
+  resourceLimits?: ResourceLimits;
+  // Wait for the session's current run to finish instead of replacing it
+  queue?: boolean;
+  // Stop each run after this long and report it as timed out; restarts are not attempted
+  maxRuntimeMs?: number;
+  // Env vars filled from the keychain secret store, e.g. { ANTHROPIC_API_KEY: 'anthropic' }
+  secrets?: Record<string, string>;
+  // Settings preset supplying provider, extra args, model, system prompt and env profile
+  preset?: string;
+  // Pause on settings.agentApproval tools until approveAction (Claude SDK runs only)
+  requireApproval?: boolean;
+}
+
+// What callers send: the provider may be left to the preset
+export type AgentStartRequest = Omit<AgentStartOptions, 'providerId'> & {
+  providerId?: ProviderId;
+};
+
+/**
+ * Re-run the request when the agent CLI exits with a failure. Delays start at `backoffMs`
+ * and double per attempt; user stops and guardrail stops are never restarted.
+ */
+export interface AgentRestartPolicy {
+  mode: 'never' | 'on-failure';
+  maxRetries?: number; // default 3
+  backoffMs?: number; // default 2000
+}
+
+export type AgentStatus =
+  | 'starting'
+  | 'running'
+  | 'stalled'
+  | 'stopping'
+  | 'stopped'
+  | 'error'
+  | 'timed-out';
+
+export interface AgentSessionInfo {
+  providerId: ProviderId;
+  workspaceId: string;
+  agentId?: string;
+  running: boolean;
+  restarts: number;
+  queued: number;
+  stalled: boolean;
+  timedOut: boolean;
+  remainingMs?: number; // runtime left for a running session with maxRuntimeMs
+  status?: AgentStatus;
+  attachedClients: number;
+  pendingApprovals: number;
+  startQueuePosition?: number; // 1-based place among starts waiting for a concurrency slot
+}
+
+type RestartState = {
+  opts: AgentStartOptions;
+  maxRetries: number;
+  backoffMs: number;
+  restarts: number;
+  runId: number;
+  launching?: boolean;
+  timer?: NodeJS.Timeout;
+  interrupted?: boolean; // the current run was interrupted on purpose; don't restart it
+};
+
+// Parsed response of one Claude run, saved to its conversation when the run ends
+type RunTranscript = {
+  runId: number;
+  conversationId: string;
+  texts: string[];
+  result?: string;
+  toolCalls: Array<{ id?: string; name: string; isError?: boolean }>;
+  usage?: { inputTokens?: number; outputTokens?: number; costUsd?: number };
+};
+
+type RuntimeLimit = {
+  deadline: number;
+  timer?: NodeJS.Timeout; // cleared once the run ends
+  runId?: number;
+  timedOut: boolean;
+};
+
+type QueuedMessage = {
+  messageId: string;
+  opts: AgentStartOptions;
+};
+
+/**
+ * Outcome of a session's latest run, including what it changed in the worktree.
+ */
+export interface AgentRunResult {
+  status: 'stopped' | 'error' | 'timed-out';
+  exitCode?: number | null;
+  error?: string;
+  finishedAt: string;
+  diff?: DiffSummary; // absent when the start commit couldn't be resolved
+  diffError?: string;
+}
+
+type PendingApproval = {
+  k: string;
+  toolName: string;
+  resolve: (decision: { approved: boolean; message?: string }) => void;
+};
+
+// Tools a Claude run may use without asking
+const CLAUDE_EDIT_TOOLS = ['Edit', 'MultiEdit', 'Write', 'Read'];
+
+const MAX_RESTART_BACKOFF_MS = 60_000;
+
+// Loaded lazily so the build does not require the SDK
+function loadClaudeSdk(): any {
+  try {
+    // eslint-disable-next-line @typescript-eslint/no-var-requires
+    return require('@anthropic/claude-code-sdk');
+  } catch {
+    return null;
+  }
 }
 
 export class AgentService extends EventEmitter {
-  private processes = new Map<string, ChildProcess>(); // key: providerId:workspaceId
-  private writers = new Map<string, WriteStream>();
+  private processes = new Map<string, ChildProcess>(); // key: providerId:workspaceId[:agentId]
+  private writers = new Map<string, RotatingLog>();
+  // Diff guardrail monitors, keyed by workspaceId
+  private guards = new Map<string, { timer?: NodeJS.Timeout; level: GuardrailLevel }>();
+  // Output of the latest run per provider/workspace, for late-attaching renderers
+  private outputs = new Map<string, AgentOutputBuffer>();
+  // Sessions started with an on-failure restart policy, keyed like `processes`
+  private restartStates = new Map<string, RestartState>();
+  private runSeq = 0;
+  // Latest run id per session, so exits of replaced runs don't report a status
+  private latestRuns = new Map<string, number>();
+  private statuses = new Map<string, AgentStatus>();
+  // Sessions whose current run was stopped or interrupted on request; it ends as 'stopped'
+  private stopRequested = new Set<string>();
+  // Messages waiting for the session's current run to end, keyed like `processes`
+  private queues = new Map<string, QueuedMessage[]>();
+  // Starts waiting for a free slot under settings.agentConcurrency, oldest first
+  private startQueue: Array<QueuedMessage & { sessionKey: string }> = [];
+  // Sessions between deliver() and their process existing; they already hold a slot
+  private starting = new Set<string>();
+  private transcripts = new Map<string, RunTranscript>();
+  // Incomplete trailing line of codex stdout, per workspace, awaiting JSONL parsing
+  private codexPartials = new Map<string, string>();
+  // Last output time per running session, for settings.agentStall
+  private activity = new Map<string, { at: number; stalled: boolean }>();
+  private stallTimer?: NodeJS.Timeout;
+  // Max-runtime deadlines of the latest run per session
+  private runtimes = new Map<string, RuntimeLimit>();
+  // Renderer clients (webContents ids) following each session; runs never depend on these
+  private attachments = new Map<string, Set<number>>();
+  // Tool calls held until the user approves or denies them, by approval id
+  private approvals = new Map<string, PendingApproval>();
+  // Commit each session's latest run started from, for the diff summary when it ends
+  private baselines = new Map<string, { worktreePath: string; baseRef: string }>();
+  private results = new Map<string, AgentRunResult>();
+
+  constructor() {
+    super();
+    // Codex runs are owned by codexService; release their guard when the turn ends
+    codexService.on('codex:complete', (data: any) => {
+      if (data?.workspaceId) {
+        this.releaseGuard(data.workspaceId);
+        this.codexPartials.delete(data.workspaceId);
+        // A replaced turn completes after its successor opened the log; leave that one open
+        if (!codexService.isStreaming(data.workspaceId)) {
+          const k = this.key('codex', data.workspaceId);
+          this.append(k, `\n[COMPLETE] exit code ${data.exitCode ?? null}\n`);
+          this.writers.get(k)?.end();
+          this.writers.delete(k);
+        }
+        this.onRunEnded(this.key('codex', data.workspaceId), data.exitCode !== 0, undefined, {
+          exitCode: data.exitCode ?? null,
+        });
+      }
+    });
+    codexService.on('codex:output', (data: any) => {
+      if (data?.workspaceId && typeof data.output === 'string') {
+        const buffer = this.outputs.get(this.key('codex', data.workspaceId));
+        buffer?.append(data.output, 'stdout', data.seq);
+        this.append(this.key('codex', data.workspaceId), data.output);
+        this.touch(this.key('codex', data.workspaceId));
+        this.parseCodexLines(data.workspaceId, data.output);
+      }
+    });
+    codexService.on('codex:error', (data: any) => {
+      if (data?.stream === 'stderr' && data.workspaceId && typeof data.error === 'string') {
+        const buffer = this.outputs.get(this.key('codex', data.workspaceId));
+        buffer?.append(data.error, 'stderr', data.seq);
+        this.append(this.key('codex', data.workspaceId), `[stderr] ${data.error}`);
+      }
+    });
+  }
+
+  /**
+   * Buffer a chunk and broadcast it with its session seq, so a client that reconnects can
+   * resume through getLogs(offset = last seq + 1) without gaps or duplicates.
+   */
+  private emitOutput(k: string, tag: Record<string, string>, output: string) {
+    const seq = this.outputs.get(k)?.append(output, 'stdout');
+    this.touch(k);
+    this.emit('agent:output', { ...tag, output, stream: 'stdout', seq });
+  }
+
+  private isRunning(k: string): boolean {
+    const [providerId, workspaceId] = k.split(':');
+    return providerId === 'codex' ? codexService.isStreaming(workspaceId) : this.processes.has(k);
+  }
+
+  /** Record output (or a fresh start) for stall detection; clears a reported stall. */
+  private touch(k: string) {
+    const prev = this.activity.get(k);
+    this.activity.set(k, { at: Date.now(), stalled: false });
+    if (prev?.stalled) {
+      this.emit('agent:stall', { ...this.tagOfKey(k), stalled: false, silentMs: 0 });
+      if (this.statuses.get(k) === 'stalled') this.setStatus(k, 'running');
+    }
+    if (!this.stallTimer) {
+      this.stallTimer = setInterval(() => this.checkStalls(), STALL_CHECK_INTERVAL_MS);
+      this.stallTimer.unref?.();
+    }
+  }
+
+  private checkStalls() {
+    const { timeoutMs, autoTerminate } = getAppSettings().agentStall;
+    const now = Date.now();
+    for (const [k, entry] of this.activity) {
+      if (!this.isRunning(k)) {
+        this.activity.delete(k);
+        continue;
+      }
+      // Waiting on the user is not a stall; silence counts from when the approval settles
+      if (this.hasPendingApproval(k)) {
+        entry.at = now;
+        continue;
+      }
+      const silentMs = now - entry.at;
+      if (!timeoutMs || entry.stalled || silentMs < timeoutMs) continue;
+      entry.stalled = true;
+      const tag = this.tagOfKey(k);
+      this.emit('agent:stall', { ...tag, stalled: true, silentMs });
+      this.setStatus(k, 'stalled', { silentMs });
+      if (autoTerminate) {
+        const reason = `no output for ${Math.round(silentMs / 1000)}s`;
+        this.append(k, `\n[STALLED] ${reason}\n`);
+        this.emit('agent:error', { ...tag, error: `Agent stopped: ${reason}`, stalled: true });
+        this.terminateSession(k, 'stalled');
+      }
+    }
+    if (this.activity.size === 0 && this.stallTimer) {
+      clearInterval(this.stallTimer);
+      this.stallTimer = undefined;
+    }
+  }
+
+  private armRuntime(k: string, maxRuntimeMs: number, runId?: number) {
+    this.disarmRuntime(k);
+    this.runtimes.delete(k);
+    if (!maxRuntimeMs) return;
+    const entry: RuntimeLimit = { deadline: Date.now() + maxRuntimeMs, runId, timedOut: false };
+    entry.timer = setTimeout(() => {
+      entry.timer = undefined;
+      entry.timedOut = true;
+      // A timeout is final: no restart, but anything queued still runs afterwards
+      this.clearRestart(k);
+      this.append(k, `\n[TIMED OUT] exceeded max runtime of ${maxRuntimeMs}ms\n`);
+      this.emit('agent:timed-out', { ...this.tagOfKey(k), maxRuntimeMs });
+      this.terminateSession(k, 'timed-out');
+    }, maxRuntimeMs);
+    this.runtimes.set(k, entry);
+  }
+
+  private disarmRuntime(k: string, runId?: number) {
+    const entry = this.runtimes.get(k);
+    if (!entry?.timer) return;
+    if (runId !== undefined && entry.runId !== undefined && runId !== entry.runId) return;
+    clearTimeout(entry.timer);
+    entry.timer = undefined;
+  }
+
+  /**
+   * Record a lifecycle transition (starting → running → stopped | error | timed-out) and
+   * broadcast it as `agent:status`, with exit details on the terminal states.
+   */
+  private setStatus(k: string, status: AgentStatus, detail: Record<string, unknown> = {}) {
+    const previous = this.statuses.get(k);
+    if (previous === status) return;
+    this.statuses.set(k, status);
+    this.emit('agent:status', { ...this.tagOfKey(k), status, previous, ...detail });
+  }
+
+  private tagOfKey(k: string) {
+    const [providerId, workspaceId, agentId] = k.split(':');
+    return { providerId, workspaceId, ...(agentId ? { agentId } : {}) };
+  }
+
+  /**
+   * Signal a session's run without the bookkeeping of stopStream, so it ends like a
+   * failure and the restart policy and queue still apply.
+   */
+  private terminateSession(k: string, reason: string) {
+    if (this.isRunning(k)) this.setStatus(k, 'stopping', { reason });
+    const [providerId, workspaceId] = k.split(':');
+    if (providerId === 'codex') {
+      void codexService.stopMessageStream(workspaceId);
+      return;
+    }
+    const proc = this.processes.get(k);
+    if (!proc) return;
+    terminateProcess(
+      {
+        pid: proc.pid,
+        kill: (signal) => proc.kill(signal as NodeJS.Signals),
+        exited: () => proc.exitCode !== null || proc.signalCode !== null,
+      },
+      resolveKillOptions('SIGTERM')
+    );
+  }
+
+  private emitEvents(tag: Record<string, string>, message: unknown) {
+    const k = this.key(tag.providerId as ProviderId, tag.workspaceId, tag.agentId);
+    for (const event of parseAgentEvents(tag.providerId, message)) {
+      this.recordTranscript(k, event);
+      this.emit('agent:event', { ...tag, event });
+    }
+  }
+
+  private recordTranscript(k: string, event: AgentEvent) {
+    const t = this.transcripts.get(k);
+    if (!t) return;
+    if (event.type === 'assistant-text' && !event.partial) {
+      t.texts.push(event.text);
+    } else if (event.type === 'tool-call') {
+      t.toolCalls.push({ id: event.id, name: event.name });
+    } else if (event.type === 'tool-result') {
+      const call = t.toolCalls.find((c) => c.id !== undefined && c.id === event.id);
+      if (call) call.isError = !!event.isError;
+    } else if (event.type === 'usage') {
+      t.usage = { inputTokens: event.inputTokens, outputTokens: event.outputTokens };
+      if (event.costUsd !== undefined) t.usage.costUsd = event.costUsd;
+    } else if (event.type === 'result' && event.text) {
+      t.result = event.text;
+    }
+  }
+
+  /**
+   * Save the run's response to its conversation so history survives restarts and renderer
+   * reloads, mirroring what codexService does for Codex runs.
+   */
+  private persistTranscript(k: string, runId?: number) {
+    const t = this.transcripts.get(k);
+    if (!t || (runId !== undefined && runId !== t.runId)) return;
+    this.transcripts.delete(k);
+    const content = (t.texts.join('\n\n') || t.result || '').trim();
+    if (!content) return;
+    const [providerId, workspaceId, agentId] = k.split(':');
+    databaseService
+      .saveMessage({
+        id: `agent-${Date.now()}`,
+        conversationId: t.conversationId,
+        content,
+        sender: 'agent',
+        metadata: JSON.stringify({
+          workspaceId,
+          providerId,
+          ...(agentId ? { agentId } : {}),
+          toolCalls: t.toolCalls,
+          usage: t.usage,
+        }),
+      })
+      .catch((e) => console.error('Failed to persist agent message on complete:', e));
+  }
+
+  private parseCodexLines(workspaceId: string, chunk: string) {
+    const lines = ((this.codexPartials.get(workspaceId) ?? '') + chunk).split('\n');
+    this.codexPartials.set(workspaceId, lines.pop() ?? '');
+    for (const line of lines) {
+      const trimmed = line.trim();
+      if (!trimmed.startsWith('{')) continue;
+      try {
+        this.emitEvents({ providerId: 'codex', workspaceId }, JSON.parse(trimmed));
+      } catch {}
+    }
+  }
+
+  /** Result of the session's latest finished run, or null if none has finished yet. */
+  getResult(providerId: ProviderId, workspaceId: string, agentId?: string): AgentRunResult | null {
+    const k = this.key(providerId, workspaceId, agentId?.trim() || undefined);
+    return this.results.get(k) ?? null;
+  }
+
+  /**
+   * Summarize the worktree changes since the run's start commit and emit 'agent:result'.
+   * Sessions sharing a worktree see each other's changes in their summaries.
+   */
+  private async publishResult(
+    k: string,
+    status: AgentRunResult['status'],
+    exit: { exitCode?: number | null; error?: string }
+  ) {
+    const result: AgentRunResult = { status, ...exit, finishedAt: new Date().toISOString() };
+    const baseline = this.baselines.get(k);
+    if (baseline) {
+      try {
+        result.diff = await summarizeDiff(baseline.worktreePath, baseline.baseRef);
+      } catch (e: any) {
+        result.diffError = e?.message || String(e);
+      }
+    }
+    this.results.set(k, result);
+    this.emit('agent:result', { ...this.tagOfKey(k), ...result });
+  }
+
+  /**
+   * Buffered output of the most recent run, paged by chunk sequence number.
+   */
+  getLogs(
+    providerId: ProviderId,
+    workspaceId: string,
+    offset?: number,
+    limit?: number,
+    agentId?: string
+  ): AgentLogsPage | null {
+    const buffer = this.outputs.get(this.key(providerId, workspaceId, agentId));
+    return buffer?.read(offset, limit) ?? null;
+  }
+
+  /**
+   * Register a client as following the session and return the buffered output from
+   * `fromSeq` so it can catch up before live frames. Sessions keep running whether or not
+   * anyone is attached; this only tracks who is watching.
+   */
+  attach(
+    providerId: ProviderId,
+    workspaceId: string,
+    clientId: number,
+    fromSeq?: number,
+    agentId?: string
+  ): { replay: AgentLogsPage; attachedClients: number } {
+    const k = this.key(providerId, workspaceId, agentId?.trim() || undefined);
+    const buffer = this.outputs.get(k);
+    if (!buffer) throw new Error('No agent session to attach to');
+    const clients = this.attachments.get(k) ?? new Set<number>();
+    clients.add(clientId);
+    this.attachments.set(k, clients);
+    // read() caps the page at the buffer's bounds, so this is everything still held
+    const replay = buffer.read(fromSeq ?? 0, Number.MAX_SAFE_INTEGER);
+    return { replay, attachedClients: clients.size };
+  }
+
+  /** Stop following the session; returns the number of clients still attached. */
+  detach(providerId: ProviderId, workspaceId: string, clientId: number, agentId?: string): number {
+    const k = this.key(providerId, workspaceId, agentId?.trim() || undefined);
+    const clients = this.attachments.get(k);
+    if (!clients) return 0;
+    clients.delete(clientId);
+    if (clients.size === 0) this.attachments.delete(k);
+    return clients.size;
+  }
+
+  /**
+   * Settle a tool call held by an approval-mode run. Returns false when the id is unknown,
+   * e.g. because the run already ended.
+   */
+  approveAction(approvalId: string, approved: boolean, message?: string): boolean {
+    const pending = this.approvals.get(approvalId);
+    if (!pending) return false;
+    this.approvals.delete(approvalId);
+    pending.resolve({ approved, message });
+    this.touch(pending.k);
+    this.emit('agent:approval-resolved', { ...this.tagOfKey(pending.k), approvalId, approved });
+    return true;
+  }
+
+  private requestApproval(k: string, toolName: string, input: unknown) {
+    const approvalId = crypto.randomUUID();
+    return new Promise<{ approved: boolean; message?: string }>((resolve) => {
+      this.approvals.set(approvalId, { k, toolName, resolve });
+      this.emit('agent:approval-required', { ...this.tagOfKey(k), approvalId, toolName, input });
+    });
+  }
+
+  private hasPendingApproval(k: string): boolean {
+    for (const pending of this.approvals.values()) if (pending.k === k) return true;
+    return false;
+  }
+
+  /** Deny everything a session is still waiting on so its run can wind down. */
+  private denyPendingApprovals(k: string, message: string) {
+    for (const [approvalId, pending] of this.approvals) {
+      if (pending.k !== k) continue;
+      this.approvals.delete(approvalId);
+      pending.resolve({ approved: false, message });
+      this.emit('agent:approval-resolved', { ...this.tagOfKey(k), approvalId, approved: false });
+    }
+  }
+
+  /**
+   * SDK permission options for an approval-mode run: gated tools go through `canUseTool`
+   * and wait for approveAction; other tools outside the usual allow-list stay denied.
+   */
+  private approvalOptions(k: string, mcpTools: string[]) {
+    const gated = new Set(getAppSettings().agentApproval.tools);
+    const allowed = [...CLAUDE_EDIT_TOOLS, ...mcpTools].filter((t) => !gated.has(t));
+    return {
+      // acceptEdits would let gated edit tools through without asking
+      permissionMode: CLAUDE_EDIT_TOOLS.some((t) => gated.has(t)) ? 'default' : 'acceptEdits',
+      allowedTools: allowed,
+      canUseTool: async (toolName: string, input: Record<string, unknown>) => {
+        if (!gated.has(toolName)) {
+          return { behavior: 'deny', message: `${toolName} is not allowed in this run` };
+        }
+        const decision = await this.requestApproval(k, toolName, input);
+        return decision.approved
+          ? { behavior: 'allow', updatedInput: input }
+          : { behavior: 'deny', message: decision.message || 'Denied by the user' };
+      },
+    };
+  }
+
+  /** Drop a client from every session, e.g. when its window closes. */
+  detachClient(clientId: number) {
+    for (const [k, clients] of this.attachments) {
+      clients.delete(clientId);
+      if (clients.size === 0) this.attachments.delete(k);
+    }
+  }
+
+  /**
+   * Agent sessions that have run since startup, optionally for one workspace.
+   */
+  listAgents(workspaceId?: string): AgentSessionInfo[] {
+    const out: AgentSessionInfo[] = [];
+    // Sessions still waiting for their first slot have no output buffer yet
+    const keys = new Set([...this.outputs.keys(), ...this.startQueue.map((e) => e.sessionKey)]);
+    for (const k of keys) {
+      const [providerId, wid, agentId] = k.split(':') as [ProviderId, string, string?];
+      if (workspaceId && wid !== workspaceId) continue;
+      const running = this.isRunning(k);
+      const restarts = this.restartStates.get(k)?.restarts ?? 0;
+      const queued = this.queues.get(k)?.length ?? 0;
+      const runtime = this.runtimes.get(k);
+      const waiting = this.startQueue.findIndex((e) => e.sessionKey === k);
+      out.push({
+        providerId,
+        workspaceId: wid,
+        ...(agentId ? { agentId } : {}),
+        running,
+        restarts,
+        queued,
+        status: this.statuses.get(k),
+        stalled: running && !!this.activity.get(k)?.stalled,
+        timedOut: !!runtime?.timedOut,
+        ...(running && runtime?.timer
+          ? { remainingMs: Math.max(0, runtime.deadline - Date.now()) }
+          : {}),
+        attachedClients: this.attachments.get(k)?.size ?? 0,
+        pendingApprovals: [...this.approvals.values()].filter((a) => a.k === k).length,
+        ...(waiting >= 0 ? { startQueuePosition: waiting + 1 } : {}),
+      });
+    }
+    return out;
+  }
 
-  private key(providerId: ProviderId, workspaceId: string) {
-    return `${providerId}:${workspaceId}`;
+  private key(providerId: ProviderId, workspaceId: string, agentId?: string) {
+    return agentId ? `${providerId}:${workspaceId}:${agentId}` : `${providerId}:${workspaceId}`;
   }
 
-  private ensureLog(providerId: ProviderId, workspaceId: string) {
-    const base = app.getPath('userData');
-    const dir = path.join(base, 'logs', 'agent', providerId, workspaceId);
-    if (!existsSync(dir)) mkdirSync(dir, { recursive: true });
-    const file = path.join(dir, 'stream.log');
-    const w = createWriteStream(file, { flags: 'w', encoding: 'utf8' });
-    this.writers.set(this.key(providerId, workspaceId), w);
+  private ensureLog(providerId: ProviderId, workspaceId: string, agentId?: string) {
+    const k = this.key(providerId, workspaceId, agentId);
+    this.writers.get(k)?.end();
+    const w = agentLogStore.open(providerId, workspaceId, agentId);
+    this.writers.set(k, w);
     return w;
   }
 
-  private append(providerId: ProviderId, workspaceId: string, data: string) {
-    const w = this.writers.get(this.key(providerId, workspaceId));
+  private append(k: string, data: string) {
+    const w = this.writers.get(k);
     if (w && !w.destroyed) w.write(data);
   }
 
   async isInstalled(providerId: ProviderId): Promise<boolean> {
     try {
+      // Disallowed providers report as unavailable
+      const provider = resolveProvider(providerId);
       if (providerId === 'codex') {
         return await codexService.getInstallationStatus();
       }
       if (providerId === 'claude') {
-        await execFileAsync('claude', ['--version']);
+        await execFileAsync(provider.command, ['--version']);
         return true;
       }
       return false;
@@ -64,28 +659,307 @@ export class AgentService extends EventEmitter {
     return '';
   }
 
-  async startStream(opts: AgentStartOptions): Promise<void> {
+  /**
+   * Start a run for the message. With `queue`, a message for a busy session is held until
+   * the current run (and any pending restart) ends; `agent:message-status` events
+   * acknowledge each step (queued, delivered, dropped).
+   */
+  async startStream(req: AgentStartRequest): Promise<{ messageId: string; queued: boolean }> {
+    const opts = this.applyPreset(req);
+    const agentId = opts.agentId?.trim() || undefined;
+    const sessionKey = this.key(opts.providerId, opts.workspaceId, agentId);
+    const messageId = crypto.randomUUID();
+    const pending = this.queues.get(sessionKey) ?? [];
+    if (opts.queue && (pending.length > 0 || this.isSessionBusy(sessionKey, opts))) {
+      pending.push({ messageId, opts });
+      this.queues.set(sessionKey, pending);
+      this.emit('agent:message-status', {
+        ...this.tagOf(opts),
+        status: 'queued',
+        messageId,
+        position: pending.length,
+      });
+      return { messageId, queued: true };
+    }
+    if (!this.hasCapacity(sessionKey)) {
+      // A new message replaces the session's waiting one, as it would replace a running run
+      this.dropWaitingStarts(sessionKey, 'Replaced by a newer message');
+      this.startQueue.push({ sessionKey, messageId, opts });
+      this.emitSlotQueued(sessionKey, messageId, opts);
+      return { messageId, queued: true };
+    }
+    await this.deliver(sessionKey, messageId, opts);
+    return { messageId, queued: false };
+  }
+
+  /**
+   * Whether the session may start a run under settings.agentConcurrency. Replacing the
+   * session's own running run doesn't take another slot. Restarts don't consult the cap.
+   */
+  private hasCapacity(k: string): boolean {
+    const { maxRunning } = getAppSettings().agentConcurrency;
+    if (!maxRunning || this.isRunning(k) || this.starting.has(k)) return true;
+    const keys = new Set([...this.outputs.keys(), ...this.starting]);
+    let active = 0;
+    for (const key of keys) {
+      if (this.starting.has(key) || this.isRunning(key)) active++;
+    }
+    return active < maxRunning;
+  }
+
+  private emitSlotQueued(k: string, messageId: string, opts: AgentStartOptions) {
+    this.emit('agent:message-status', {
+      ...this.tagOf(opts),
+      status: 'queued',
+      messageId,
+      reason: 'concurrency',
+      position: this.startQueue.findIndex((e) => e.sessionKey === k) + 1,
+    });
+  }
+
+  /** Start waiting sessions, oldest first, while slots are free. */
+  private drainStartQueue() {
+    while (this.startQueue.length > 0 && this.hasCapacity(this.startQueue[0].sessionKey)) {
+      const next = this.startQueue.shift()!;
+      this.deliver(next.sessionKey, next.messageId, next.opts).catch((error) => {
+        this.emit('agent:message-status', {
+          ...this.tagOf(next.opts),
+          status: 'dropped',
+          messageId: next.messageId,
+          error: error?.message || String(error),
+        });
+        this.drainStartQueue();
+      });
+    }
+  }
+
+  private dropWaitingStarts(k: string, reason: string) {
+    const dropped = this.startQueue.filter((e) => e.sessionKey === k);
+    if (dropped.length === 0) return;
+    this.startQueue = this.startQueue.filter((e) => e.sessionKey !== k);
+    for (const { messageId, opts } of dropped) {
+      this.emit('agent:message-status', {
+        ...this.tagOf(opts),
+        status: 'dropped',
+        messageId,
+        error: reason,
+      });
+    }
+  }
+
+  /**
+   * Fill the provider and env profile from the named preset; explicit options win. The
+   * preset's CLI args are resolved at each launch so prompt file edits reach restarts.
+   */
+  private applyPreset(opts: AgentStartRequest): AgentStartOptions {
+    const preset = findAgentPreset(opts.preset);
+    if (!preset) {
+      if (!opts.providerId) throw new Error('Either providerId or preset is required');
+      return { ...opts, providerId: opts.providerId };
+    }
+    if (opts.providerId && opts.providerId !== preset.providerId) {
+      throw new Error(`Preset ${preset.name} is for ${preset.providerId}, not ${opts.providerId}`);
+    }
+    return {
+      ...opts,
+      providerId: preset.providerId as ProviderId,
+      envProfile: opts.envProfile || preset.envProfile || undefined,
+    };
+  }
+
+  private tagOf(opts: AgentStartOptions) {
+    const agentId = opts.agentId?.trim() || undefined;
+    return {
+      providerId: opts.providerId,
+      workspaceId: opts.workspaceId,
+      ...(agentId ? { agentId } : {}),
+    };
+  }
+
+  private isSessionBusy(k: string, opts: AgentStartOptions): boolean {
+    if (this.restartStates.get(k)?.timer) return true;
+    if (this.startQueue.some((e) => e.sessionKey === k)) return true;
+    return opts.providerId === 'codex'
+      ? codexService.isStreaming(opts.workspaceId)
+      : this.processes.has(k);
+  }
+
+  private async deliver(sessionKey: string, messageId: string, opts: AgentStartOptions) {
+    // Synchronous up to launch() so a draining loop sees the slot as taken
+    this.starting.add(sessionKey);
+    this.clearRestart(sessionKey);
+    const policy = opts.restartPolicy;
+    if (policy?.mode === 'on-failure') {
+      const retries = Math.floor(Number(policy.maxRetries ?? 3));
+      const backoff = Number(policy.backoffMs ?? 2000);
+      this.restartStates.set(sessionKey, {
+        opts,
+        maxRetries: Number.isFinite(retries) && retries > 0 ? retries : 0,
+        backoffMs: Number.isFinite(backoff) && backoff > 0 ? backoff : 0,
+        restarts: 0,
+        runId: 0,
+      });
+    }
+    try {
+      await this.launch(opts);
+    } finally {
+      this.starting.delete(sessionKey);
+      // A failed launch frees the slot it was holding
+      this.drainStartQueue();
+    }
+    this.emit('agent:message-status', { ...this.tagOf(opts), status: 'delivered', messageId });
+  }
+
+  private deliverNext(k: string) {
+    const pending = this.queues.get(k);
+    const next = pending?.[0];
+    if (!pending || !next || this.isSessionBusy(k, next.opts)) return;
+    pending.shift();
+    if (pending.length === 0) this.queues.delete(k);
+    if (!this.hasCapacity(k)) {
+      this.startQueue.push({ sessionKey: k, ...next });
+      this.emitSlotQueued(k, next.messageId, next.opts);
+      return;
+    }
+    this.deliver(k, next.messageId, next.opts).catch((error) => {
+      this.emit('agent:message-status', {
+        ...this.tagOf(next.opts),
+        status: 'dropped',
+        messageId: next.messageId,
+        error: error?.message || String(error),
+      });
+      this.deliverNext(k);
+    });
+  }
+
+  private dropQueued(k: string, reason: string) {
+    this.dropWaitingStarts(k, reason);
+    const pending = this.queues.get(k);
+    if (!pending) return;
+    this.queues.delete(k);
+    for (const { messageId, opts } of pending) {
+      this.emit('agent:message-status', {
+        ...this.tagOf(opts),
+        status: 'dropped',
+        messageId,
+        error: reason,
+      });
+    }
+  }
+
+  private async launch(opts: AgentStartOptions): Promise<void> {
     const { providerId, workspaceId, worktreePath, message, conversationId } = opts;
+    // The renderer only names a provider; the binary and its defaults come from the registry
+    const provider = resolveProvider(providerId);
+    const agentId = opts.agentId?.trim() || undefined;
+    if (agentId && !/^[A-Za-z0-9._-]+$/.test(agentId)) {
+      throw new Error('agentId may only contain letters, digits, ".", "_" and "-"');
+    }
+    // codexService tracks a single agent per workspace
+    if (agentId && providerId === 'codex') {
+      throw new Error('Named agent sessions are not supported for Codex');
+    }
+    if (opts.usePty && providerId === 'codex') {
+      throw new Error('Running Codex under a PTY is not supported');
+    }
+    const limits = normalizeResourceLimits(opts.resourceLimits);
+    if (limits && providerId === 'codex') {
+      throw new Error('Resource limits are not supported for Codex');
+    }
+    const preset = findAgentPreset(opts.preset);
+    const presetArgs = preset ? presetCliArgs(preset) : [];
+    // Only the SDK can hold a tool call open; codex exec and the CLI in -p mode cannot
+    if (opts.requireApproval) {
+      if (providerId === 'codex') throw new Error('Approval mode is not supported for Codex');
+      const cliOnly = opts.usePty || limits || provider.defaultArgs.length || presetArgs.length;
+      if (cliOnly || !loadClaudeSdk()) {
+        throw new Error('Approval mode needs the Claude Code SDK and a run without CLI options');
+      }
+    }
+    const maxRuntimeMs = Math.floor(Number(opts.maxRuntimeMs ?? 0));
+    if (!Number.isFinite(maxRuntimeMs) || maxRuntimeMs < 0) {
+      throw new Error('maxRuntimeMs must be a non-negative number');
+    }
+    // Resolve before starting anything so an unknown profile fails the request up front
+    // Secrets are resolved per launch so values never sit in restart or queue state
+    const profileEnv = {
+      ...resolveEnvProfile(opts.envProfile),
+      ...(await secretStore.resolveEnv(opts.secrets)),
+    };
+    assertRequiredEnv(provider, { ...process.env, ...profileEnv });
+    // Event payloads identify the session; agentId is only present for named sessions
+    const tag = { providerId, workspaceId, ...(agentId ? { agentId } : {}) };
+
+    // Seqs continue from the previous run so they stay monotonic for the whole session
+    const prevOutput = this.outputs.get(this.key(providerId, workspaceId, agentId));
+    this.outputs.set(
+      this.key(providerId, workspaceId, agentId),
+      new AgentOutputBuffer(prevOutput?.nextSequence() ?? 0)
+    );
+    this.stopRequested.delete(this.key(providerId, workspaceId, agentId));
+    this.setStatus(this.key(providerId, workspaceId, agentId), 'starting');
+    await this.startGuard(providerId, workspaceId, worktreePath);
+    const baseRef = await getBaselineRef(worktreePath);
+    if (baseRef) {
+      this.baselines.set(this.key(providerId, workspaceId, agentId), { worktreePath, baseRef });
+    } else {
+      this.baselines.delete(this.key(providerId, workspaceId, agentId));
+    }
 
     // If codex, delegate to codexService (and events are bridged in agent IPC setup)
     if (providerId === 'codex') {
-      await codexService.sendMessageStream(workspaceId, message, conversationId);
+      const state = this.restartStates.get(this.key('codex', workspaceId));
+      // The previous run's completion arrives while we replace it; don't treat it as a crash
+      if (state) state.launching = true;
+      this.ensureLog('codex', workspaceId).write(
+        `=== Agent Stream ${new Date().toISOString()} ===\nProvider: codex\nWorkspace: ${workspaceId}\nMessage: ${message}\n\n--- Output ---\n`
+      );
+      try {
+        await codexService.sendMessageStream(
+          workspaceId,
+          message,
+          conversationId,
+          profileEnv,
+          presetArgs
+        );
+        this.touch(this.key('codex', workspaceId));
+        this.setStatus(this.key('codex', workspaceId), 'running');
+        this.armRuntime(this.key('codex', workspaceId), maxRuntimeMs);
+      } finally {
+        if (state) state.launching = false;
+      }
       return;
     }
 
-    // Ensure only one process per workspace across providers
-    for (const [key, proc] of this.processes) {
-      const [, wid] = key.split(':');
-      if (wid === workspaceId) {
-        try {
-          proc.kill('SIGTERM');
-        } catch {}
-        this.processes.delete(key);
+    // Ensure only one unnamed process per workspace across providers; named sessions
+    // run alongside it and only replace themselves
+    if (!agentId) {
+      for (const [key, proc] of this.processes) {
+        const [, wid, aid] = key.split(':');
+        if (wid === workspaceId && !aid) {
+          try {
+            proc.kill('SIGTERM');
+          } catch {}
+          this.processes.delete(key);
+        }
       }
     }
 
-    // Only one process per provider/workspace (redundant after global sweep but retained for safety)
-    const k = this.key(providerId, workspaceId);
+    // Only one process per provider/workspace/agent
+    const k = this.key(providerId, workspaceId, agentId);
+    const runId = ++this.runSeq;
+    this.latestRuns.set(k, runId);
+    // A replaced run still ends later; it must not tear down its successor's log, process,
+    // guard or completion event
+    const isLatestRun = () => this.latestRuns.get(k) === runId;
+    this.armRuntime(k, maxRuntimeMs, runId);
+    if (conversationId) {
+      this.transcripts.set(k, { runId, conversationId, texts: [], toolCalls: [] });
+    } else {
+      this.transcripts.delete(k);
+    }
+    const restartState = this.restartStates.get(k);
+    if (restartState) restartState.runId = runId;
     const prev = this.processes.get(k);
     if (prev) {
       try {
@@ -94,27 +968,32 @@ export class AgentService extends EventEmitter {
       this.processes.delete(k);
     }
 
-    const writer = this.ensureLog(providerId, workspaceId);
+    const writer = this.ensureLog(providerId, workspaceId, agentId);
     writer.write(
-      `=== Agent Stream ${new Date().toISOString()} ===\nProvider: ${providerId}\nWorkspace: ${workspaceId}\nMessage: ${message}\n\n--- Output ---\n`
+      `=== Agent Stream ${new Date().toISOString()} ===\nProvider: ${providerId}\nWorkspace: ${workspaceId}\n${agentId ? `Agent: ${agentId}\n` : ''}Message: ${message}\n\n--- Output ---\n`
     );
 
     if (providerId === 'claude') {
       // Try SDK first (preferred), fallback to CLI with safe edit flags
       let usedSdk = false;
+      // Configured MCP servers, with all of their tools allowed alongside the edit tools
+      const mcpServers = mcpServersFor(providerId, workspaceId);
+      const mcpTools = mcpServers.map((s) => `mcp__${s.name}`);
       try {
         // Try to load SDK dynamically; avoid static import so build doesn't require it
-        let cc: any = null;
-        try {
-          // eslint-disable-next-line @typescript-eslint/no-var-requires
-          cc = require('@anthropic/claude-code-sdk');
-        } catch {}
-        if (cc && typeof cc.query === 'function') {
+        const cc = loadClaudeSdk();
+        // The SDK runs in-process without a terminal, limits or CLI args, so PTY runs, limited
+        // runs, configured provider defaults and preset args go straight to the CLI
+        const sdkUsable =
+          !opts.usePty && !limits && provider.defaultArgs.length === 0 && presetArgs.length === 0;
+        if (sdkUsable && cc && typeof cc.query === 'function') {
           usedSdk = true;
           const abortController = new AbortController();
           // Store abort handle so stopStream can cancel
           const abortHandle = { kill: () => abortController.abort() } as unknown as ChildProcess;
           this.processes.set(k, abortHandle);
+          this.touch(k);
+          this.setStatus(k, 'running');
           (async () => {
             try {
               const q: AsyncGenerator<any, void> = cc.query({
@@ -122,12 +1001,24 @@ export class AgentService extends EventEmitter {
                 options: {
                   cwd: worktreePath,
                   includePartialMessages: true,
-                  permissionMode: 'acceptEdits',
-                  allowedTools: ['Edit', 'MultiEdit', 'Write', 'Read'],
+                  ...(opts.requireApproval
+                    ? this.approvalOptions(k, mcpTools)
+                    : {
+                        permissionMode: 'acceptEdits',
+                        allowedTools: [...CLAUDE_EDIT_TOOLS, ...mcpTools],
+                      }),
+                  ...(mcpServers.length ? { mcpServers: claudeMcpServers(mcpServers) } : {}),
+                  env: {
+                    ...process.env,
+                    ...scratchService.envFor(worktreePath),
+                    ...dependencyCacheService.envFor(worktreePath),
+                    ...profileEnv,
+                  },
                   abortController,
                 },
               });
               for await (const msg of q) {
+                this.emitEvents(tag, msg);
                 try {
                   let out = '';
                   if (msg?.type === 'stream_event') {
@@ -142,27 +1033,35 @@ export class AgentService extends EventEmitter {
                     out = msg.result;
                   }
                   if (out) {
-                    this.append(providerId, workspaceId, out);
-                    this.emit('agent:output', { providerId, workspaceId, output: out });
+                    this.append(k, out);
+                    this.emitOutput(k, tag, out);
                   }
                 } catch {}
               }
-              this.append(providerId, workspaceId, `\n[COMPLETE] sdk success\n`);
+              if (isLatestRun()) {
+                this.append(k, `\n[COMPLETE] sdk success\n`);
+                this.writers.delete(k);
+                this.processes.delete(k);
+                this.releaseGuard(workspaceId);
+                this.emit('agent:complete', { ...tag, exitCode: 0 });
+              }
               try {
                 writer.end();
               } catch {}
-              this.writers.delete(k);
-              this.processes.delete(k);
-              this.emit('agent:complete', { providerId, workspaceId, exitCode: 0 });
+              this.onRunEnded(k, false, runId, { exitCode: 0 });
             } catch (err: any) {
               const em = err?.message || String(err);
-              this.append(providerId, workspaceId, `\n[ERROR] ${em}\n`);
-              this.emit('agent:error', { providerId, workspaceId, error: em });
+              if (isLatestRun()) {
+                this.append(k, `\n[ERROR] ${em}\n`);
+                this.emit('agent:error', { ...tag, error: em });
+                this.writers.delete(k);
+                this.processes.delete(k);
+                this.releaseGuard(workspaceId);
+              }
               try {
                 writer.end();
               } catch {}
-              this.writers.delete(k);
-              this.processes.delete(k);
+              this.onRunEnded(k, true, runId, { error: em });
             }
           })();
         }
@@ -189,14 +1088,19 @@ export class AgentService extends EventEmitter {
           'Write',
           '--allowedTools',
           'Read',
+          ...mcpTools.flatMap((t) => ['--allowedTools', t]),
+          ...claudeMcpArgs(mcpServers),
+          ...provider.defaultArgs,
+          ...presetArgs,
         ];
-        const child = spawn('claude', args, {
-          cwd: worktreePath,
-          stdio: ['ignore', 'pipe', 'pipe'],
-        });
-        this.processes.set(k, child);
+        const env = {
+          ...process.env,
+          ...scratchService.envFor(worktreePath),
+          ...dependencyCacheService.envFor(worktreePath),
+          ...profileEnv,
+        };
         let partial = '';
-        child.stdout.on('data', (buf) => {
+        const onStdout = (buf: Buffer | string) => {
           partial += buf.toString();
           // Process line-delimited JSON events
           let idx;
@@ -206,6 +1110,7 @@ export class AgentService extends EventEmitter {
             if (!line) continue;
             try {
               const obj = JSON.parse(line);
+              this.emitEvents(tag, obj);
               let out = '';
               if (obj?.type === 'stream_event') {
                 const ev = obj?.event || {};
@@ -220,33 +1125,105 @@ export class AgentService extends EventEmitter {
                 out = obj.message;
               }
               if (out) {
-                this.append(providerId, workspaceId, out);
-                this.emit('agent:output', { providerId, workspaceId, output: out });
+                this.append(k, out);
+                this.emitOutput(k, tag, out);
               }
             } catch {
               // If not JSON, treat as plain text chunk
-              this.append(providerId, workspaceId, line + '\n');
-              this.emit('agent:output', { providerId, workspaceId, output: line + '\n' });
+              this.append(k, line + '\n');
+              this.emitOutput(k, tag, line + '\n');
             }
           }
-        });
-        child.stderr.on('data', (buf) => {
-          const s = buf.toString();
-          this.append(providerId, workspaceId, `\n[stderr] ${s}`);
-          this.emit('agent:error', { providerId, workspaceId, error: s });
-        });
-        child.on('close', (code) => {
-          this.append(providerId, workspaceId, `\n[COMPLETE] exit code ${code}\n`);
+        };
+        let stopWatch: (() => void) | null = null;
+        const onClose = (code: number | null) => {
+          stopWatch?.();
+          if (isLatestRun()) {
+            this.append(k, `\n[COMPLETE] exit code ${code}\n`);
+            this.writers.delete(k);
+            this.processes.delete(k);
+            this.releaseGuard(workspaceId);
+            this.emit('agent:complete', { ...tag, exitCode: code ?? 0 });
+          }
           try {
             writer.end();
           } catch {}
-          this.writers.delete(k);
-          this.processes.delete(k);
-          this.emit('agent:complete', { providerId, workspaceId, exitCode: code ?? 0 });
-        });
-        child.on('error', (err) => {
-          this.emit('agent:error', { providerId, workspaceId, error: err.message });
-        });
+          this.onRunEnded(k, code !== 0, runId, { exitCode: code });
+        };
+        const onError = (err: Error) => {
+          stopWatch?.();
+          this.emit('agent:error', { ...tag, error: err.message });
+          this.onRunEnded(k, true, runId, { error: err.message });
+        };
+
+        const watch = (pid: number | undefined) => {
+          if (!limits || !pid) return;
+          stopWatch = watchResourceUsage(pid, limits, (reason) => {
+            this.append(k, `\n[LIMIT] ${reason}\n`);
+            this.emit('agent:error', { ...tag, error: `Agent stopped: ${reason}`, limit: true });
+            this.terminateSession(k, 'limit');
+          });
+        };
+        const launchCmd = limits
+          ? await wrapWithCgroup(provider.command, args, limits)
+          : { command: provider.command, args };
+
+        if (opts.usePty) {
+          // Some CLIs only behave interactively with a TTY. Output (stdout and stderr
+          // merged) goes through the same line parser, so events are unchanged.
+          try {
+            const proc = startPty({
+              id: `agent:${k}`,
+              cwd: worktreePath,
+              command: launchCmd.command,
+              args: launchCmd.args,
+              env,
+              cols: 200,
+              rows: 50,
+              labels: { purpose: 'agent', ...tag },
+            });
+            const handle = {
+              pid: proc.pid,
+              exitCode: null as number | null,
+              signalCode: null as NodeJS.Signals | null,
+              kill: (signal?: NodeJS.Signals) => {
+                proc.kill(signal);
+                return true;
+              },
+            };
+            this.processes.set(k, handle as unknown as ChildProcess);
+            this.touch(k);
+            this.setStatus(k, 'running');
+            watch(proc.pid);
+            proc.onData(onStdout);
+            proc.onExit(({ exitCode }) => {
+              handle.exitCode = exitCode;
+              if (partial.trim()) onStdout('\n');
+              onClose(exitCode);
+            });
+          } catch (err: any) {
+            onError(err instanceof Error ? err : new Error(String(err)));
+          }
+        } else {
+          const child = spawn(launchCmd.command, launchCmd.args, {
+            cwd: worktreePath,
+            env,
+            stdio: ['ignore', 'pipe', 'pipe'],
+          });
+          this.processes.set(k, child);
+          this.touch(k);
+          this.setStatus(k, 'running');
+          watch(child.pid);
+          child.stdout.on('data', onStdout);
+          child.stderr.on('data', (buf) => {
+            const s = buf.toString();
+            this.append(k, `\n[stderr] ${s}`);
+            const seq = this.outputs.get(k)?.append(s, 'stderr');
+            this.emit('agent:error', { ...tag, error: s, stream: 'stderr', seq });
+          });
+          child.on('close', onClose);
+          child.on('error', onError);
+        }
       }
       return;
     }
@@ -254,24 +1231,227 @@ export class AgentService extends EventEmitter {
     // No other providers handled here
   }
 
-  async stopStream(providerId: ProviderId, workspaceId: string): Promise<boolean> {
+  /**
+   * Cancel the run in flight with SIGINT (abort for SDK runs) while keeping the session:
+   * queued messages still follow and the restart policy stays registered, but it does not
+   * treat this run's exit as a crash. Returns false when nothing is running.
+   */
+  async interrupt(providerId: ProviderId, workspaceId: string, agentId?: string) {
+    const k = this.key(providerId, workspaceId, agentId);
+    if (!this.isRunning(k)) return false;
+    const state = this.restartStates.get(k);
+    if (state) state.interrupted = true;
+    this.stopRequested.add(k);
+    this.setStatus(k, 'stopping', { reason: 'interrupted' });
+    this.denyPendingApprovals(k, 'Interrupted');
+    this.append(k, `\n[INTERRUPTED]\n`);
+    this.emit('agent:interrupted', this.tagOfKey(k));
     if (providerId === 'codex') {
-      return await codexService.stopMessageStream(workspaceId);
+      return await codexService.stopMessageStream(workspaceId, { signal: 'SIGINT' });
+    }
+    const proc = this.processes.get(k);
+    if (!proc) return false;
+    terminateProcess(
+      {
+        pid: proc.pid,
+        kill: (signal) => proc.kill(signal as NodeJS.Signals),
+        exited: () => proc.exitCode !== null || proc.signalCode !== null,
+      },
+      resolveKillOptions('SIGINT', { signal: 'SIGINT' })
+    );
+    return true;
+  }
+
+  /** Whether any provider currently has a run in flight for the workspace. */
+  isActive(workspaceId: string): boolean {
+    if (codexService.isStreaming(workspaceId)) return true;
+    for (const key of this.processes.keys()) {
+      if (key.split(':')[1] === workspaceId) return true;
+    }
+    return false;
+  }
+
+  async stopStream(
+    providerId: ProviderId,
+    workspaceId: string,
+    kill?: KillOptions,
+    agentId?: string
+  ): Promise<boolean> {
+    const k = this.key(providerId, workspaceId, agentId);
+    // A deliberate stop must not look like a crash to the restart policy, and cancels
+    // anything queued behind the run
+    this.clearRestart(k);
+    this.disarmRuntime(k);
+    if (this.isRunning(k)) {
+      this.stopRequested.add(k);
+      this.setStatus(k, 'stopping', { reason: 'requested' });
+    }
+    this.dropQueued(k, 'Agent stopped');
+    this.denyPendingApprovals(k, 'Agent stopped');
+    if (providerId === 'codex') {
+      const stopped = await codexService.stopMessageStream(workspaceId, kill);
+      this.releaseGuard(workspaceId);
+      return stopped;
     }
-    const k = this.key(providerId, workspaceId);
     const p = this.processes.get(k);
     if (!p) return true;
     try {
-      p.kill('SIGTERM');
+      // SDK runs store an abort handle without pid/exitCode; it is never escalated
+      terminateProcess(
+        {
+          pid: p.pid,
+          kill: (signal) => p.kill(signal as NodeJS.Signals),
+          exited: () => p.exitCode !== null || p.signalCode !== null,
+        },
+        resolveKillOptions('SIGTERM', kill)
+      );
       this.processes.delete(k);
       const w = this.writers.get(k);
       if (w && !w.destroyed) w.end();
       this.writers.delete(k);
+      this.releaseGuard(workspaceId);
       return true;
     } catch {
       return false;
     }
   }
+
+  /**
+   * Periodically measure the cumulative diff of a workspace against the commit its first
+   * running session started from and warn or stop its agents once guardrails are crossed.
+   * Later launches in the same workspace keep that baseline so the diff stays cumulative.
+   */
+  private async startGuard(providerId: ProviderId, workspaceId: string, worktreePath: string) {
+    // The launching run is not registered yet, so isActive only sees the runs already going
+    if (this.guards.has(workspaceId) && this.isActive(workspaceId)) return;
+    this.stopGuard(workspaceId);
+    const { getAppSettings } = await import('../settings');
+    const limits = getAppSettings()?.agentGuardrails;
+    if (!limits || !guardrailsEnabled(limits)) return;
+    const baseRef = await getBaselineRef(worktreePath);
+    if (!baseRef) return;
+
+    const guard: { timer?: NodeJS.Timeout; level: GuardrailLevel } = { level: 'ok' };
+    let checking = false;
+    guard.timer = setInterval(async () => {
+      if (checking) return;
+      checking = true;
+      try {
+        const stats = await measureDiff(worktreePath, baseRef);
+        const level = evaluateDiffGuardrails(stats, limits);
+        if (level === 'ok' || level === guard.level) return;
+        guard.level = level;
+        this.emit('agent:guardrail', { providerId, workspaceId, level, stats, limits });
+        if (level === 'stop') {
+          await this.stopWorkspaceSessions(providerId, workspaceId);
+          const lines = stats.insertions + stats.deletions;
+          this.emit('agent:error', {
+            providerId,
+            workspaceId,
+            error: `Agent stopped: diff guardrail exceeded (${stats.files} files, ${lines} lines)`,
+          });
+        }
+      } catch {
+        // Measurement is best-effort; try again on the next tick
+      } finally {
+        checking = false;
+      }
+    }, GUARDRAIL_CHECK_INTERVAL_MS);
+    this.guards.set(workspaceId, guard);
+  }
+
+  private clearRestart(k: string) {
+    const state = this.restartStates.get(k);
+    if (state?.timer) clearTimeout(state.timer);
+    this.restartStates.delete(k);
+  }
+
+  /**
+   * Apply the session's restart policy when a run ends. `runId` ties CLI runs to the
+   * launch that registered them so a replaced run's exit is ignored.
+   */
+  private onRunEnded(
+    k: string,
+    failed: boolean,
+    runId?: number,
+    exit: { exitCode?: number | null; error?: string } = {}
+  ) {
+    if (runId === undefined || this.latestRuns.get(k) === runId) {
+      const requested = this.stopRequested.delete(k);
+      const runtime = this.runtimes.get(k);
+      const timedOut =
+        !!runtime?.timedOut &&
+        (runId === undefined || runtime.runId === undefined || runtime.runId === runId);
+      const status = timedOut ? 'timed-out' : failed && !requested ? 'error' : 'stopped';
+      this.setStatus(k, status, exit);
+      this.denyPendingApprovals(k, 'Run ended');
+      void this.publishResult(k, status, exit);
+    }
+    this.disarmRuntime(k, runId);
+    this.persistTranscript(k, runId);
+    if (!this.scheduleRestart(k, failed, runId)) this.deliverNext(k);
+    this.drainStartQueue();
+  }
+
+  /**
+   * Returns true when the session is still owned by a run or restart, so the queue waits.
+   */
+  private scheduleRestart(k: string, failed: boolean, runId?: number): boolean {
+    const state = this.restartStates.get(k);
+    if (!state) return false;
+    if (state.launching || state.timer) return true;
+    if (runId !== undefined && runId !== state.runId) return true;
+    if (state.interrupted) {
+      state.interrupted = false;
+      return false;
+    }
+    if (!failed || state.restarts >= state.maxRetries) return false;
+
+    const delayMs = Math.min(state.backoffMs * 2 ** state.restarts, MAX_RESTART_BACKOFF_MS);
+    state.restarts += 1;
+    const { providerId, workspaceId } = state.opts;
+    const agentId = state.opts.agentId?.trim() || undefined;
+    const tag = { providerId, workspaceId, ...(agentId ? { agentId } : {}) };
+    this.emit('agent:restarting', {
+      ...tag,
+      attempt: state.restarts,
+      maxRetries: state.maxRetries,
+      delayMs,
+    });
+    state.timer = setTimeout(() => {
+      state.timer = undefined;
+      if (this.restartStates.get(k) !== state) return;
+      this.launch(state.opts).catch((error) => {
+        this.emit('agent:error', { ...tag, error: error?.message || String(error) });
+        this.deliverNext(k);
+      });
+    }, delayMs);
+    return true;
+  }
+
+  /** The diff is shared by every session in the worktree, so a guardrail stop ends them all. */
+  private async stopWorkspaceSessions(providerId: ProviderId, workspaceId: string) {
+    if (providerId === 'codex') {
+      await this.stopStream('codex', workspaceId);
+    }
+    for (const key of Array.from(this.processes.keys())) {
+      const [pid, wid, aid] = key.split(':') as [ProviderId, string, string?];
+      if (wid === workspaceId) await this.stopStream(pid, wid, undefined, aid);
+    }
+  }
+
+  /** Stop the workspace's guard once no session in it is still running. */
+  private releaseGuard(workspaceId: string) {
+    if (!this.isActive(workspaceId)) this.stopGuard(workspaceId);
+  }
+
+  private stopGuard(workspaceId?: string) {
+    if (!workspaceId) return;
+    const guard = this.guards.get(workspaceId);
+    if (!guard) return;
+    if (guard.timer) clearInterval(guard.timer);
+    this.guards.delete(workspaceId);
+  }
 }
 
 export const agentService = new AgentService();
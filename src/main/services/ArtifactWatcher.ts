@@ -0,0 +1,188 @@
+import { EventEmitter } from 'events';
+import fs from 'fs';
+import path from 'path';
+import { log } from '../lib/logger';
+
+export type ArtifactKind = 'plan' | 'test-report' | 'agent-result' | 'coverage';
+
+/**
+ * Well-known result files agents tend to leave behind, relative to the worktree root.
+ */
+const MARKERS: Array<{ rel: string; kind: ArtifactKind }> = [
+  { rel: 'PLAN.md', kind: 'plan' },
+  { rel: 'PLANNING.md', kind: 'plan' },
+  { rel: 'test-report.xml', kind: 'test-report' },
+  { rel: 'junit.xml', kind: 'test-report' },
+  { rel: path.join('.emdash', 'result.json'), kind: 'agent-result' },
+  { rel: path.join('coverage', 'coverage-summary.json'), kind: 'coverage' },
+];
+
+const DEBOUNCE_MS = 500;
+const MAX_PARSE_BYTES = 2 * 1024 * 1024;
+
+export interface ArtifactDetectedEvent {
+  worktreePath: string;
+  workspaceId?: string;
+  kind: ArtifactKind;
+  path: string; // relative to the worktree
+  metadata: Record<string, unknown>;
+  detectedAt: string;
+}
+
+type WatchEntry = {
+  workspaceId?: string;
+  watchers: Map<string, fs.FSWatcher>; // key: directory relative to the worktree ('' = root)
+  seen: Map<string, number>; // marker rel -> mtimeMs already reported
+  timers: Map<string, NodeJS.Timeout>;
+};
+
+/**
+ * Watches agent worktrees for result markers and emits 'artifact:detected' with parsed
+ * metadata. Only the handful of directories that can contain markers are watched
+ * (non-recursively), so large trees such as node_modules cost nothing.
+ */
+export class ArtifactWatcher extends EventEmitter {
+  private entries = new Map<string, WatchEntry>();
+
+  watch(worktreePath: string, workspaceId?: string) {
+    const root = path.resolve(worktreePath);
+    const existing = this.entries.get(root);
+    if (existing) {
+      if (workspaceId) existing.workspaceId = workspaceId;
+      return;
+    }
+    const entry: WatchEntry = {
+      workspaceId,
+      watchers: new Map(),
+      seen: new Map(),
+      timers: new Map(),
+    };
+    this.entries.set(root, entry);
+
+    // Markers already present belong to earlier runs; only report changes from now on
+    for (const marker of MARKERS) {
+      try {
+        entry.seen.set(marker.rel, fs.statSync(path.join(root, marker.rel)).mtimeMs);
+      } catch {}
+    }
+    for (const dir of this.markerDirs()) this.watchDir(root, entry, dir);
+  }
+
+  unwatch(worktreePath: string) {
+    const root = path.resolve(worktreePath);
+    const entry = this.entries.get(root);
+    if (!entry) return;
+    for (const w of entry.watchers.values()) w.close();
+    for (const t of entry.timers.values()) clearTimeout(t);
+    this.entries.delete(root);
+  }
+
+  private markerDirs(): string[] {
+    const dirs = MARKERS.map((m) => path.dirname(m.rel)).map((d) => (d === '.' ? '' : d));
+    return Array.from(new Set(dirs));
+  }
+
+  private watchDir(root: string, entry: WatchEntry, dir: string) {
+    if (entry.watchers.has(dir)) return;
+    const abs = path.join(root, dir);
+    if (!fs.existsSync(abs)) return;
+    try {
+      const watcher = fs.watch(abs, (_event, filename) => {
+        if (!filename) return;
+        const rel = dir ? path.join(dir, filename.toString()) : filename.toString();
+        // A marker subdirectory appeared (e.g. coverage/); start watching it too
+        if (!dir && this.markerDirs().includes(rel)) {
+          this.watchDir(root, entry, rel);
+          for (const marker of MARKERS) {
+            if (path.dirname(marker.rel) === rel) this.schedule(root, entry, marker.rel);
+          }
+          return;
+        }
+        this.schedule(root, entry, rel);
+      });
+      watcher.on('error', () => {
+        watcher.close();
+        entry.watchers.delete(dir);
+      });
+      entry.watchers.set(dir, watcher);
+    } catch (error) {
+      log.warn('artifactWatcher: failed to watch directory', { dir: abs, error });
+    }
+  }
+
+  private schedule(root: string, entry: WatchEntry, rel: string) {
+    const marker = MARKERS.find((m) => m.rel === rel);
+    if (!marker) return;
+    const pending = entry.timers.get(rel);
+    if (pending) clearTimeout(pending);
+    entry.timers.set(
+      rel,
+      setTimeout(() => {
+        entry.timers.delete(rel);
+        this.check(root, entry, marker.rel, marker.kind);
+      }, DEBOUNCE_MS)
+    );
+  }
+
+  private check(root: string, entry: WatchEntry, rel: string, kind: ArtifactKind) {
+    const abs = path.join(root, rel);
+    let stat: fs.Stats;
+    try {
+      stat = fs.statSync(abs);
+    } catch {
+      entry.seen.delete(rel);
+      return;
+    }
+    if (!stat.isFile() || entry.seen.get(rel) === stat.mtimeMs) return;
+    entry.seen.set(rel, stat.mtimeMs);
+
+    const event: ArtifactDetectedEvent = {
+      worktreePath: root,
+      workspaceId: entry.workspaceId,
+      kind,
+      path: rel,
+      metadata: { size: stat.size, ...this.parse(abs, kind, stat.size) },
+      detectedAt: new Date().toISOString(),
+    };
+    this.emit('artifact:detected', event);
+  }
+
+  private parse(abs: string, kind: ArtifactKind, size: number): Record<string, unknown> {
+    if (size > MAX_PARSE_BYTES) return { truncated: true };
+    try {
+      const text = fs.readFileSync(abs, 'utf8');
+      if (kind === 'plan') {
+        const heading = text.match(/^#\s+(.+)$/m);
+        return {
+          title: heading ? heading[1].trim() : undefined,
+          lines: text.split('\n').length,
+          tasks: (text.match(/^\s*[-*]\s+\[[ xX]\]/gm) || []).length,
+        };
+      }
+      if (kind === 'test-report') {
+        const root = text.match(/<testsuites?\b([^>]*)>/);
+        const attr = (name: string) => {
+          const m = root?.[1].match(new RegExp(`\\b${name}="(\\d+)"`));
+          return m ? parseInt(m[1], 10) : undefined;
+        };
+        return {
+          tests: attr('tests'),
+          failures: attr('failures'),
+          errors: attr('errors'),
+          skipped: attr('skipped'),
+        };
+      }
+      const json = JSON.parse(text);
+      if (kind === 'coverage') {
+        return { total: json?.total };
+      }
+      return json && typeof json === 'object' && !Array.isArray(json)
+        ? { keys: Object.keys(json).slice(0, 50), status: json.status, summary: json.summary }
+        : {};
+    } catch (error) {
+      return { parseError: (error as Error).message };
+    }
+  }
+}
+
+export const artifactWatcher = new ArtifactWatcher();
@@ -6,6 +6,11 @@ import path from 'path';
 import { app } from 'electron';
 import { databaseService } from './DatabaseService';
 import { log } from '../lib/logger';
+import { scratchService } from './ScratchService';
+import { dependencyCacheService } from './DependencyCacheService';
+import { resolveKillOptions, terminateProcess, type KillOptions } from '../lib/processKill';
+import { assertRequiredEnv, resolveProvider, type AgentProviderSpec } from './ProviderRegistry';
+import { codexMcpArgs, mcpServersFor } from './McpConfig';
 
 const execAsync = promisify(exec);
 
@@ -33,6 +38,14 @@ export class CodexService extends EventEmitter {
   private pendingCancellationLogs: Set<string> = new Set();
   // Track the active conversation for a workspace while a stream is running
   private activeConversations: Map<string, string> = new Map();
+  // Per-workspace sequence of stdout/stderr chunks; never reset so clients can resume by seq
+  private outputSeqs: Map<string, number> = new Map();
+
+  private nextOutputSeq(workspaceId: string): number {
+    const seq = this.outputSeqs.get(workspaceId) ?? 0;
+    this.outputSeqs.set(workspaceId, seq + 1);
+    return seq;
+  }
 
   /**
    * Resolve CLI args for Codex exec based on env vars.
@@ -42,9 +55,10 @@ export class CodexService extends EventEmitter {
    * - Otherwise, pass `--sandbox <mode>` and optionally `--approval <policy>`.
    *
    * This keeps the default behavior safe (workspace-write) while enabling
-   * power users to opt out explicitly.
+   * power users to opt out explicitly. `extraArgs` (provider defaults from settings)
+   * go right before the prompt.
    */
-  private buildCodexExecArgs(message: string): string[] {
+  private buildCodexExecArgs(message: string, extraArgs: string[] = []): string[] {
     const bypassEnv = (
       process.env.CODEX_DANGEROUSLY_BYPASS ||
       process.env.CODEX_DANGEROUSLY_BYPASS_APPROVALS_AND_SANDBOX ||
@@ -59,7 +73,7 @@ export class CodexService extends EventEmitter {
     const bypass = truthy(bypassEnv) || sandboxEnv === 'danger-full-access';
 
     if (bypass) {
-      return ['exec', '--dangerously-bypass-approvals-and-sandbox', message];
+      return ['exec', '--dangerously-bypass-approvals-and-sandbox', ...extraArgs, message];
     }
 
     // sandbox mode fallback
@@ -84,7 +98,7 @@ export class CodexService extends EventEmitter {
         break;
     }
 
-    args.push(message);
+    args.push(...extraArgs, message);
     return args;
   }
 
@@ -256,7 +270,9 @@ export class CodexService extends EventEmitter {
   public async sendMessageStream(
     workspaceId: string,
     message: string,
-    conversationId?: string
+    conversationId?: string,
+    extraEnv: Record<string, string> = {},
+    extraArgs: string[] = []
   ): Promise<void> {
     // Find agent for this workspace
 
@@ -281,6 +297,15 @@ export class CodexService extends EventEmitter {
       return;
     }
 
+    let provider: AgentProviderSpec;
+    try {
+      provider = resolveProvider('codex');
+      assertRequiredEnv(provider, { ...process.env, ...extraEnv });
+    } catch (e: any) {
+      this.emit('codex:error', { workspaceId, error: e?.message || String(e) });
+      return;
+    }
+
     // If a stream is already running for this workspace, stop it first
     if (this.runningProcesses.has(workspaceId)) {
       await this.stopMessageStream(workspaceId);
@@ -299,14 +324,24 @@ export class CodexService extends EventEmitter {
 
     try {
       // Spawn codex directly with args to avoid shell quoting issues (backticks, quotes, etc.)
-      const args = this.buildCodexExecArgs(message);
+      const args = this.buildCodexExecArgs(message, [
+        ...provider.defaultArgs,
+        ...codexMcpArgs(mcpServersFor('codex', workspaceId)),
+        ...extraArgs,
+      ]);
       log.info(
         `Executing: codex ${args.map((a) => (a.includes(' ') ? '"' + a + '"' : a)).join(' ')} in ${agent.worktreePath}`
       );
 
       this.initializeStreamLog(workspaceId, agent, message);
-      const child = spawn('codex', args, {
+      const child = spawn(provider.command, args, {
         cwd: agent.worktreePath,
+        env: {
+          ...process.env,
+          ...scratchService.envFor(agent.worktreePath),
+          ...dependencyCacheService.envFor(agent.worktreePath),
+          ...extraEnv,
+        },
         stdio: ['ignore', 'pipe', 'pipe'],
       });
 
@@ -323,6 +358,8 @@ export class CodexService extends EventEmitter {
           output,
           agentId: agent.id,
           conversationId: convId,
+          seq: this.nextOutputSeq(workspaceId),
+          stream: 'stdout',
         });
       });
 
@@ -331,7 +368,14 @@ export class CodexService extends EventEmitter {
         const error = data.toString();
         this.appendStreamLog(workspaceId, `\n[ERROR] ${error}\n`);
         const convId = this.activeConversations.get(workspaceId);
-        this.emit('codex:error', { workspaceId, error, agentId: agent.id, conversationId: convId });
+        this.emit('codex:error', {
+          workspaceId,
+          error,
+          agentId: agent.id,
+          conversationId: convId,
+          seq: this.nextOutputSeq(workspaceId),
+          stream: 'stderr',
+        });
       });
 
       // Handle completion
@@ -407,7 +451,7 @@ export class CodexService extends EventEmitter {
     }
   }
 
-  public async stopMessageStream(workspaceId: string): Promise<boolean> {
+  public async stopMessageStream(workspaceId: string, kill?: KillOptions): Promise<boolean> {
     const process = this.runningProcesses.get(workspaceId);
     if (!process) {
       console.log('[CodexService] stopMessageStream: no running process for', workspaceId);
@@ -445,14 +489,23 @@ export class CodexService extends EventEmitter {
       process.once('error', handleError);
 
       try {
-        const killed = process.kill('SIGINT');
-        if (!killed) {
-          console.warn(
-            '[CodexService] stopMessageStream: SIGINT not delivered, sending SIGTERM',
-            workspaceId
-          );
-          process.kill('SIGTERM');
-        }
+        terminateProcess(
+          {
+            pid: process.pid,
+            kill: (signal) => {
+              const killed = process.kill(signal as NodeJS.Signals);
+              if (!killed && signal !== 'SIGTERM' && signal !== 'SIGKILL') {
+                console.warn(
+                  `[CodexService] stopMessageStream: ${signal} not delivered, sending SIGTERM`,
+                  workspaceId
+                );
+                process.kill('SIGTERM');
+              }
+            },
+            exited: () => process.exitCode !== null || process.signalCode !== null,
+          },
+          resolveKillOptions('SIGINT', kill)
+        );
       } catch (err: any) {
         if (err && typeof err === 'object' && err.code === 'ESRCH') {
           console.warn('[CodexService] stopMessageStream: process already exited', workspaceId);
@@ -503,12 +556,23 @@ export class CodexService extends EventEmitter {
       };
     }
 
+    let provider: AgentProviderSpec;
+    try {
+      provider = resolveProvider('codex');
+      assertRequiredEnv(provider, process.env);
+    } catch (e: any) {
+      return { success: false, error: e?.message || String(e), agentId: agent.id };
+    }
+
     // Update agent status
     agent.status = 'running';
     agent.lastMessage = message;
 
     try {
-      const args = this.buildCodexExecArgs(message);
+      const args = this.buildCodexExecArgs(message, [
+        ...provider.defaultArgs,
+        ...codexMcpArgs(mcpServersFor('codex', workspaceId)),
+      ]);
       log.info(
         `Executing: codex ${args.map((a) => (a.includes(' ') ? '"' + a + '"' : a)).join(' ')} in ${agent.worktreePath}`
       );
@@ -516,7 +580,7 @@ export class CodexService extends EventEmitter {
       const { stdout, stderr } = await new Promise<{ stdout: string; stderr: string }>(
         (resolve, reject) => {
           execFile(
-            'codex',
+            provider.command,
             args,
             { cwd: agent.worktreePath, timeout: 60000 },
             (error, stdout, stderr) => {
@@ -575,6 +639,13 @@ export class CodexService extends EventEmitter {
     return Array.from(this.agents.values()).find((a) => a.workspaceId === workspaceId) || null;
   }
 
+  /**
+   * Whether a Codex turn is currently streaming for the workspace
+   */
+  public isStreaming(workspaceId: string): boolean {
+    return this.runningProcesses.has(workspaceId);
+  }
+
   /**
    * Get all agents
    */
@@ -0,0 +1,66 @@
+import fs from 'fs';
+import path from 'path';
+
+const CLONE_CONCURRENCY = 32;
+
+/**
+ * Probe whether files can be cloned copy-on-write (APFS clonefile, btrfs/xfs reflink)
+ * from `srcDir` into `destDir`. Uses COPYFILE_FICLONE_FORCE so unsupported filesystems
+ * fail instead of silently falling back to a full copy.
+ */
+export async function supportsCopyOnWrite(srcDir: string, destDir: string): Promise<boolean> {
+  const probe = `.emdash-cow-probe-${process.pid}-${Date.now()}`;
+  const src = path.join(srcDir, probe);
+  const dest = path.join(destDir, probe);
+  try {
+    await fs.promises.writeFile(src, 'probe');
+    await fs.promises.copyFile(src, dest, fs.constants.COPYFILE_FICLONE_FORCE);
+    return true;
+  } catch {
+    return false;
+  } finally {
+    await fs.promises.rm(src, { force: true }).catch(() => {});
+    await fs.promises.rm(dest, { force: true }).catch(() => {});
+  }
+}
+
+/**
+ * Recursively clone `src` into `dest` using copy-on-write file copies.
+ * Top-level entries listed in `exclude` are skipped. Symlinks are recreated as-is.
+ */
+export async function cloneTree(
+  src: string,
+  dest: string,
+  options: { exclude?: string[] } = {}
+): Promise<number> {
+  const exclude = new Set(options.exclude ?? []);
+  const files: Array<[string, string]> = [];
+
+  const walk = async (from: string, to: string, top: boolean) => {
+    await fs.promises.mkdir(to, { recursive: true });
+    const entries = await fs.promises.readdir(from, { withFileTypes: true });
+    for (const entry of entries) {
+      if (top && exclude.has(entry.name)) continue;
+      const s = path.join(from, entry.name);
+      const d = path.join(to, entry.name);
+      if (entry.isDirectory()) {
+        await walk(s, d, false);
+      } else if (entry.isSymbolicLink()) {
+        await fs.promises.symlink(await fs.promises.readlink(s), d);
+      } else if (entry.isFile()) {
+        files.push([s, d]);
+      }
+    }
+  };
+  await walk(src, dest, true);
+
+  let next = 0;
+  const worker = async () => {
+    while (next < files.length) {
+      const [s, d] = files[next++];
+      await fs.promises.copyFile(s, d, fs.constants.COPYFILE_FICLONE_FORCE);
+    }
+  };
+  await Promise.all(Array.from({ length: Math.min(CLONE_CONCURRENCY, files.length) }, worker));
+  return files.length;
+}
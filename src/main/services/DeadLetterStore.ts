@@ -0,0 +1,240 @@
+import { app, BrowserWindow, WebContents } from 'electron';
+import fs from 'fs';
+import path from 'path';
+import crypto from 'crypto';
+import { log } from '../lib/logger';
+import { metricsRegistry } from '../lib/metrics';
+import { eventLog } from './EventLog';
+
+const MAX_LETTERS = 1000;
+// Unacknowledged critical events are re-sent after 2s, 4s, ... then dead-lettered
+const ACK_TIMEOUT_MS = 2000;
+const MAX_DELIVERY_ATTEMPTS = 5;
+
+const deadLetterCount = metricsRegistry.counter(
+  'emdash_dead_letters_total',
+  'Critical events no renderer received, by reason.'
+);
+
+export interface DeadLetter {
+  id: string;
+  channel: string;
+  payload: unknown;
+  reason: string;
+  failedAt: string;
+  attempts: number;
+}
+
+function trySend(wc: WebContents | undefined | null, channel: string, payload: unknown): boolean {
+  if (!wc || wc.isDestroyed()) return false;
+  try {
+    wc.send(channel, payload);
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+/**
+ * Persistent store for critical events (agent completions/errors, PTY exits) that could not
+ * be delivered to any renderer, e.g. because every window was closed or the target
+ * webContents was destroyed. Letters survive restarts and can be inspected and re-emitted.
+ */
+class DeadLetterStore {
+  private letters: DeadLetter[] | null = null;
+
+  private get file(): string {
+    return path.join(app.getPath('userData'), 'dead-letters.json');
+  }
+
+  private load(): DeadLetter[] {
+    if (this.letters) return this.letters;
+    try {
+      const raw = fs.readFileSync(this.file, 'utf8');
+      const parsed = JSON.parse(raw);
+      this.letters = Array.isArray(parsed) ? parsed : [];
+    } catch {
+      this.letters = [];
+    }
+    return this.letters;
+  }
+
+  private persist() {
+    try {
+      fs.writeFileSync(this.file, JSON.stringify(this.letters ?? [], null, 2), 'utf8');
+    } catch (error) {
+      // Keep the letters in memory so they can still be re-emitted this session
+      log.error('deadLetterStore: failed to persist dead letters', error);
+    }
+  }
+
+  record(channel: string, payload: unknown, reason: string): DeadLetter {
+    const letters = this.load();
+    const letter: DeadLetter = {
+      id: crypto.randomUUID(),
+      channel,
+      payload,
+      reason,
+      failedAt: new Date().toISOString(),
+      attempts: 1,
+    };
+    letters.push(letter);
+    deadLetterCount.inc({ reason });
+    if (letters.length > MAX_LETTERS) {
+      const dropped = letters.splice(0, letters.length - MAX_LETTERS);
+      log.warn(`deadLetterStore: dropped ${dropped.length} oldest dead letters`);
+    }
+    log.warn(`deadLetterStore: undeliverable ${channel} (${reason})`);
+    eventLog.record({
+      kind: 'client-dropped',
+      severity: 'warn',
+      message: `Could not deliver ${channel}: ${reason}`,
+      scope: {
+        workspaceId: (payload as any)?.workspaceId,
+        ptyId: channel.startsWith('pty:exit:') ? channel.slice('pty:exit:'.length) : undefined,
+      },
+      detail: { channel, deadLetterId: letter.id },
+    });
+    this.persist();
+    return letter;
+  }
+
+  list(): DeadLetter[] {
+    return [...this.load()];
+  }
+
+  /**
+   * Re-emit letters (all when `ids` is omitted) to every open window. Delivered letters are
+   * removed; the rest stay with their attempt count bumped.
+   */
+  redeliver(ids?: string[]): { delivered: number; remaining: number } {
+    const letters = this.load();
+    const wanted = ids ? new Set(ids) : null;
+    let delivered = 0;
+    this.letters = letters.filter((letter) => {
+      if (wanted && !wanted.has(letter.id)) return true;
+      if (broadcast(letter.channel, letter.payload)) {
+        delivered += 1;
+        return false;
+      }
+      letter.attempts += 1;
+      return true;
+    });
+    this.persist();
+    return { delivered, remaining: this.letters.length };
+  }
+
+  discard(ids?: string[]): number {
+    const letters = this.load();
+    const wanted = ids ? new Set(ids) : null;
+    this.letters = wanted ? letters.filter((l) => !wanted.has(l.id)) : [];
+    this.persist();
+    return letters.length - this.letters.length;
+  }
+}
+
+function broadcast(channel: string, payload: unknown): boolean {
+  let delivered = false;
+  for (const w of BrowserWindow.getAllWindows()) {
+    if (trySend(w.webContents, channel, payload)) delivered = true;
+  }
+  return delivered;
+}
+
+export const deadLetterStore = new DeadLetterStore();
+
+// Critical events sent but not yet acknowledged; key: `${deliveryId}:${webContents id}`
+const pendingDeliveries = new Map<
+  string,
+  { timer: NodeJS.Timeout; settle: (acked: boolean) => void }
+>();
+
+/**
+ * Send an event stamped with a deliveryId and re-send it until the renderer acknowledges it
+ * (critical:ack), gives up after MAX_DELIVERY_ATTEMPTS or the renderer goes away. `settle`
+ * is called once with the outcome. Returns false when the first send already failed.
+ */
+function sendAcked(
+  wc: WebContents,
+  channel: string,
+  payload: Record<string, unknown>,
+  settle: (acked: boolean) => void
+): boolean {
+  if (!trySend(wc, channel, payload)) return false;
+  const key = `${payload.deliveryId}:${wc.id}`;
+  let attempts = 1;
+  const arm = () =>
+    setTimeout(() => {
+      if (attempts >= MAX_DELIVERY_ATTEMPTS || !trySend(wc, channel, payload)) {
+        pendingDeliveries.delete(key);
+        settle(false);
+        return;
+      }
+      attempts += 1;
+      pendingDeliveries.set(key, { timer: arm(), settle });
+    }, ACK_TIMEOUT_MS * attempts);
+  pendingDeliveries.set(key, { timer: arm(), settle });
+  return true;
+}
+
+export function acknowledgeDelivery(wcId: number, deliveryId: string) {
+  const key = `${deliveryId}:${wcId}`;
+  const pending = pendingDeliveries.get(key);
+  if (!pending) return;
+  clearTimeout(pending.timer);
+  pendingDeliveries.delete(key);
+  pending.settle(true);
+}
+
+// Only object payloads can carry a deliveryId; anything else is sent fire-and-forget
+function stamp(payload: unknown): Record<string, unknown> | null {
+  if (!payload || typeof payload !== 'object' || Array.isArray(payload)) return null;
+  return { ...(payload as Record<string, unknown>), deliveryId: crypto.randomUUID() };
+}
+
+/**
+ * Broadcast a critical event to all windows, retrying until one acknowledges it. It is
+ * dead-lettered when no window accepts or acknowledges it, unless `deadLetter` is false.
+ */
+export function broadcastCritical(
+  channel: string,
+  payload: unknown,
+  options: { deadLetter?: boolean } = {}
+): void {
+  const deadLetter = (reason: string) => {
+    if (options.deadLetter !== false) deadLetterStore.record(channel, payload, reason);
+  };
+  const stamped = stamp(payload);
+  if (!stamped) {
+    if (!broadcast(channel, payload)) deadLetter('no open windows');
+    return;
+  }
+  let outstanding = 0;
+  let acked = false;
+  const settle = (ok: boolean) => {
+    acked = acked || ok;
+    outstanding -= 1;
+    if (outstanding === 0 && !acked) deadLetter('not acknowledged');
+  };
+  for (const w of BrowserWindow.getAllWindows()) {
+    if (sendAcked(w.webContents, channel, stamped, settle)) outstanding += 1;
+  }
+  if (outstanding === 0) deadLetter('no open windows');
+}
+
+/**
+ * Send a critical event to a specific renderer, retrying until it is acknowledged and
+ * dead-lettering it when that renderer is gone or never acknowledges it.
+ */
+export function sendCritical(wc: WebContents | undefined, channel: string, payload: unknown) {
+  const stamped = stamp(payload);
+  if (wc && stamped) {
+    const sent = sendAcked(wc, channel, stamped, (acked) => {
+      if (!acked) deadLetterStore.record(channel, payload, 'not acknowledged');
+    });
+    if (sent) return;
+  } else if (trySend(wc, channel, payload)) {
+    return;
+  }
+  deadLetterStore.record(channel, payload, wc ? 'renderer destroyed' : 'no subscriber');
+}
@@ -0,0 +1,112 @@
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+import crypto from 'crypto';
+import { app } from 'electron';
+import { log } from '../lib/logger';
+import { getAppSettings, type DependencyCacheConfig } from '../settings';
+
+function resolveBaseDir(): string {
+  const override = process.env.EMDASH_CACHE_DIR;
+  if (override && override.trim().length > 0) {
+    return path.resolve(override);
+  }
+  try {
+    return path.join(app.getPath('userData'), 'dependency-caches');
+  } catch (error) {
+    log.warn('dependencyCacheService: unable to resolve userData path, using tmp fallback', {
+      error,
+    });
+    return path.join(os.tmpdir(), 'emdash-dependency-caches');
+  }
+}
+
+/**
+ * Named dependency caches shared by every worktree of a project. A cache is either
+ * symlinked into the worktree (e.g. node_modules) or exposed through an env var
+ * (e.g. GOMODCACHE, PIP_CACHE_DIR), so setup hooks reuse what earlier workspaces fetched.
+ */
+class DependencyCacheService {
+  private readonly baseDir = resolveBaseDir();
+
+  cacheDir(projectPath: string, name: string): string {
+    const abs = path.resolve(projectPath);
+    const hash = crypto.createHash('sha1').update(abs).digest('hex').slice(0, 12);
+    const label = path.basename(abs).replace(/[^a-zA-Z0-9._-]/g, '_') || 'project';
+    return path.join(this.baseDir, `${label}-${hash}`, name.replace(/[^a-zA-Z0-9._-]/g, '_'));
+  }
+
+  /**
+   * Symlink every cache with a `link` path into a freshly created worktree.
+   * Returns the relative link paths that were created.
+   */
+  linkIntoWorktree(
+    projectPath: string,
+    worktreePath: string,
+    caches: DependencyCacheConfig[]
+  ): string[] {
+    const linked: string[] = [];
+    const root = path.resolve(worktreePath);
+    for (const cache of caches) {
+      if (!cache.link) continue;
+      try {
+        const target = path.resolve(root, cache.link);
+        if (!target.startsWith(root + path.sep)) continue;
+        // Tracked or previously created content wins over the shared cache
+        if (fs.existsSync(target)) continue;
+        const dir = this.cacheDir(projectPath, cache.name);
+        fs.mkdirSync(dir, { recursive: true });
+        fs.mkdirSync(path.dirname(target), { recursive: true });
+        fs.symlinkSync(dir, target, process.platform === 'win32' ? 'junction' : 'dir');
+        linked.push(cache.link);
+      } catch (error) {
+        log.warn('dependencyCacheService: failed to link cache', { cache: cache.name, error });
+      }
+    }
+    return linked;
+  }
+
+  /**
+   * Env vars pointing env-based caches at the shared directory of the workspace's project.
+   */
+  envFor(workspacePath?: string): Record<string, string> {
+    const caches = getAppSettings()?.repository?.dependencyCaches ?? [];
+    if (!workspacePath || caches.length === 0) return {};
+    const projectPath = this.resolveProjectPath(workspacePath);
+    const env: Record<string, string> = {};
+    for (const cache of caches) {
+      if (!cache.env) continue;
+      try {
+        const dir = this.cacheDir(projectPath, cache.name);
+        fs.mkdirSync(dir, { recursive: true });
+        env[cache.env] = dir;
+      } catch (error) {
+        log.warn('dependencyCacheService: failed to prepare cache', { cache: cache.name, error });
+      }
+    }
+    return env;
+  }
+
+  /**
+   * Map a linked worktree back to its main checkout via the `.git` file so all
+   * worktrees of a project share the same caches.
+   */
+  private resolveProjectPath(workspacePath: string): string {
+    const gitMeta = path.join(workspacePath, '.git');
+    try {
+      if (fs.statSync(gitMeta).isFile()) {
+        const m = fs.readFileSync(gitMeta, 'utf8').match(/gitdir:\s*(.*)\s*$/i);
+        if (m && m[1]) {
+          // <project>/.git/worktrees/<name>
+          const gitDir = path.resolve(workspacePath, m[1].trim());
+          if (path.basename(path.dirname(gitDir)) === 'worktrees') {
+            return path.dirname(path.dirname(path.dirname(gitDir)));
+          }
+        }
+      }
+    } catch {}
+    return workspacePath;
+  }
+}
+
+export const dependencyCacheService = new DependencyCacheService();
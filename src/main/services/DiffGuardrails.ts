@@ -0,0 +1,104 @@
+import { execFile } from 'child_process';
+import { promisify } from 'util';
+import fs from 'fs';
+import path from 'path';
+
+const execFileAsync = promisify(execFile);
+
+// Untracked files larger than this count as a changed file but their lines are not read
+const MAX_UNTRACKED_BYTES = 1024 * 1024;
+
+export interface DiffStats {
+  files: number;
+  insertions: number;
+  deletions: number;
+}
+
+/**
+ * Thresholds for the cumulative diff an agent run may produce. A value of 0 disables that check.
+ */
+export interface DiffGuardrailLimits {
+  warnLines: number;
+  warnFiles: number;
+  stopLines: number;
+  stopFiles: number;
+}
+
+export type GuardrailLevel = 'ok' | 'warn' | 'stop';
+
+export function guardrailsEnabled(limits: DiffGuardrailLimits): boolean {
+  return (
+    limits.warnLines > 0 || limits.warnFiles > 0 || limits.stopLines > 0 || limits.stopFiles > 0
+  );
+}
+
+export function evaluateDiffGuardrails(
+  stats: DiffStats,
+  limits: DiffGuardrailLimits
+): GuardrailLevel {
+  const lines = stats.insertions + stats.deletions;
+  const over = (value: number, limit: number) => limit > 0 && value >= limit;
+  if (over(lines, limits.stopLines) || over(stats.files, limits.stopFiles)) return 'stop';
+  if (over(lines, limits.warnLines) || over(stats.files, limits.warnFiles)) return 'warn';
+  return 'ok';
+}
+
+/**
+ * Resolve the commit an agent run starts from so later measurements include
+ * anything the agent commits on top of it.
+ */
+export async function getBaselineRef(worktreePath: string): Promise<string | null> {
+  try {
+    const { stdout } = await execFileAsync('git', ['rev-parse', 'HEAD'], { cwd: worktreePath });
+    return stdout.trim() || null;
+  } catch {
+    return null;
+  }
+}
+
+export async function measureDiff(worktreePath: string, baseRef: string): Promise<DiffStats> {
+  const stats: DiffStats = { files: 0, insertions: 0, deletions: 0 };
+
+  const { stdout } = await execFileAsync('git', ['diff', '--numstat', baseRef], {
+    cwd: worktreePath,
+    maxBuffer: 10 * 1024 * 1024,
+  });
+  for (const line of stdout.split('\n')) {
+    const parts = line.split('\t');
+    if (parts.length < 3) continue;
+    if (parts[2].endsWith('codex-stream.log')) continue;
+    stats.files += 1;
+    stats.insertions += parts[0] === '-' ? 0 : parseInt(parts[0], 10) || 0;
+    stats.deletions += parts[1] === '-' ? 0 : parseInt(parts[1], 10) || 0;
+  }
+
+  // Untracked files are not part of `git diff`; count their lines as insertions
+  try {
+    const { stdout: untracked } = await execFileAsync(
+      'git',
+      ['ls-files', '--others', '--exclude-standard'],
+      { cwd: worktreePath, maxBuffer: 10 * 1024 * 1024 }
+    );
+    const files = untracked.split('\n').filter((rel) => rel && !rel.endsWith('codex-stream.log'));
+    stats.files += files.length;
+    // One at a time so a large untracked tree cannot exhaust file descriptors
+    for (const rel of files) {
+      stats.insertions += await countUntrackedLines(path.join(worktreePath, rel));
+    }
+  } catch {}
+
+  return stats;
+}
+
+async function countUntrackedLines(file: string): Promise<number> {
+  try {
+    const st = await fs.promises.stat(file);
+    if (!st.isFile() || st.size > MAX_UNTRACKED_BYTES) return 0;
+    const buf = await fs.promises.readFile(file);
+    let count = 0;
+    for (let i = 0; i < buf.length; i++) if (buf[i] === 0x0a) count++;
+    return count;
+  } catch {
+    return 0;
+  }
+}
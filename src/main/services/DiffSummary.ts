@@ -0,0 +1,119 @@
+import { execFile } from 'child_process';
+import { promisify } from 'util';
+import fs from 'fs';
+import path from 'path';
+
+const execFileAsync = promisify(execFile);
+
+export type DiffFileStatus = 'added' | 'modified' | 'deleted' | 'renamed';
+
+export interface DiffFileChange {
+  path: string;
+  previousPath?: string; // for renames
+  status: DiffFileStatus;
+  insertions: number;
+  deletions: number;
+  binary?: boolean;
+}
+
+export interface DiffSummary {
+  baseRef: string;
+  files: DiffFileChange[];
+  added: number;
+  modified: number;
+  deleted: number;
+  renamed: number;
+  insertions: number;
+  deletions: number;
+}
+
+// Agent bookkeeping written into the worktree, not part of the work
+const IGNORED = (rel: string) => rel.endsWith('codex-stream.log');
+
+/**
+ * Combine `git diff --name-status -M` and `git diff --numstat -M` output (both against the
+ * same base) with untracked files into one summary.
+ */
+export function buildDiffSummary(
+  baseRef: string,
+  nameStatus: string,
+  numstat: string,
+  untracked: Array<{ path: string; lines: number }> = []
+): DiffSummary {
+  const byPath = new Map<string, DiffFileChange>();
+  for (const line of nameStatus.split('\n')) {
+    const parts = line.split('\t');
+    if (parts.length < 2) continue;
+    const code = parts[0].charAt(0);
+    const file = parts[parts.length - 1];
+    if (IGNORED(file)) continue;
+    const change: DiffFileChange = {
+      path: file,
+      status:
+        code === 'A' ? 'added' : code === 'D' ? 'deleted' : code === 'R' ? 'renamed' : 'modified',
+      insertions: 0,
+      deletions: 0,
+    };
+    if (code === 'R' && parts.length >= 3) change.previousPath = parts[1];
+    byPath.set(file, change);
+  }
+  for (const line of numstat.split('\n')) {
+    const parts = line.split('\t');
+    if (parts.length < 3) continue;
+    // Renames print as "old => new" or "dir/{old => new}"; key them by the new path
+    const file = resolveRenamedPath(parts.slice(2).join('\t'));
+    const change = byPath.get(file);
+    if (!change) continue;
+    if (parts[0] === '-' || parts[1] === '-') change.binary = true;
+    change.insertions = parseInt(parts[0], 10) || 0;
+    change.deletions = parseInt(parts[1], 10) || 0;
+  }
+  for (const u of untracked) {
+    if (IGNORED(u.path) || byPath.has(u.path)) continue;
+    byPath.set(u.path, { path: u.path, status: 'added', insertions: u.lines, deletions: 0 });
+  }
+
+  const files = [...byPath.values()].sort((a, b) => a.path.localeCompare(b.path));
+  const count = (s: DiffFileStatus) => files.filter((f) => f.status === s).length;
+  return {
+    baseRef,
+    files,
+    added: count('added'),
+    modified: count('modified'),
+    deleted: count('deleted'),
+    renamed: count('renamed'),
+    insertions: files.reduce((n, f) => n + f.insertions, 0),
+    deletions: files.reduce((n, f) => n + f.deletions, 0),
+  };
+}
+
+function resolveRenamedPath(field: string): string {
+  const braces = /^(.*)\{(.*) => (.*)\}(.*)$/.exec(field);
+  if (braces) return `${braces[1]}${braces[3]}${braces[4]}`.replace(/\/\//g, '/');
+  const arrow = field.indexOf(' => ');
+  return arrow >= 0 ? field.slice(arrow + 4) : field;
+}
+
+/**
+ * Summarize everything that changed in the worktree since `baseRef`, including commits the
+ * agent made on top of it and untracked files.
+ */
+export async function summarizeDiff(worktreePath: string, baseRef: string): Promise<DiffSummary> {
+  const git = (args: string[]) =>
+    execFileAsync('git', args, { cwd: worktreePath, maxBuffer: 10 * 1024 * 1024 });
+  const [{ stdout: nameStatus }, { stdout: numstat }, { stdout: others }] = await Promise.all([
+    git(['diff', '--name-status', '-M', baseRef]),
+    git(['diff', '--numstat', '-M', baseRef]),
+    git(['ls-files', '--others', '--exclude-standard']),
+  ]);
+  const untracked: Array<{ path: string; lines: number }> = [];
+  for (const rel of others.split('\n').filter(Boolean)) {
+    let lines = 0;
+    try {
+      const buf = fs.readFileSync(path.join(worktreePath, rel));
+      for (let i = 0; i < buf.length; i++) if (buf[i] === 0x0a) lines++;
+    } catch {}
+    untracked.push({ path: rel, lines });
+  }
+  return buildDiffSummary(baseRef, nameStatus, numstat, untracked);
+}
@@ -0,0 +1,29 @@
+import path from 'path';
+import { getAppSettings } from '../settings';
+
+/**
+ * Resolve a named env profile from settings into the variables to layer over the
+ * session environment. `pathPrepend` entries are placed in front of the inherited PATH.
+ * Returns an empty object when no profile is requested and throws for unknown names.
+ */
+export function resolveEnvProfile(
+  name?: string | null,
+  baseEnv: NodeJS.ProcessEnv = process.env
+): Record<string, string> {
+  const wanted = typeof name === 'string' ? name.trim() : '';
+  if (!wanted) return {};
+  const profile = getAppSettings().envProfiles.profiles.find((p) => p.name === wanted);
+  if (!profile) {
+    throw new Error(`Unknown env profile: ${wanted}`);
+  }
+  const env: Record<string, string> = { ...profile.env };
+  if (profile.pathPrepend.length > 0) {
+    const pathKey =
+      process.platform === 'win32'
+        ? (Object.keys(baseEnv).find((k) => k.toUpperCase() === 'PATH') ?? 'Path')
+        : 'PATH';
+    const inherited = env[pathKey] ?? baseEnv[pathKey] ?? '';
+    env[pathKey] = [...profile.pathPrepend, inherited].filter(Boolean).join(path.delimiter);
+  }
+  return env;
+}
@@ -0,0 +1,63 @@
+const MAX_EVENTS = 500;
+
+export type ServerEventKind =
+  | 'session-failed'
+  | 'session-exited'
+  | 'client-dropped'
+  | 'git-error'
+  | 'agent-failed';
+
+export interface ServerEventScope {
+  workspaceId?: string;
+  worktreePath?: string;
+  ptyId?: string;
+}
+
+export interface ServerEvent {
+  seq: number;
+  at: string;
+  kind: ServerEventKind;
+  severity: 'info' | 'warn' | 'error';
+  message: string;
+  scope: ServerEventScope;
+  detail?: Record<string, unknown>;
+}
+
+/**
+ * Bounded in-memory log of significant main-process events (terminal failures, dropped
+ * renderers, git errors) so the app can explain e.g. why a terminal disconnected without
+ * sending the user to the log files.
+ */
+class EventLog {
+  private events: ServerEvent[] = [];
+  private seq = 0;
+
+  record(event: Omit<ServerEvent, 'seq' | 'at'>): ServerEvent {
+    const entry: ServerEvent = { seq: ++this.seq, at: new Date().toISOString(), ...event };
+    this.events.push(entry);
+    if (this.events.length > MAX_EVENTS) this.events.shift();
+    return entry;
+  }
+
+  /**
+   * Most recent events first. Scope filters match any event carrying the same id or path.
+   */
+  recent(
+    options: ServerEventScope & { limit?: number; kinds?: ServerEventKind[] } = {}
+  ): ServerEvent[] {
+    const limit = Math.max(1, Math.min(options.limit ?? 50, MAX_EVENTS));
+    const kinds = options.kinds?.length ? new Set(options.kinds) : null;
+    const out: ServerEvent[] = [];
+    for (let i = this.events.length - 1; i >= 0 && out.length < limit; i--) {
+      const e = this.events[i];
+      if (kinds && !kinds.has(e.kind)) continue;
+      if (options.workspaceId && e.scope.workspaceId !== options.workspaceId) continue;
+      if (options.ptyId && e.scope.ptyId !== options.ptyId) continue;
+      if (options.worktreePath && e.scope.worktreePath !== options.worktreePath) continue;
+      out.push(e);
+    }
+    return out;
+  }
+}
+
+export const eventLog = new EventLog();
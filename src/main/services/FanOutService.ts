@@ -0,0 +1,178 @@
+import { EventEmitter } from 'events';
+import crypto from 'crypto';
+import { log } from '../lib/logger';
+import { agentService, type AgentStatus, type ProviderId } from './AgentService';
+import { findAgentPreset } from './AgentPresets';
+import { artifactWatcher } from './ArtifactWatcher';
+import { codexService } from './CodexService';
+import { databaseService } from './DatabaseService';
+import { worktreeService } from './WorktreeService';
+
+export type FanOutMemberStatus = 'creating' | AgentStatus;
+
+export interface FanOutTarget {
+  workspaceName: string;
+  providerId?: ProviderId;
+  preset?: string;
+}
+
+export interface FanOutRequest {
+  projectPath: string;
+  projectId: string;
+  prompt: string;
+  targets: FanOutTarget[];
+  concurrency?: number; // concurrent worktree creations, default 3
+}
+
+export interface FanOutMember {
+  workspaceName: string;
+  providerId: ProviderId;
+  preset?: string;
+  workspaceId?: string;
+  worktreePath?: string;
+  branch?: string;
+  status: FanOutMemberStatus;
+  error?: string;
+}
+
+export interface FanOutBatch {
+  id: string;
+  prompt: string;
+  createdAt: string;
+  members: FanOutMember[];
+}
+
+const MAX_BATCHES = 50;
+
+/**
+ * Runs one prompt in several fresh worktrees at once ("try the same task with 3 agents").
+ * Each target gets its own worktree, workspace record and agent run; member progress is
+ * emitted as 'fanout:progress' with the whole batch so listeners never need to merge.
+ */
+export class FanOutService extends EventEmitter {
+  private batches = new Map<string, FanOutBatch>();
+
+  constructor() {
+    super();
+    agentService.on('agent:status', (data: any) => {
+      if (data?.agentId) return;
+      for (const batch of this.batches.values()) {
+        const member = batch.members.find(
+          (m) => m.workspaceId === data?.workspaceId && m.providerId === data?.providerId
+        );
+        if (!member) continue;
+        member.status = data.status;
+        member.error = data.error;
+        this.emitProgress(batch);
+      }
+    });
+  }
+
+  /**
+   * Validate the request and return the batch right away; worktrees are created and
+   * agents started in the background, reporting through 'fanout:progress'.
+   */
+  start(req: FanOutRequest): FanOutBatch {
+    const prompt = String(req.prompt ?? '').trim();
+    if (!prompt) throw new Error('A prompt is required');
+    const targets = Array.isArray(req.targets) ? req.targets : [];
+    if (targets.length === 0) throw new Error('At least one target is required');
+    for (const t of targets) {
+      if (!String(t?.workspaceName ?? '').trim()) throw new Error('Every target needs a name');
+      if (!t.providerId && !t.preset) {
+        throw new Error(`Target ${t.workspaceName} needs a providerId or preset`);
+      }
+    }
+
+    const batch: FanOutBatch = {
+      id: crypto.randomUUID(),
+      prompt,
+      createdAt: new Date().toISOString(),
+      members: targets.map((t) => ({
+        workspaceName: t.workspaceName.trim(),
+        // Presets name their provider; resolving here also rejects unknown presets up front
+        providerId: t.providerId ?? (findAgentPreset(t.preset)!.providerId as ProviderId),
+        preset: t.preset,
+        status: 'creating',
+      })),
+    };
+    this.batches.set(batch.id, batch);
+    this.prune();
+    void this.run(batch, req).catch((error) => {
+      log.error('Fan-out batch failed:', error);
+    });
+    return batch;
+  }
+
+  get(batchId: string): FanOutBatch | null {
+    return this.batches.get(batchId) ?? null;
+  }
+
+  private async run(batch: FanOutBatch, req: FanOutRequest) {
+    const results = await worktreeService.createWorktrees(
+      req.projectPath,
+      batch.members.map((m) => m.workspaceName),
+      req.projectId,
+      { concurrency: req.concurrency }
+    );
+    await Promise.all(
+      batch.members.map(async (member, index) => {
+        const result = results[index];
+        if (!result?.success || !result.worktree) {
+          this.fail(batch, member, result?.error || 'Failed to create worktree');
+          return;
+        }
+        const wt = result.worktree;
+        member.workspaceId = wt.id;
+        member.worktreePath = wt.path;
+        member.branch = wt.branch;
+        try {
+          await databaseService.saveWorkspace({
+            id: wt.id,
+            projectId: req.projectId,
+            name: member.workspaceName,
+            branch: wt.branch,
+            path: wt.path,
+            status: 'running',
+            metadata: {
+              fanOut: { batchId: batch.id, provider: member.providerId, preset: member.preset },
+            },
+          });
+          // Codex runs go through codexService, which needs an agent for the workspace
+          if (member.providerId === 'codex') await codexService.createAgent(wt.id, wt.path);
+          artifactWatcher.watch(wt.path, wt.id);
+          await agentService.startStream({
+            providerId: member.providerId,
+            preset: member.preset,
+            workspaceId: wt.id,
+            worktreePath: wt.path,
+            message: batch.prompt,
+          });
+        } catch (error: any) {
+          this.fail(batch, member, error?.message || String(error));
+        }
+      })
+    );
+  }
+
+  private fail(batch: FanOutBatch, member: FanOutMember, error: string) {
+    member.status = 'error';
+    member.error = error;
+    this.emitProgress(batch);
+  }
+
+  private emitProgress(batch: FanOutBatch) {
+    this.emit('fanout:progress', batch);
+  }
+
+  /** Forget the oldest batches; their agents and worktrees are unaffected. */
+  private prune() {
+    while (this.batches.size > MAX_BATCHES) {
+      const oldest = this.batches.keys().next().value;
+      if (oldest === undefined) break;
+      this.batches.delete(oldest);
+    }
+  }
+}
+
+export const fanOutService = new FanOutService();
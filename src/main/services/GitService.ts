@@ -1,4 +1,4 @@
-import { execFile } from 'child_process';
+import { execFile, spawn } from 'child_process';
 import { promisify } from 'util';
 import * as fs from 'fs';
 import * as path from 'path';
@@ -239,3 +239,56 @@ export async function getFileDiff(
     }
   }
 }
+
+const MAX_GENERATOR_INPUT = 512 * 1024;
+
+/**
+ * Pipe the staged diff to the configured generator command and return the message it prints.
+ * Returns null when no generator is configured or nothing is staged. `env` applies only to
+ * reading the diff, e.g. to point GIT_INDEX_FILE at a temporary index.
+ */
+export async function generateCommitMessage(
+  workspacePath: string,
+  env?: NodeJS.ProcessEnv
+): Promise<string | null> {
+  const { getAppSettings } = await import('../settings');
+  const { generatorCommand, timeoutMs } = getAppSettings().commitMessage;
+  if (!generatorCommand) return null;
+
+  const { stdout: diff } = await execFileAsync('git', ['diff', '--cached'], {
+    cwd: workspacePath,
+    env,
+    maxBuffer: 20 * 1024 * 1024,
+  });
+  if (!diff.trim()) return null;
+
+  return new Promise<string>((resolve, reject) => {
+    const child = spawn(generatorCommand, { cwd: workspacePath, shell: true });
+    let out = '';
+    let err = '';
+    const timer = setTimeout(() => {
+      child.kill();
+      reject(new Error(`Commit message generator timed out after ${timeoutMs}ms`));
+    }, timeoutMs);
+    child.stdout.on('data', (d) => (out += d.toString()));
+    child.stderr.on('data', (d) => (err += d.toString()));
+    child.on('error', (e) => {
+      clearTimeout(timer);
+      reject(e);
+    });
+    child.on('close', (code) => {
+      clearTimeout(timer);
+      const message = out.trim();
+      if (code !== 0) {
+        reject(new Error(err.trim() || `Commit message generator exited with code ${code}`));
+      } else if (!message) {
+        reject(new Error('Commit message generator produced no output'));
+      } else {
+        resolve(message);
+      }
+    });
+    // Generators may exit without reading all input; ignore EPIPE
+    child.stdin.on('error', () => {});
+    child.stdin.end(diff.slice(0, MAX_GENERATOR_INPUT));
+  });
+}
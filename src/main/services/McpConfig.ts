@@ -0,0 +1,43 @@
+import { getAppSettings, type McpServerConfig } from '../settings';
+
+/**
+ * MCP servers from settings that apply to a run of `providerId` in `workspaceId`.
+ */
+export function mcpServersFor(providerId: string, workspaceId: string): McpServerConfig[] {
+  return getAppSettings().mcp.servers.filter(
+    (s) =>
+      (s.providers.length === 0 || s.providers.includes(providerId)) &&
+      (s.workspaces.length === 0 || s.workspaces.includes(workspaceId))
+  );
+}
+
+/** `mcpServers` map in the shape Claude's `--mcp-config` and SDK options expect. */
+export function claudeMcpServers(servers: McpServerConfig[]) {
+  const out: Record<string, { command: string; args: string[]; env: Record<string, string> }> =
+    {};
+  for (const s of servers) out[s.name] = { command: s.command, args: s.args, env: s.env };
+  return out;
+}
+
+export function claudeMcpArgs(servers: McpServerConfig[]): string[] {
+  if (servers.length === 0) return [];
+  return ['--mcp-config', JSON.stringify({ mcpServers: claudeMcpServers(servers) })];
+}
+
+/**
+ * Codex reads MCP servers from config.toml; pass them as `-c` overrides instead. JSON
+ * strings and arrays of strings are valid TOML values.
+ */
+export function codexMcpArgs(servers: McpServerConfig[]): string[] {
+  const args: string[] = [];
+  for (const s of servers) {
+    const prefix = `mcp_servers.${s.name}`;
+    args.push('-c', `${prefix}.command=${JSON.stringify(s.command)}`);
+    args.push('-c', `${prefix}.args=${JSON.stringify(s.args)}`);
+    const env = Object.entries(s.env)
+      .map(([k, v]) => `${k}=${JSON.stringify(v)}`)
+      .join(', ');
+    if (env) args.push('-c', `${prefix}.env={ ${env} }`);
+  }
+  return args;
+}
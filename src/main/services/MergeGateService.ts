@@ -0,0 +1,150 @@
+import { EventEmitter } from 'events';
+import { exec, execFile } from 'child_process';
+import { promisify } from 'util';
+import { log } from '../lib/logger';
+import type { MergeGateConfig } from '../settings';
+
+const execAsync = promisify(exec);
+const execFileAsync = promisify(execFile);
+
+const DEFAULT_GATE_TIMEOUT_MS = 10 * 60 * 1000;
+const MAX_GATE_OUTPUT = 16 * 1024;
+
+export type MergeGateStatus = 'pending' | 'running' | 'passed' | 'failed';
+
+export interface MergeGateResult {
+  id: string;
+  kind: MergeGateConfig['kind'];
+  status: MergeGateStatus;
+  output?: string;
+  durationMs?: number;
+}
+
+/**
+ * Evaluates the configured pre-merge gates for a workspace branch.
+ * Emits 'gate:status' for every transition so the UI can render per-gate progress.
+ */
+export class MergeGateService extends EventEmitter {
+  // Commits a human approved for merging; a new commit on the branch needs a new approval
+  private approvedCommits = new Set<string>();
+
+  private async getGates(): Promise<MergeGateConfig[]> {
+    const { getAppSettings } = await import('../settings');
+    return getAppSettings()?.mergeGates?.gates ?? [];
+  }
+
+  /** Approve the branch's current tip for merging. Returns the approved commit. */
+  async approve(repoPath: string, branch: string): Promise<string> {
+    const { stdout } = await execFileAsync(
+      'git',
+      ['rev-parse', '--verify', '--end-of-options', `refs/heads/${branch}^{commit}`],
+      { cwd: repoPath }
+    );
+    const commit = stdout.trim();
+    this.approvedCommits.add(commit);
+    return commit;
+  }
+
+  /**
+   * Run the gates for `commit`, the branch tip about to be merged. Command gates run in the
+   * worktree, which must be clean and checked out at that commit so they test what is merged.
+   */
+  async evaluate(
+    worktreePath: string | undefined,
+    options: { branch: string; commit: string }
+  ): Promise<{ passed: boolean; gates: MergeGateResult[] }> {
+    const gates = await this.getGates();
+    const results: MergeGateResult[] = gates.map((g) => ({
+      id: g.id,
+      kind: g.kind,
+      status: 'pending',
+    }));
+
+    let passed = true;
+    for (let i = 0; i < gates.length; i++) {
+      const gate = gates[i];
+      const result = results[i];
+
+      // Stop at the first red gate; remaining gates stay pending
+      if (!passed) break;
+
+      if (gate.kind === 'approval') {
+        const approved = this.approvedCommits.has(options.commit);
+        result.status = approved ? 'passed' : 'failed';
+        if (!approved) result.output = 'Awaiting human approval';
+        this.report(worktreePath, options.branch, result);
+        passed = result.status === 'passed';
+        continue;
+      }
+
+      if (!worktreePath) {
+        result.status = 'failed';
+        result.output = 'Worktree path unavailable; cannot run gate command';
+        this.report(worktreePath, options.branch, result);
+        passed = false;
+        continue;
+      }
+
+      const mismatch = await this.checkWorktree(worktreePath, options.commit);
+      if (mismatch) {
+        result.status = 'failed';
+        result.output = mismatch;
+        this.report(worktreePath, options.branch, result);
+        passed = false;
+        continue;
+      }
+
+      result.status = 'running';
+      this.report(worktreePath, options.branch, result);
+      const started = Date.now();
+      try {
+        const { stdout, stderr } = await execAsync(gate.command!, {
+          cwd: worktreePath,
+          timeout: gate.timeoutMs ?? DEFAULT_GATE_TIMEOUT_MS,
+          maxBuffer: 10 * 1024 * 1024,
+        });
+        result.status = 'passed';
+        result.output = this.trimOutput([stdout, stderr].filter(Boolean).join('\n'));
+      } catch (error: any) {
+        result.status = 'failed';
+        result.output = this.trimOutput(
+          [error?.stdout, error?.stderr, error?.killed ? 'Gate timed out' : error?.message]
+            .filter(Boolean)
+            .join('\n')
+        );
+        passed = false;
+        log.warn(`Merge gate ${gate.id} failed for ${options.branch}`);
+      }
+      result.durationMs = Date.now() - started;
+      this.report(worktreePath, options.branch, result);
+    }
+
+    return { passed, gates: results };
+  }
+
+  /** Why the worktree does not match the commit being merged, or null when it does. */
+  private async checkWorktree(worktreePath: string, commit: string): Promise<string | null> {
+    try {
+      const [{ stdout: head }, { stdout: status }] = await Promise.all([
+        execFileAsync('git', ['rev-parse', 'HEAD'], { cwd: worktreePath }),
+        execFileAsync('git', ['status', '--porcelain'], { cwd: worktreePath }),
+      ]);
+      if (head.trim() !== commit) return 'Worktree is not checked out at the branch being merged';
+      const dirty = status.split('\n').some((l) => l.trim() && !l.endsWith('codex-stream.log'));
+      return dirty ? 'Worktree has uncommitted changes; commit or stash them first' : null;
+    } catch (error: any) {
+      return `Unable to inspect worktree: ${error?.message || error}`;
+    }
+  }
+
+  private report(worktreePath: string | undefined, branch: string, gate: MergeGateResult) {
+    this.emit('gate:status', { worktreePath, branch, gate: { ...gate } });
+  }
+
+  private trimOutput(text: string): string {
+    const t = text.trim();
+    return t.length > MAX_GATE_OUTPUT ? t.slice(t.length - MAX_GATE_OUTPUT) : t;
+  }
+}
+
+export const mergeGateService = new MergeGateService();
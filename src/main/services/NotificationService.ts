@@ -0,0 +1,138 @@
+import { app, BrowserWindow } from 'electron';
+import { statfs } from 'fs/promises';
+import crypto from 'crypto';
+import { log } from '../lib/logger';
+import { agentService, type AgentRunResult } from './AgentService';
+import { worktreeService } from './WorktreeService';
+
+const MAX_NOTIFICATIONS = 100;
+const DISK_CHECK_INTERVAL_MS = 5 * 60 * 1000;
+// A volume is nearly full below either bound
+const DISK_MIN_FREE_BYTES = 2 * 1024 * 1024 * 1024;
+const DISK_MIN_FREE_RATIO = 0.05;
+
+export type AppNotificationKind =
+  | 'shutdown-pending'
+  | 'worktree-removed'
+  | 'agent-finished'
+  | 'disk-nearly-full';
+
+export interface AppNotification {
+  id: string;
+  at: string;
+  kind: AppNotificationKind;
+  severity: 'info' | 'warn' | 'error';
+  title: string;
+  message: string;
+  scope: { workspaceId?: string; worktreePath?: string };
+}
+
+/**
+ * One channel (app:notification) for main-process events worth a toast, so the renderer
+ * does not have to listen to or poll every service that can produce one.
+ */
+export class NotificationService {
+  private recentItems: AppNotification[] = [];
+  private lowDisk = new Set<string>();
+  private diskTimer: NodeJS.Timeout | null = null;
+  private started = false;
+
+  start() {
+    if (this.started) return;
+    this.started = true;
+
+    app.on('before-quit', () => {
+      this.notify({
+        kind: 'shutdown-pending',
+        severity: 'warn',
+        title: 'emdash is quitting',
+        message: 'Running terminals and agents will be stopped.',
+        scope: {},
+      });
+    });
+    worktreeService.on('worktree:removed', (data: any) => {
+      this.notify({
+        kind: 'worktree-removed',
+        severity: 'info',
+        title: 'Worktree removed',
+        message: data?.branchDeleted
+          ? `Removed ${data.branch} and its branch`
+          : `Removed ${data?.path ?? 'worktree'}`,
+        scope: { worktreePath: data?.path },
+      });
+    });
+    agentService.on(
+      'agent:result',
+      (result: AgentRunResult & { providerId: string; workspaceId: string }) => {
+        const failed = result.status !== 'stopped';
+        const files = result.diff
+          ? `${result.providerId}: ${result.diff.files.length} file(s) changed`
+          : `${result.providerId} run finished`;
+        this.notify({
+          kind: 'agent-finished',
+          severity: failed ? 'error' : 'info',
+          title:
+            result.status === 'timed-out'
+              ? 'Agent run timed out'
+              : failed
+                ? 'Agent run failed'
+                : 'Agent finished',
+          message: failed ? result.error || `${result.providerId} exited with an error` : files,
+          scope: { workspaceId: result.workspaceId },
+        });
+      }
+    );
+
+    void this.checkDisk();
+    this.diskTimer = setInterval(() => void this.checkDisk(), DISK_CHECK_INTERVAL_MS);
+    this.diskTimer.unref();
+  }
+
+  notify(input: Omit<AppNotification, 'id' | 'at'>): AppNotification {
+    const notification: AppNotification = {
+      id: crypto.randomUUID(),
+      at: new Date().toISOString(),
+      ...input,
+    };
+    this.recentItems.push(notification);
+    if (this.recentItems.length > MAX_NOTIFICATIONS) this.recentItems.shift();
+    for (const w of BrowserWindow.getAllWindows()) {
+      if (!w.webContents.isDestroyed()) w.webContents.send('app:notification', notification);
+    }
+    return notification;
+  }
+
+  /** Most recent first, for windows that open after a notification was sent. */
+  recent(limit = 50): AppNotification[] {
+    return this.recentItems.slice(-Math.max(1, Math.min(limit, MAX_NOTIFICATIONS))).reverse();
+  }
+
+  private async checkDisk() {
+    for (const dir of new Set([app.getPath('userData'), app.getPath('home')])) {
+      try {
+        const st = await statfs(dir);
+        const free = st.bavail * st.bsize;
+        const total = st.blocks * st.bsize;
+        const low = free < DISK_MIN_FREE_BYTES || (total > 0 && free / total < DISK_MIN_FREE_RATIO);
+        // Notify once per episode; clear when space has been freed again
+        if (!low) {
+          this.lowDisk.delete(dir);
+          continue;
+        }
+        if (this.lowDisk.has(dir)) continue;
+        this.lowDisk.add(dir);
+        this.notify({
+          kind: 'disk-nearly-full',
+          severity: 'warn',
+          title: 'Disk nearly full',
+          message: `${(free / 1024 ** 3).toFixed(1)} GB free on the volume holding ${dir}`,
+          scope: {},
+        });
+      } catch (error) {
+        log.debug('notifications: disk check failed', { dir, error });
+      }
+    }
+  }
+}
+
+export const notificationService = new NotificationService();
@@ -0,0 +1,82 @@
+import { execFile } from 'child_process';
+import { accessSync, constants, statSync } from 'fs';
+import path from 'path';
+import { promisify } from 'util';
+import { isKnownProvider, listAllowedProviders } from './ProviderRegistry';
+
+const execFileAsync = promisify(execFile);
+
+// Agent CLIs worth reporting, whether or not emdash can launch them yet
+const KNOWN_CLIS = ['claude', 'codex', 'aider', 'gemini'];
+const VERSION_TIMEOUT_MS = 5000;
+
+export interface DiscoveredProvider {
+  name: string;
+  path: string;
+  version: string | null; // first line of `--version`, null when it failed or timed out
+  supported: boolean; // emdash can start agents with it
+  allowed: boolean; // and settings allow it
+}
+
+/**
+ * First executable named `name` on `pathEnv`, honouring PATHEXT on Windows.
+ */
+export function findOnPath(
+  name: string,
+  pathEnv = process.env.PATH ?? '',
+  platform: NodeJS.Platform = process.platform
+): string | null {
+  const exts =
+    platform === 'win32'
+      ? (process.env.PATHEXT || '.EXE;.CMD;.BAT;.COM').split(';').filter(Boolean)
+      : [''];
+  const delimiter = platform === 'win32' ? ';' : ':';
+  for (const dir of pathEnv.split(delimiter)) {
+    if (!dir) continue;
+    for (const ext of exts) {
+      const candidate = path.join(dir, name + ext);
+      try {
+        if (!statSync(candidate).isFile()) continue;
+        if (platform !== 'win32') accessSync(candidate, constants.X_OK);
+        return candidate;
+      } catch {}
+    }
+  }
+  return null;
+}
+
+async function probeVersion(file: string): Promise<string | null> {
+  try {
+    const { stdout, stderr } = await execFileAsync(file, ['--version'], {
+      timeout: VERSION_TIMEOUT_MS,
+      // .cmd shims need a shell on Windows
+      shell: process.platform === 'win32',
+    });
+    const line = (stdout || stderr).split('\n').find((l) => l.trim());
+    return line ? line.trim() : null;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Agent CLIs installed on this machine's PATH, with their resolved path and version, so the
+ * provider picker only offers what can actually run.
+ */
+export async function discoverProviders(): Promise<DiscoveredProvider[]> {
+  const allowed = new Set<string>(listAllowedProviders().map((p) => p.id));
+  const found = KNOWN_CLIS.map((name) => ({ name, file: findOnPath(name) }));
+  const probed = await Promise.all(
+    found.map(async ({ name, file }) => {
+      if (!file) return null;
+      return {
+        name,
+        path: file,
+        version: await probeVersion(file),
+        supported: isKnownProvider(name),
+        allowed: allowed.has(name),
+      };
+    })
+  );
+  return probed.filter((p): p is DiscoveredProvider => p !== null);
+}
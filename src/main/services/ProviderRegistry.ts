@@ -0,0 +1,62 @@
+import { getAppSettings } from '../settings';
+
+export type ProviderId = 'codex' | 'claude';
+
+export interface AgentProviderSpec {
+  id: ProviderId;
+  command: string; // binary that is executed; never taken from the renderer
+  defaultArgs: string[];
+  requiredEnv: string[];
+}
+
+const BUILTIN_COMMANDS: Record<ProviderId, string> = {
+  codex: 'codex',
+  claude: 'claude',
+};
+
+export function isKnownProvider(id: unknown): id is ProviderId {
+  return typeof id === 'string' && Object.prototype.hasOwnProperty.call(BUILTIN_COMMANDS, id);
+}
+
+/**
+ * Look up a provider that settings allow agents to be started with.
+ * Throws for unknown or disallowed ids so callers can surface the error to the renderer.
+ */
+export function resolveProvider(id: unknown): AgentProviderSpec {
+  if (!isKnownProvider(id)) {
+    throw new Error(`Unknown agent provider: ${String(id)}`);
+  }
+  const cfg = getAppSettings().agentProviders;
+  if (!cfg.allowed.includes(id)) {
+    throw new Error(`Agent provider not allowed: ${id}`);
+  }
+  return {
+    id,
+    command: BUILTIN_COMMANDS[id],
+    defaultArgs: [...(cfg.defaultArgs[id] ?? [])],
+    requiredEnv: [...(cfg.requiredEnv[id] ?? [])],
+  };
+}
+
+/**
+ * Fail fast when a variable the provider needs is missing from the session environment.
+ */
+export function assertRequiredEnv(spec: AgentProviderSpec, env: NodeJS.ProcessEnv) {
+  const missing = spec.requiredEnv.filter((k) => !env[k]);
+  if (missing.length > 0) {
+    throw new Error(`Missing required env for ${spec.id}: ${missing.join(', ')}`);
+  }
+}
+
+/**
+ * Providers currently allowed by settings, in registry order.
+ */
+export function listAllowedProviders(): AgentProviderSpec[] {
+  const out: AgentProviderSpec[] = [];
+  for (const id of Object.keys(BUILTIN_COMMANDS) as ProviderId[]) {
+    try {
+      out.push(resolveProvider(id));
+    } catch {}
+  }
+  return out;
+}
@@ -0,0 +1,87 @@
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+import crypto from 'crypto';
+import { app } from 'electron';
+import { log } from '../lib/logger';
+
+function resolveBaseDir(): string {
+  const override = process.env.EMDASH_SCRATCH_DIR;
+  if (override && override.trim().length > 0) {
+    return path.resolve(override);
+  }
+  try {
+    return path.join(app.getPath('userData'), 'scratch');
+  } catch (error) {
+    log.warn('scratchService: unable to resolve userData path, using tmp fallback', { error });
+    return path.join(os.tmpdir(), 'emdash-scratch');
+  }
+}
+
+/**
+ * Per-workspace scratch directories that live outside the git worktree, so agents
+ * have a sanctioned place for temporary artifacts that never shows up in git status.
+ */
+class ScratchService {
+  private readonly baseDir = resolveBaseDir();
+
+  private dirFor(workspacePath: string): string {
+    const abs = path.resolve(workspacePath);
+    const hash = crypto.createHash('sha1').update(abs).digest('hex').slice(0, 12);
+    const label = path.basename(abs).replace(/[^a-zA-Z0-9._-]/g, '_') || 'workspace';
+    return path.join(this.baseDir, `${label}-${hash}`);
+  }
+
+  /**
+   * Resolve (and create) the scratch directory for a workspace path.
+   */
+  ensureScratchDir(workspacePath: string): string | null {
+    try {
+      const dir = this.dirFor(workspacePath);
+      fs.mkdirSync(dir, { recursive: true });
+      return dir;
+    } catch (error) {
+      log.warn('scratchService: failed to create scratch dir', { workspacePath, error });
+      return null;
+    }
+  }
+
+  /**
+   * Env vars to merge into PTY and agent processes started for a workspace. `cwd` may be
+   * anywhere inside the worktree; outside any worktree there is no scratch directory.
+   */
+  envFor(cwd?: string): Record<string, string> {
+    const root = cwd ? this.findWorktreeRoot(cwd) : null;
+    if (!root) return {};
+    const dir = this.ensureScratchDir(root);
+    return dir ? { EMDASH_SCRATCH: dir } : {};
+  }
+
+  /**
+   * Nearest enclosing directory with a `.git` entry (a checkout or linked worktree), so
+   * subdirectories share their workspace's scratch dir. A repository at the home directory
+   * (e.g. dotfiles) does not count.
+   */
+  private findWorktreeRoot(cwd: string): string | null {
+    const home = path.resolve(os.homedir());
+    let dir = path.resolve(cwd);
+    for (;;) {
+      if (dir === home) return null;
+      if (fs.existsSync(path.join(dir, '.git'))) return dir;
+      const parent = path.dirname(dir);
+      if (parent === dir) return null;
+      dir = parent;
+    }
+  }
+
+  async removeScratchDir(workspacePath: string): Promise<void> {
+    const dir = this.dirFor(workspacePath);
+    try {
+      await fs.promises.rm(dir, { recursive: true, force: true });
+    } catch (error) {
+      log.warn('scratchService: failed to remove scratch dir', { dir, error });
+    }
+  }
+}
+
+export const scratchService = new ScratchService();
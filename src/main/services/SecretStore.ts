@@ -0,0 +1,54 @@
+const SECRET_NAME_RE = /^[A-Za-z0-9._-]+$/;
+const ENV_NAME_RE = /^[A-Za-z_][A-Za-z0-9_]*$/;
+
+/**
+ * Named secrets (API keys and the like) kept in the OS keychain. Sessions reference them
+ * by name and the values are resolved in the main process only; nothing here hands a
+ * value back to the renderer.
+ */
+export class SecretStore {
+  private readonly SERVICE_NAME = 'emdash-agent-secrets';
+
+  async listNames(): Promise<string[]> {
+    const keytar = await import('keytar');
+    const creds = await keytar.findCredentials(this.SERVICE_NAME);
+    return creds.map((c) => c.account).sort();
+  }
+
+  async set(name: string, value: string): Promise<void> {
+    const clean = String(name ?? '').trim();
+    if (!SECRET_NAME_RE.test(clean)) {
+      throw new Error('Secret names may only contain letters, digits, ".", "_" and "-"');
+    }
+    if (!value) throw new Error('Secret value cannot be empty.');
+    const keytar = await import('keytar');
+    await keytar.setPassword(this.SERVICE_NAME, clean, value);
+  }
+
+  async delete(name: string): Promise<boolean> {
+    const keytar = await import('keytar');
+    return await keytar.deletePassword(this.SERVICE_NAME, String(name ?? '').trim());
+  }
+
+  /**
+   * Resolve `{ ENV_VAR: secretName }` into env values. Throws for bad variable names and
+   * unknown secrets so a run never starts with a key silently missing.
+   */
+  async resolveEnv(refs?: Record<string, string> | null): Promise<Record<string, string>> {
+    const entries = Object.entries(refs ?? {});
+    if (entries.length === 0) return {};
+    const keytar = await import('keytar');
+    const env: Record<string, string> = {};
+    for (const [envName, secretName] of entries) {
+      if (!ENV_NAME_RE.test(envName)) {
+        throw new Error(`Invalid environment variable name: ${envName}`);
+      }
+      const value = await keytar.getPassword(this.SERVICE_NAME, String(secretName ?? '').trim());
+      if (value === null) throw new Error(`Unknown secret: ${secretName}`);
+      env[envName] = value;
+    }
+    return env;
+  }
+}
+
+export const secretStore = new SecretStore();
@@ -34,6 +34,25 @@ function snapshotPath(id: string): string {
   return path.join(BASE_DIR, `${safe}.json`);
 }
 
+// CSI, OSC (BEL or ST terminated), DCS/PM/APC strings, charset selection and the remaining
+// two-byte escape sequences
+const ANSI_RE = new RegExp(
+  [
+    '\\x1b\\[[0-?]*[ -/]*[@-~]',
+    '\\x1b\\][^\\x07\\x1b]*(?:\\x07|\\x1b\\\\)',
+    '\\x1b[P^_][^\\x1b]*\\x1b\\\\',
+    '\\x1b[()*+][0-9A-Za-z]',
+    '\\x1b[0-~]',
+  ].join('|'),
+  'g'
+);
+
+export function stripAnsi(text: string): string {
+  return text.replace(ANSI_RE, '');
+}
+
+export type TranscriptFormat = 'ansi' | 'plain';
+
 async function ensureDir(): Promise<void> {
   await fs.promises.mkdir(BASE_DIR, { recursive: true });
 }
@@ -130,6 +149,24 @@ class TerminalSnapshotService {
     }
   }
 
+  /**
+   * Saved scrollback for a terminal as text, either with its escape sequences intact or
+   * reduced to plain text with normalised line endings. Null when nothing was recorded.
+   */
+  async getTranscript(
+    id: string,
+    format: TranscriptFormat = 'plain'
+  ): Promise<{ text: string; createdAt: string } | null> {
+    const snapshot = await this.getSnapshot(id);
+    if (!snapshot) return null;
+    if (format === 'ansi') return { text: snapshot.data, createdAt: snapshot.createdAt };
+    const text = stripAnsi(snapshot.data)
+      .replace(/\r\n/g, '\n')
+      .replace(/\r/g, '\n')
+      .replace(/[ \t]+$/gm, '');
+    return { text, createdAt: snapshot.createdAt };
+  }
+
   async deleteSnapshot(id: string): Promise<void> {
     await removeFile(snapshotPath(id));
   }
@@ -0,0 +1,194 @@
+import { EventEmitter } from 'events';
+import { execFile } from 'child_process';
+import { promisify } from 'util';
+import fs from 'fs';
+import path from 'path';
+import { log } from '../lib/logger';
+import { databaseService } from './DatabaseService';
+import { worktreeService } from './WorktreeService';
+import { agentService } from './AgentService';
+import { listPtys } from './ptyManager';
+
+const execFileAsync = promisify(execFile);
+
+const SWEEP_INTERVAL_MS = 60 * 60 * 1000;
+const DAY_MS = 24 * 60 * 60 * 1000;
+
+export interface StaleWorktree {
+  workspaceId: string;
+  projectId: string;
+  projectPath: string;
+  name: string;
+  branch: string;
+  path: string;
+  lastActivityAt: string;
+  dirty: boolean;
+}
+
+export type GcAuditAction = 'flagged' | 'removed' | 'skipped' | 'failed';
+
+export interface GcAuditEvent {
+  action: GcAuditAction;
+  workspaceId: string;
+  path: string;
+  branch: string;
+  lastActivityAt: string;
+  reason?: string;
+  at: string;
+}
+
+/**
+ * Background reaper for workspaces nobody has touched in a while. A worktree is stale when
+ * it has no commits, no workspace updates and no live PTY/agent session within the configured
+ * window. Depending on settings it is only flagged or removed (dirty worktrees are never
+ * removed, and branches are kept so commits stay recoverable). Every decision is emitted as
+ * a 'gc:audit' event.
+ */
+export class WorktreeReaper extends EventEmitter {
+  private timer: NodeJS.Timeout | null = null;
+  private sweeping = false;
+
+  start() {
+    if (this.timer) return;
+    this.timer = setInterval(() => {
+      void this.sweep();
+    }, SWEEP_INTERVAL_MS);
+    this.timer.unref?.();
+  }
+
+  stop() {
+    if (this.timer) clearInterval(this.timer);
+    this.timer = null;
+  }
+
+  /**
+   * List stale worktrees without touching them.
+   */
+  async findStale(staleAfterDays?: number): Promise<StaleWorktree[]> {
+    const { getAppSettings } = await import('../settings');
+    const days = staleAfterDays ?? getAppSettings().worktreeGc.staleAfterDays;
+    const cutoff = Date.now() - days * DAY_MS;
+
+    const [projects, workspaces] = await Promise.all([
+      databaseService.getProjects(),
+      databaseService.getWorkspaces(),
+    ]);
+    const projectPaths = new Map(projects.map((p) => [p.id, p.path]));
+    const ptyCwds = listPtys().map((p) => path.resolve(p.cwd));
+
+    const stale: StaleWorktree[] = [];
+    for (const ws of workspaces) {
+      const projectPath = projectPaths.get(ws.projectId);
+      if (!projectPath || !ws.path) continue;
+      // Only linked worktrees are candidates; never the project checkout itself
+      if (path.resolve(ws.path) === path.resolve(projectPath)) continue;
+      if (!this.isLinkedWorktree(ws.path)) continue;
+
+      const root = path.resolve(ws.path);
+      if (ptyCwds.some((cwd) => cwd === root || cwd.startsWith(root + path.sep))) continue;
+      if (agentService.isActive(ws.id)) continue;
+
+      const lastCommit = await this.lastCommitTime(ws.path);
+      const lastActivity = Math.max(lastCommit, Date.parse(ws.updatedAt) || 0);
+      if (lastActivity >= cutoff) continue;
+
+      stale.push({
+        workspaceId: ws.id,
+        projectId: ws.projectId,
+        projectPath,
+        name: ws.name,
+        branch: ws.branch,
+        path: ws.path,
+        lastActivityAt: new Date(lastActivity).toISOString(),
+        dirty: await this.isDirty(ws.path),
+      });
+    }
+    return stale;
+  }
+
+  async sweep(): Promise<GcAuditEvent[]> {
+    const { getAppSettings } = await import('../settings');
+    const gc = getAppSettings().worktreeGc;
+    if (!gc.enabled || this.sweeping) return [];
+
+    this.sweeping = true;
+    const events: GcAuditEvent[] = [];
+    try {
+      for (const wt of await this.findStale(gc.staleAfterDays)) {
+        if (gc.action !== 'remove') {
+          events.push(this.audit('flagged', wt));
+          continue;
+        }
+        if (wt.dirty) {
+          events.push(this.audit('skipped', wt, 'Worktree has uncommitted changes'));
+          continue;
+        }
+        try {
+          await worktreeService.removeWorktree(
+            wt.projectPath,
+            wt.workspaceId,
+            wt.path,
+            wt.branch,
+            { deleteBranch: false }
+          );
+          await databaseService.deleteWorkspace(wt.workspaceId);
+          events.push(this.audit('removed', wt));
+        } catch (error) {
+          events.push(this.audit('failed', wt, (error as Error).message));
+        }
+      }
+    } catch (error) {
+      log.error('Stale worktree sweep failed:', error);
+    } finally {
+      this.sweeping = false;
+    }
+    return events;
+  }
+
+  private audit(action: GcAuditAction, wt: StaleWorktree, reason?: string): GcAuditEvent {
+    const event: GcAuditEvent = {
+      action,
+      workspaceId: wt.workspaceId,
+      path: wt.path,
+      branch: wt.branch,
+      lastActivityAt: wt.lastActivityAt,
+      ...(reason ? { reason } : {}),
+      at: new Date().toISOString(),
+    };
+    log.info(`worktree gc: ${action} ${wt.path}${reason ? ` (${reason})` : ''}`);
+    this.emit('gc:audit', event);
+    return event;
+  }
+
+  private isLinkedWorktree(worktreePath: string): boolean {
+    try {
+      return fs.statSync(path.join(worktreePath, '.git')).isFile();
+    } catch {
+      return false;
+    }
+  }
+
+  private async lastCommitTime(worktreePath: string): Promise<number> {
+    try {
+      const { stdout } = await execFileAsync('git', ['log', '-1', '--format=%ct'], {
+        cwd: worktreePath,
+      });
+      return (parseInt(stdout.trim(), 10) || 0) * 1000;
+    } catch {
+      return 0;
+    }
+  }
+
+  private async isDirty(worktreePath: string): Promise<boolean> {
+    try {
+      const { stdout } = await execFileAsync('git', ['status', '--porcelain'], {
+        cwd: worktreePath,
+      });
+      return stdout.trim().length > 0;
+    } catch {
+      return true;
+    }
+  }
+}
+
+export const worktreeReaper = new WorktreeReaper();
@@ -1,11 +1,33 @@
-import { execFile } from 'child_process';
+import { EventEmitter } from 'events';
+import { execFile, spawn } from 'child_process';
 import { log } from '../lib/logger';
 import { promisify } from 'util';
 import path from 'path';
 import fs from 'fs';
+import os from 'os';
 import crypto from 'crypto';
+import { mergeGateService, MergeGateResult } from './MergeGateService';
+import { scratchService } from './ScratchService';
+import { dependencyCacheService } from './DependencyCacheService';
+import { cloneTree, supportsCopyOnWrite } from './CowClone';
+import { databaseService } from './DatabaseService';
+import {
+  extractTicket,
+  isValidBranchName,
+  renderBranchTemplate,
+  resolveBranchUser,
+  slugify,
+} from '../lib/branchNaming';
+import { AppError } from '../lib/errorCatalog';
+import type { DependencyCacheConfig } from '../settings';
 
 const execFileAsync = promisify(execFile);
+const DIRTY_CHECK_INTERVAL_MS = 5000;
+
+/** Single-quote a value for the POSIX shell git runs `exec` lines and editors through. */
+function shellQuote(value: string): string {
+  return `'${value.replace(/'/g, `'\\''`)}'`;
+}
 
 export interface WorktreeInfo {
   id: string;
@@ -16,20 +38,86 @@ export interface WorktreeInfo {
   status: 'active' | 'paused' | 'completed' | 'error';
   createdAt: string;
   lastActivity?: string;
+  summary?: WorktreeStatusSummary;
+}
+
+export interface WorktreeQuery {
+  projectPath?: string; // list a single checkout; otherwise every known project is scanned
+  projectId?: string;
+  name?: string; // case-insensitive substring match
+  branch?: string; // case-insensitive substring match
+  includeStatus?: boolean;
+  pageSize?: number;
+  pageToken?: string;
+}
+
+export interface WorktreePage {
+  worktrees: WorktreeInfo[];
+  nextPageToken?: string;
+}
+
+export interface WorktreeStatusSummary {
+  dirty: boolean;
+  lastCommitSubject?: string;
+  ahead: number;
+  behind: number;
+}
+
+export type MergeStrategy = 'fast-forward' | 'merge' | 'squash';
+const MERGE_STRATEGIES: MergeStrategy[] = ['fast-forward', 'merge', 'squash'];
+
+export interface MergeToBaseResult {
+  merged: boolean;
+  branch: string;
+  baseBranch: string;
+  strategy: MergeStrategy;
+  conflicts: string[];
+  gates: MergeGateResult[];
+  output?: string;
+}
+
+export type BatchCreateResult =
+  | { workspaceName: string; success: true; worktree: WorktreeInfo }
+  | { workspaceName: string; success: false; error: string };
+
+export type RebaseAction = 'pick' | 'reword' | 'squash' | 'fixup' | 'drop';
+
+export interface RebasePlanEntry {
+  action: RebaseAction;
+  sha: string;
+  subject: string;
+  message?: string; // replacement commit message for reword/squash
+}
+
+export interface RebasePlan {
+  base: string;
+  entries: RebasePlanEntry[];
+}
+
+export type SyncStrategy = 'rebase' | 'merge';
+
+export interface SyncWorktreeResult {
+  synced: boolean;
+  strategy: SyncStrategy;
+  upstream: string;
+  conflicts: string[];
+  output?: string;
 }
 
-export class WorktreeService {
+export type SyncProgress = { step: 'fetch' | 'rebase' | 'merge' | 'done'; message: string };
+
+export class WorktreeService extends EventEmitter {
   private worktrees = new Map<string, WorktreeInfo>();
+  // Last observed dirty state per worktree path, used to emit 'worktree:dirty-changed'
+  private dirtyState = new Map<string, boolean>();
+  private dirtyTimer: NodeJS.Timeout | null = null;
+  private checkingDirty = false;
 
   /**
    * Slugify workspace name to make it shell-safe
    */
-  private slugify(name: string): string {
-    return name
-      .toLowerCase()
-      .replace(/[^a-z0-9-]/g, '-')
-      .replace(/-+/g, '-')
-      .replace(/^-|-$/g, '');
+  private slugify(name: string, strategy?: string): string {
+    return slugify(name, strategy);
   }
 
   /**
@@ -47,17 +135,22 @@ export class WorktreeService {
   async createWorktree(
     projectPath: string,
     workspaceName: string,
-    projectId: string
+    projectId: string,
+    options: { copyFiles?: string[]; ticket?: string } = {}
   ): Promise<WorktreeInfo> {
     try {
-      const sluggedName = this.slugify(workspaceName);
       const timestamp = Date.now();
       const { getAppSettings } = await import('../settings');
       const settings = getAppSettings();
+      const slugStrategy = settings?.repository?.slugStrategy;
+      const sluggedName = this.slugify(workspaceName, slugStrategy) || 'workspace';
       const template = settings?.repository?.branchTemplate || 'agent/{slug}-{timestamp}';
-      const branchName = this.renderBranchNameTemplate(template, {
+      const branchName = await this.resolveBranchName(projectPath, template, {
+        workspaceName,
         slug: sluggedName,
         timestamp: String(timestamp),
+        ticket: options.ticket,
+        slugStrategy,
       });
       const worktreePath = path.join(projectPath, '..', `worktrees/${sluggedName}-${timestamp}`);
       const worktreeId = this.stableIdFromPath(worktreePath);
@@ -66,7 +159,7 @@ export class WorktreeService {
 
       // Check if worktree path already exists
       if (fs.existsSync(worktreePath)) {
-        throw new Error(`Worktree directory already exists: ${worktreePath}`);
+        throw new AppError('WORKTREE_EXISTS', { path: worktreePath });
       }
 
       // Ensure worktrees directory exists
@@ -75,15 +168,25 @@ export class WorktreeService {
         fs.mkdirSync(worktreesDir, { recursive: true });
       }
 
-      // Create the worktree
-      const { stdout, stderr } = await execFileAsync(
-        'git',
-        ['worktree', 'add', '-b', branchName, worktreePath],
-        { cwd: projectPath }
-      );
-
-      log.debug('Git worktree stdout:', stdout);
-      log.debug('Git worktree stderr:', stderr);
+      // Create the worktree, cloning a prepared checkout copy-on-write when enabled
+      const cloned =
+        settings?.repository?.cloneFromGolden === true &&
+        (await this.cloneWorktreeFromGolden(
+          projectPath,
+          settings.repository.goldenWorktreePath || projectPath,
+          worktreePath,
+          branchName
+        ));
+      if (!cloned) {
+        const { stdout, stderr } = await execFileAsync(
+          'git',
+          ['worktree', 'add', '-b', branchName, worktreePath],
+          { cwd: projectPath }
+        );
+
+        log.debug('Git worktree stdout:', stdout);
+        log.debug('Git worktree stderr:', stderr);
+      }
 
       // Do not treat localized/progress stderr as failure.
       // Many git commands emit progress to stderr; rely on exit code instead.
@@ -97,6 +200,18 @@ export class WorktreeService {
       // Ensure codex logs are ignored in this worktree
       this.ensureCodexLogIgnored(worktreePath);
 
+      // Bring over local, untracked config (.env etc.) so the project runs immediately
+      this.copyUntrackedFiles(
+        projectPath,
+        worktreePath,
+        options.copyFiles ?? settings?.repository?.copyFiles ?? []
+      );
+      this.linkDependencyCaches(
+        projectPath,
+        worktreePath,
+        settings?.repository?.dependencyCaches ?? []
+      );
+
       const worktreeInfo: WorktreeInfo = {
         id: worktreeId,
         name: workspaceName,
@@ -108,6 +223,7 @@ export class WorktreeService {
       };
 
       this.worktrees.set(worktreeInfo.id, worktreeInfo);
+      this.emit('worktree:created', { worktree: worktreeInfo });
 
       log.info(`Created worktree: ${workspaceName} -> ${branchName}`);
 
@@ -127,14 +243,55 @@ export class WorktreeService {
       return worktreeInfo;
     } catch (error) {
       log.error('Failed to create worktree:', error);
+      if (error instanceof AppError) throw error;
       throw new Error(`Failed to create worktree: ${error}`);
     }
   }
 
+  /**
+   * Create several worktrees at once with a bounded number of concurrent git operations.
+   * Results are returned in input order; one failure does not abort the others.
+   */
+  async createWorktrees(
+    projectPath: string,
+    workspaceNames: string[],
+    projectId: string,
+    options: { concurrency?: number; copyFiles?: string[] } = {}
+  ): Promise<BatchCreateResult[]> {
+    const results: BatchCreateResult[] = new Array(workspaceNames.length);
+    const limit = Math.max(1, Math.min(options.concurrency ?? 3, workspaceNames.length || 1));
+    let next = 0;
+
+    const worker = async () => {
+      while (next < workspaceNames.length) {
+        const index = next++;
+        const workspaceName = workspaceNames[index];
+        try {
+          const worktree = await this.createWorktree(projectPath, workspaceName, projectId, {
+            copyFiles: options.copyFiles,
+          });
+          results[index] = { workspaceName, success: true, worktree };
+        } catch (error) {
+          results[index] = {
+            workspaceName,
+            success: false,
+            error: error instanceof Error ? error.message : String(error),
+          };
+        }
+      }
+    };
+
+    await Promise.all(Array.from({ length: limit }, () => worker()));
+    return results;
+  }
+
   /**
    * List all worktrees for a project
    */
-  async listWorktrees(projectPath: string): Promise<WorktreeInfo[]> {
+  async listWorktrees(
+    projectPath: string,
+    options: { includeStatus?: boolean } = {}
+  ): Promise<WorktreeInfo[]> {
     try {
       const { stdout } = await execFileAsync('git', ['worktree', 'list'], {
         cwd: projectPath,
@@ -193,6 +350,14 @@ export class WorktreeService {
         }
       }
 
+      if (options.includeStatus && worktrees.length > 0) {
+        const baseBranch = await this.getDefaultBranch(projectPath);
+        const summaries = await Promise.all(
+          worktrees.map((wt) => this.getStatusSummary(wt.path, baseBranch))
+        );
+        return worktrees.map((wt, i) => ({ ...wt, summary: summaries[i] }));
+      }
+
       return worktrees;
     } catch (error) {
       log.error('Failed to list worktrees:', error);
@@ -200,18 +365,226 @@ export class WorktreeService {
     }
   }
 
+  /**
+   * Filtered, paginated listing across one or all projects. Results are ordered by path and
+   * page tokens are opaque cursors (the last path returned), so pages stay stable while
+   * worktrees are added or removed.
+   */
+  async queryWorktrees(query: WorktreeQuery = {}): Promise<WorktreePage> {
+    let sources: Array<{ id: string; path: string }>;
+    if (query.projectPath) {
+      let id = query.projectId;
+      if (!id) {
+        const projects = await databaseService.getProjects();
+        id = projects.find((p) => p.path === query.projectPath)?.id;
+      }
+      sources = [{ id: id ?? path.basename(query.projectPath), path: query.projectPath }];
+    } else {
+      const projects = await databaseService.getProjects();
+      sources = projects
+        .filter((p) => !query.projectId || p.id === query.projectId)
+        .map((p) => ({ id: p.id, path: p.path }));
+    }
+
+    const name = query.name?.trim().toLowerCase();
+    const branch = query.branch?.trim().toLowerCase();
+    const all: Array<{ wt: WorktreeInfo; projectPath: string }> = [];
+    for (const source of sources) {
+      const listed = await this.listWorktrees(source.path);
+      for (const wt of listed) {
+        if (name && !wt.name.toLowerCase().includes(name)) continue;
+        if (branch && !wt.branch.toLowerCase().includes(branch)) continue;
+        // Tracked worktrees already know their project; only untracked ones need filling in
+        const tracked = Array.from(this.worktrees.values()).some((t) => t.path === wt.path);
+        const projectId = tracked ? wt.projectId : source.id;
+        all.push({ wt: { ...wt, projectId }, projectPath: source.path });
+      }
+    }
+    all.sort((a, b) => a.wt.path.localeCompare(b.wt.path));
+
+    let start = 0;
+    if (query.pageToken) {
+      const cursor = Buffer.from(query.pageToken, 'base64url').toString('utf8');
+      start = all.findIndex((item) => item.wt.path.localeCompare(cursor) > 0);
+      if (start === -1) start = all.length;
+    }
+    const pageSize = query.pageSize && query.pageSize > 0 ? Math.floor(query.pageSize) : 0;
+    const page = pageSize ? all.slice(start, start + pageSize) : all.slice(start);
+    const hasMore = pageSize > 0 && start + pageSize < all.length;
+    const nextPageToken = hasMore
+      ? Buffer.from(page[page.length - 1].wt.path, 'utf8').toString('base64url')
+      : undefined;
+
+    let worktrees = page.map((item) => item.wt);
+    if (query.includeStatus && page.length > 0) {
+      const baseBranches = new Map<string, Promise<string>>();
+      const baseFor = (projectPath: string) => {
+        if (!baseBranches.has(projectPath)) {
+          baseBranches.set(projectPath, this.getDefaultBranch(projectPath));
+        }
+        return baseBranches.get(projectPath)!;
+      };
+      const summaries = await Promise.all(
+        page.map(async (item) =>
+          this.getStatusSummary(item.wt.path, await baseFor(item.projectPath))
+        )
+      );
+      worktrees = worktrees.map((wt, i) => ({ ...wt, summary: summaries[i] }));
+    }
+
+    return { worktrees, ...(nextPageToken ? { nextPageToken } : {}) };
+  }
+
+  /**
+   * Check every known worktree for uncommitted changes on a timer, so
+   * 'worktree:dirty-changed' fires without anyone polling status.
+   */
+  startDirtyWatch() {
+    if (this.dirtyTimer) return;
+    void this.checkDirtyStates();
+    this.dirtyTimer = setInterval(() => void this.checkDirtyStates(), DIRTY_CHECK_INTERVAL_MS);
+    this.dirtyTimer.unref?.();
+  }
+
+  stopDirtyWatch() {
+    if (this.dirtyTimer) clearInterval(this.dirtyTimer);
+    this.dirtyTimer = null;
+  }
+
+  private async checkDirtyStates() {
+    if (this.checkingDirty) return;
+    this.checkingDirty = true;
+    try {
+      const paths = new Set(Array.from(this.worktrees.values()).map((wt) => wt.path));
+      try {
+        for (const ws of await databaseService.getWorkspaces()) paths.add(ws.path);
+      } catch {}
+      for (const worktreePath of paths) {
+        if (!worktreePath || !fs.existsSync(worktreePath)) continue;
+        try {
+          const { stdout } = await execFileAsync('git', ['status', '--porcelain'], {
+            cwd: worktreePath,
+          });
+          const dirty = stdout.split('\n').some((l) => l.trim() && !l.endsWith('codex-stream.log'));
+          this.noteDirtyState(worktreePath, dirty);
+        } catch {}
+      }
+    } finally {
+      this.checkingDirty = false;
+    }
+  }
+
+  /** Emits 'worktree:dirty-changed' on the first observation of a worktree and on changes. */
+  private noteDirtyState(worktreePath: string, dirty: boolean) {
+    const key = path.resolve(worktreePath);
+    const previous = this.dirtyState.get(key);
+    this.dirtyState.set(key, dirty);
+    if (previous !== dirty) {
+      this.emit('worktree:dirty-changed', { path: worktreePath, dirty });
+    }
+  }
+
+  /**
+   * Dirty state, last commit subject and ahead/behind counts relative to the base branch.
+   */
+  private async getStatusSummary(
+    worktreePath: string,
+    baseBranch: string
+  ): Promise<WorktreeStatusSummary> {
+    const summary: WorktreeStatusSummary = { dirty: false, ahead: 0, behind: 0 };
+    const git = (args: string[]) => execFileAsync('git', args, { cwd: worktreePath });
+    const remote = await this.resolveBaseRemote(worktreePath, baseBranch);
+
+    const [status, subject, counts] = await Promise.allSettled([
+      git(['status', '--porcelain']),
+      git(['log', '-1', '--format=%s']),
+      git(['rev-list', '--left-right', '--count', `${remote}/${baseBranch}...HEAD`]).catch(() =>
+        git(['rev-list', '--left-right', '--count', `${baseBranch}...HEAD`])
+      ),
+    ]);
+
+    if (status.status === 'fulfilled') {
+      summary.dirty = status.value.stdout
+        .split('\n')
+        .some((l) => l.trim() && !l.endsWith('codex-stream.log'));
+      this.noteDirtyState(worktreePath, summary.dirty);
+    }
+    if (subject.status === 'fulfilled') {
+      summary.lastCommitSubject = subject.value.stdout.trim() || undefined;
+    }
+    if (counts.status === 'fulfilled') {
+      const [behind, ahead] = counts.value.stdout.trim().split(/\s+/);
+      summary.behind = parseInt(behind, 10) || 0;
+      summary.ahead = parseInt(ahead, 10) || 0;
+    }
+    return summary;
+  }
+
   /**
    * Render a branch name from a user-configurable template.
    * Supported placeholders: {slug}, {timestamp}
    */
   private renderBranchNameTemplate(
     template: string,
-    ctx: { slug: string; timestamp: string }
+    ctx: { slug: string; timestamp: string; user?: string; ticket?: string }
   ): string {
-    const replaced = template
-      .replace(/\{slug\}/g, ctx.slug)
-      .replace(/\{timestamp\}/g, ctx.timestamp);
-    return this.sanitizeBranchName(replaced);
+    return this.sanitizeBranchName(renderBranchTemplate(template, ctx));
+  }
+
+  /**
+   * Preview the branch a workspace would get, e.g. while editing the template in settings.
+   */
+  async previewBranchName(
+    projectPath: string,
+    workspaceName: string,
+    template?: string
+  ): Promise<{ branch: string; valid: boolean; error?: string }> {
+    const { getAppSettings } = await import('../settings');
+    const repo = getAppSettings()?.repository;
+    const tpl = template || repo?.branchTemplate || 'agent/{slug}-{timestamp}';
+    const slug = this.slugify(workspaceName, repo?.slugStrategy) || 'workspace';
+    try {
+      const branch = await this.resolveBranchName(projectPath, tpl, {
+        workspaceName,
+        slug,
+        timestamp: String(Date.now()),
+        slugStrategy: repo?.slugStrategy,
+      });
+      return { branch, valid: true };
+    } catch (error) {
+      const branch = this.renderBranchNameTemplate(tpl, { slug, timestamp: String(Date.now()) });
+      return { branch, valid: false, error: (error as Error).message };
+    }
+  }
+
+  /**
+   * Render the branch template for a workspace and validate the result with
+   * `git check-ref-format`, so a bad template fails loudly instead of creating odd refs.
+   */
+  async resolveBranchName(
+    projectPath: string,
+    template: string,
+    ctx: {
+      workspaceName: string;
+      slug: string;
+      timestamp: string;
+      ticket?: string;
+      slugStrategy?: string;
+    }
+  ): Promise<string> {
+    const ticketSource = ctx.ticket ?? extractTicket(ctx.workspaceName);
+    const branchName = this.renderBranchNameTemplate(template, {
+      slug: ctx.slug,
+      timestamp: ctx.timestamp,
+      user: template.includes('{user}')
+        ? await resolveBranchUser(projectPath, ctx.slugStrategy)
+        : undefined,
+      ticket: ticketSource ? this.slugify(ticketSource, ctx.slugStrategy) : undefined,
+    });
+    if (!(await isValidBranchName(branchName, projectPath))) {
+      throw new AppError('INVALID_BRANCH_NAME', { branch: branchName });
+    }
+    return branchName;
   }
 
   /**
@@ -221,7 +594,7 @@ export class WorktreeService {
     // Disallow illegal characters for Git refs, keep common allowed set including '/','-','_','.'
     let n = name
       .replace(/\s+/g, '-')
-      .replace(/[^A-Za-z0-9._\/-]+/g, '-')
+      .replace(/[^\p{L}\p{N}._\/-]+/gu, '-')
       .replace(/-+/g, '-')
       .replace(/\/+/g, '/');
     // No leading or trailing separators or dots
@@ -252,24 +625,36 @@ export class WorktreeService {
   }
 
   /**
-   * Remove a worktree
+   * Remove a worktree. `branchDeleted` reports whether its branch was actually deleted.
    */
   async removeWorktree(
     projectPath: string,
     worktreeId: string,
     worktreePath?: string,
-    branch?: string
-  ): Promise<void> {
+    branch?: string,
+    options: { deleteBranch?: boolean; force?: boolean } = {}
+  ): Promise<{ branchDeleted: boolean }> {
     try {
       let worktree = this.worktrees.get(worktreeId);
 
       let pathToRemove = worktree?.path ?? worktreePath;
-      let branchToDelete = worktree?.branch ?? branch;
+      const deleteBranch = options.deleteBranch ?? true;
+      const force = options.force ?? true;
+      let branchToDelete = deleteBranch ? (worktree?.branch ?? branch) : undefined;
 
       if (!pathToRemove) {
         throw new Error('Worktree path not provided');
       }
 
+      // Refuse to drop unmerged work unless explicitly forced; check before touching anything
+      if (branchToDelete && !force) {
+        const baseBranch = await this.getDefaultBranch(projectPath);
+        const merged = await this.isBranchMerged(projectPath, branchToDelete, baseBranch);
+        if (!merged) {
+          throw new AppError('BRANCH_NOT_MERGED', { branch: branchToDelete, base: baseBranch });
+        }
+      }
+
       // Remove the worktree directory via git first
       try {
         // Use --force to remove even when there are untracked/modified files
@@ -320,9 +705,16 @@ export class WorktreeService {
         }
       }
 
+      await scratchService.removeScratchDir(pathToRemove);
+
+      let branchDeleted = false;
       if (branchToDelete) {
-        const tryDeleteBranch = async () =>
+        // Merge status was checked against the base branch above; `branch -d` would instead
+        // judge it against whatever the main checkout has at HEAD
+        const tryDeleteBranch = async () => {
           await execFileAsync('git', ['branch', '-D', branchToDelete!], { cwd: projectPath });
+          branchDeleted = true;
+        };
         try {
           await tryDeleteBranch();
         } catch (branchError: any) {
@@ -348,12 +740,38 @@ export class WorktreeService {
       } else {
         log.info(`Removed worktree ${worktreeId}`);
       }
+      this.dirtyState.delete(path.resolve(pathToRemove));
+      this.emit('worktree:removed', {
+        worktreeId,
+        path: pathToRemove,
+        branch: worktree?.branch ?? branch,
+        branchDeleted,
+      });
+      return { branchDeleted };
     } catch (error) {
       log.error('Failed to remove worktree:', error);
+      if (error instanceof AppError) throw error;
       throw new Error(`Failed to remove worktree: ${error}`);
     }
   }
 
+  private async isBranchMerged(
+    projectPath: string,
+    branch: string,
+    baseBranch: string
+  ): Promise<boolean> {
+    const remote = await this.resolveBaseRemote(projectPath, baseBranch);
+    for (const base of [baseBranch, `${remote}/${baseBranch}`]) {
+      try {
+        await execFileAsync('git', ['merge-base', '--is-ancestor', branch, base], {
+          cwd: projectPath,
+        });
+        return true;
+      } catch {}
+    }
+    return false;
+  }
+
   /**
    * Get worktree status and changes
    */
@@ -392,8 +810,12 @@ export class WorktreeService {
         }
       }
 
+      const hasChanges =
+        stagedFiles.length > 0 || unstagedFiles.length > 0 || untrackedFiles.length > 0;
+      this.noteDirtyState(worktreePath, hasChanges);
+
       return {
-        hasChanges: stagedFiles.length > 0 || unstagedFiles.length > 0 || untrackedFiles.length > 0,
+        hasChanges,
         stagedFiles,
         unstagedFiles,
         untrackedFiles,
@@ -412,9 +834,9 @@ export class WorktreeService {
   /**
    * Get the default branch of a repository
    */
-  private async getDefaultBranch(projectPath: string): Promise<string> {
+  private async getDefaultBranch(projectPath: string, remote = 'origin'): Promise<string> {
     try {
-      const { stdout } = await execFileAsync('git', ['remote', 'show', 'origin'], {
+      const { stdout } = await execFileAsync('git', ['remote', 'show', remote], {
         cwd: projectPath,
       });
       const match = stdout.match(/HEAD branch:\s*(\S+)/);
@@ -424,6 +846,33 @@ export class WorktreeService {
     }
   }
 
+  /**
+   * Pick the remote that hosts the base branch. Honors the branch's configured upstream,
+   * then prefers an `upstream` remote (fork workflows) and finally falls back to `origin`.
+   */
+  private async resolveBaseRemote(cwd: string, baseBranch?: string): Promise<string> {
+    if (baseBranch) {
+      try {
+        const { stdout } = await execFileAsync(
+          'git',
+          ['config', '--get', `branch.${baseBranch}.remote`],
+          { cwd }
+        );
+        const configured = stdout.trim();
+        if (configured && configured !== '.') return configured;
+      } catch {}
+    }
+    try {
+      const { stdout } = await execFileAsync('git', ['remote'], { cwd });
+      const remotes = stdout.split('\n').map((r) => r.trim());
+      if (remotes.includes('upstream')) return 'upstream';
+      if (remotes.includes('origin')) return 'origin';
+      return remotes.find(Boolean) || 'origin';
+    } catch {
+      return 'origin';
+    }
+  }
+
   /**
    * Merge worktree changes back to main branch
    */
@@ -431,7 +880,7 @@ export class WorktreeService {
     try {
       const worktree = this.worktrees.get(worktreeId);
       if (!worktree) {
-        throw new Error('Worktree not found');
+        throw new AppError('WORKTREE_NOT_FOUND');
       }
 
       const defaultBranch = await this.getDefaultBranch(projectPath);
@@ -448,10 +897,468 @@ export class WorktreeService {
       log.info(`Merged worktree changes: ${worktree.name}`);
     } catch (error) {
       log.error('Failed to merge worktree changes:', error);
+      if (error instanceof AppError) throw error;
       throw new Error(`Failed to merge worktree changes: ${error}`);
     }
   }
 
+  /**
+   * Record a human approval of the workspace branch's current tip for the `approval` merge
+   * gate. Commits added afterwards need a new approval.
+   */
+  async approveMerge(
+    projectPath: string,
+    options: { worktreeId?: string; worktreePath?: string; branch?: string }
+  ): Promise<{ branch: string; commit: string }> {
+    const branch = await this.resolveWorktreeBranch(options);
+    if (!branch) {
+      throw new Error('Unable to resolve workspace branch to approve');
+    }
+    const commit = await mergeGateService.approve(projectPath, branch);
+    return { branch, commit };
+  }
+
+  /**
+   * Land a workspace branch into the project's base branch.
+   * The merge runs where the base branch is checked out (which must be clean); when it is
+   * not checked out anywhere, it runs in a temporary worktree and the branch ref is moved,
+   * so the user's checkout never switches branches.
+   * Conflicting merges are aborted and reported instead of left half-applied.
+   */
+  async mergeToBase(
+    projectPath: string,
+    options: {
+      worktreeId?: string;
+      worktreePath?: string;
+      branch?: string;
+      baseBranch?: string;
+      strategy?: MergeStrategy;
+      commitMessage?: string;
+    }
+  ): Promise<MergeToBaseResult> {
+    const strategy: MergeStrategy = options.strategy || 'merge';
+    if (!MERGE_STRATEGIES.includes(strategy)) {
+      throw new Error(`Unknown merge strategy: ${String(strategy)}`);
+    }
+    const branch = await this.resolveWorktreeBranch(options);
+    if (!branch) {
+      throw new Error('Unable to resolve workspace branch to merge');
+    }
+    const baseBranch = options.baseBranch || (await this.getDefaultBranch(projectPath));
+
+    const worktreePath =
+      options.worktreePath ??
+      (options.worktreeId ? this.worktrees.get(options.worktreeId)?.path : undefined);
+    const { stdout: branchSha } = await execFileAsync(
+      'git',
+      ['rev-parse', '--verify', '--end-of-options', `refs/heads/${branch}^{commit}`],
+      { cwd: projectPath }
+    );
+    const gateRun = await mergeGateService.evaluate(worktreePath, {
+      branch,
+      commit: branchSha.trim(),
+    });
+    if (!gateRun.passed) {
+      log.warn(`Merge of ${branch} blocked by pre-merge gates`);
+      return {
+        merged: false,
+        branch,
+        baseBranch,
+        strategy,
+        conflicts: [],
+        gates: gateRun.gates,
+      };
+    }
+
+    const checkout = await this.findBranchCheckout(projectPath, baseBranch);
+    let mergeDir: string;
+    let tempRoot: string | null = null;
+    let baseSha = '';
+    if (checkout) {
+      const { stdout: dirty } = await execFileAsync('git', ['status', '--porcelain'], {
+        cwd: checkout,
+      });
+      if (dirty.trim()) {
+        throw new AppError(
+          path.resolve(checkout) === path.resolve(projectPath) ? 'PROJECT_DIRTY' : 'WORKTREE_DIRTY'
+        );
+      }
+      mergeDir = checkout;
+    } else {
+      const { stdout } = await execFileAsync(
+        'git',
+        ['rev-parse', '--verify', `refs/heads/${baseBranch}^{commit}`],
+        { cwd: projectPath }
+      );
+      baseSha = stdout.trim();
+      tempRoot = fs.mkdtempSync(path.join(os.tmpdir(), 'emdash-merge-'));
+      mergeDir = path.join(tempRoot, 'worktree');
+      await execFileAsync('git', ['worktree', 'add', '--detach', mergeDir, baseSha], {
+        cwd: projectPath,
+      });
+    }
+
+    const result: MergeToBaseResult = {
+      merged: false,
+      branch,
+      baseBranch,
+      strategy,
+      conflicts: [],
+      gates: gateRun.gates,
+    };
+
+    let args: string[];
+    if (strategy === 'fast-forward') args = ['merge', '--ff-only', branch];
+    else if (strategy === 'squash') args = ['merge', '--squash', branch];
+    else args = ['merge', '--no-edit', branch];
+
+    try {
+      try {
+        const { stdout } = await execFileAsync('git', args, { cwd: mergeDir });
+        result.output = stdout.trim();
+      } catch (error: any) {
+        result.output = String(error?.stdout || '').trim();
+        result.conflicts = await this.listConflictedFiles(mergeDir);
+        if (result.conflicts.length === 0) {
+          throw new Error(String(error?.stderr || error?.message || error).trim());
+        }
+        try {
+          if (strategy === 'squash') {
+            await execFileAsync('git', ['reset', '--merge'], { cwd: mergeDir });
+          } else {
+            await execFileAsync('git', ['merge', '--abort'], { cwd: mergeDir });
+          }
+        } catch (abortErr) {
+          log.warn('Failed to abort conflicting merge:', abortErr);
+        }
+        log.warn(`Merge of ${branch} into ${baseBranch} has conflicts`, result.conflicts);
+        return result;
+      }
+
+      if (strategy === 'squash') {
+        const { stdout: staged } = await execFileAsync('git', ['diff', '--cached', '--name-only'], {
+          cwd: mergeDir,
+        });
+        if (staged.trim()) {
+          const message = options.commitMessage?.trim() || `Squash merge ${branch}`;
+          await execFileAsync('git', ['commit', '-m', message], { cwd: mergeDir });
+        }
+      }
+
+      if (tempRoot) {
+        // Move the base branch only if nobody else moved it meanwhile
+        const { stdout: merged } = await execFileAsync('git', ['rev-parse', 'HEAD'], {
+          cwd: mergeDir,
+        });
+        await execFileAsync(
+          'git',
+          ['update-ref', `refs/heads/${baseBranch}`, merged.trim(), baseSha],
+          { cwd: projectPath }
+        );
+      }
+    } finally {
+      if (tempRoot) {
+        try {
+          await execFileAsync('git', ['worktree', 'remove', '--force', mergeDir], {
+            cwd: projectPath,
+          });
+        } catch (cleanupErr) {
+          log.warn('Failed to remove temporary merge worktree:', cleanupErr);
+        }
+        fs.rmSync(tempRoot, { recursive: true, force: true });
+      }
+    }
+
+    result.merged = true;
+    log.info(`Merged ${branch} into ${baseBranch} (${strategy})`);
+    return result;
+  }
+
+  /** Path of the worktree (the main checkout included) that has `branch` checked out. */
+  private async findBranchCheckout(projectPath: string, branch: string): Promise<string | null> {
+    const { stdout } = await execFileAsync('git', ['worktree', 'list', '--porcelain'], {
+      cwd: projectPath,
+    });
+    let current: string | null = null;
+    for (const line of stdout.split('\n')) {
+      if (line.startsWith('worktree ')) current = line.slice('worktree '.length);
+      else if (line === `branch refs/heads/${branch}` && current) return current;
+    }
+    return null;
+  }
+
+  /**
+   * Fetch the base branch and bring the workspace branch up to date with it,
+   * either by rebasing onto it or by merging it in. Conflicts abort the operation.
+   */
+  async syncWorktree(
+    worktreePath: string,
+    options: { baseBranch?: string; strategy?: SyncStrategy; remote?: string } = {},
+    onProgress?: (progress: SyncProgress) => void
+  ): Promise<SyncWorktreeResult> {
+    const strategy: SyncStrategy = options.strategy || 'rebase';
+    const remote =
+      options.remote || (await this.resolveBaseRemote(worktreePath, options.baseBranch));
+    const baseBranch = options.baseBranch || (await this.getDefaultBranch(worktreePath, remote));
+    const upstream = `${remote}/${baseBranch}`;
+    const report = (progress: SyncProgress) => {
+      try {
+        onProgress?.(progress);
+      } catch {}
+    };
+
+    const { stdout: dirty } = await execFileAsync('git', ['status', '--porcelain'], {
+      cwd: worktreePath,
+    });
+    if (dirty.trim()) {
+      throw new AppError('WORKTREE_DIRTY');
+    }
+
+    report({ step: 'fetch', message: `Fetching ${upstream}` });
+    await this.runWithProgress(['fetch', '--progress', remote, baseBranch], worktreePath, (line) =>
+      report({ step: 'fetch', message: line })
+    );
+
+    const result: SyncWorktreeResult = { synced: false, strategy, upstream, conflicts: [] };
+    const args = strategy === 'rebase' ? ['rebase', upstream] : ['merge', '--no-edit', upstream];
+    const verb = strategy === 'rebase' ? 'Rebasing onto' : 'Merging';
+
+    report({ step: strategy, message: `${verb} ${upstream}` });
+    try {
+      const { stdout } = await execFileAsync('git', args, { cwd: worktreePath });
+      result.output = stdout.trim();
+    } catch (error: any) {
+      result.output = String(error?.stdout || '').trim();
+      result.conflicts = await this.listConflictedFiles(worktreePath);
+      if (result.conflicts.length === 0) {
+        throw new Error(String(error?.stderr || error?.message || error).trim());
+      }
+      try {
+        await execFileAsync('git', [strategy, '--abort'], { cwd: worktreePath });
+      } catch (abortErr) {
+        log.warn(`Failed to abort ${strategy} after conflicts:`, abortErr);
+      }
+      report({ step: 'done', message: `Conflicts in ${result.conflicts.length} file(s)` });
+      return result;
+    }
+
+    result.synced = true;
+    report({ step: 'done', message: `Up to date with ${upstream}` });
+    return result;
+  }
+
+  /**
+   * Build the default todo list (all picks, oldest first) for commits on the workspace
+   * branch that are not yet on the base branch. Merge commits are left out, matching how
+   * `rebase -i` linearizes history.
+   */
+  async getRebasePlan(worktreePath: string, base?: string): Promise<RebasePlan> {
+    let baseRef = base;
+    if (!baseRef) {
+      const remote = await this.resolveBaseRemote(worktreePath);
+      const baseBranch = await this.getDefaultBranch(worktreePath, remote);
+      const { stdout } = await execFileAsync(
+        'git',
+        ['merge-base', 'HEAD', `${remote}/${baseBranch}`],
+        { cwd: worktreePath }
+      );
+      baseRef = stdout.trim();
+    }
+    const { stdout } = await execFileAsync(
+      'git',
+      ['log', '--reverse', '--no-merges', '--format=%H%x09%s', `${baseRef}..HEAD`],
+      { cwd: worktreePath }
+    );
+    const entries: RebasePlanEntry[] = stdout
+      .split('\n')
+      .filter(Boolean)
+      .map((line) => {
+        const [sha, ...rest] = line.split('\t');
+        return { action: 'pick', sha, subject: rest.join('\t') };
+      });
+    return { base: baseRef, entries };
+  }
+
+  /**
+   * Run an edited rebase plan non-interactively. The todo list is injected through
+   * GIT_SEQUENCE_EDITOR and replacement messages are applied with `exec git commit --amend`.
+   * On conflicts the rebase is aborted and the conflicting files are reported.
+   */
+  async executeRebasePlan(
+    worktreePath: string,
+    plan: RebasePlan
+  ): Promise<{ success: boolean; conflicts: string[]; output?: string }> {
+    const validActions: RebaseAction[] = ['pick', 'reword', 'squash', 'fixup', 'drop'];
+    if (!plan?.base || !Array.isArray(plan.entries) || plan.entries.length === 0) {
+      throw new AppError('INVALID_REBASE_PLAN', {
+        detail: 'a base and at least one entry are required',
+      });
+    }
+    if (plan.entries[0].action === 'squash' || plan.entries[0].action === 'fixup') {
+      throw new AppError('INVALID_REBASE_PLAN', {
+        detail: 'the first commit cannot be squashed or fixed up',
+      });
+    }
+    const baseSha = await this.verifyRebaseBase(worktreePath, plan.base);
+    await this.checkRebasePlanCovers(worktreePath, baseSha, plan.entries);
+
+    const tmpDir = await fs.promises.mkdtemp(path.join(os.tmpdir(), 'emdash-rebase-'));
+    try {
+      const todo: string[] = [];
+      plan.entries.forEach((entry, i) => {
+        if (!validActions.includes(entry.action)) {
+          throw new AppError('INVALID_REBASE_PLAN', { detail: `unknown action ${entry.action}` });
+        }
+        if (!/^[0-9a-f]{7,40}$/i.test(entry.sha)) {
+          throw new AppError('INVALID_REBASE_PLAN', { detail: `invalid commit ${entry.sha}` });
+        }
+        // reword is expressed as pick + amend so no editor is needed
+        todo.push(`${entry.action === 'reword' ? 'pick' : entry.action} ${entry.sha}`);
+        const message = entry.message?.trim();
+        if (message && (entry.action === 'reword' || entry.action === 'squash')) {
+          const msgFile = path.join(tmpDir, `msg-${i}.txt`);
+          fs.writeFileSync(msgFile, message + '\n', 'utf8');
+          todo.push(`exec git commit --amend --only --allow-empty -F ${shellQuote(msgFile)}`);
+        }
+      });
+      const todoFile = path.join(tmpDir, 'git-rebase-todo');
+      fs.writeFileSync(todoFile, todo.join('\n') + '\n', 'utf8');
+
+      try {
+        const { stdout } = await execFileAsync('git', ['rebase', '-i', '--', baseSha], {
+          cwd: worktreePath,
+          env: {
+            ...process.env,
+            GIT_SEQUENCE_EDITOR: `cp ${shellQuote(todoFile)}`,
+            // Accept git's default combined message for squashes without a replacement
+            GIT_EDITOR: 'true',
+          },
+        });
+        return { success: true, conflicts: [], output: stdout.trim() };
+      } catch (error: any) {
+        const conflicts = await this.listConflictedFiles(worktreePath);
+        try {
+          await execFileAsync('git', ['rebase', '--abort'], { cwd: worktreePath });
+        } catch {}
+        if (conflicts.length === 0) {
+          throw new Error(String(error?.stderr || error?.message || error).trim());
+        }
+        return { success: false, conflicts, output: String(error?.stdout || '').trim() };
+      }
+    } finally {
+      await fs.promises.rm(tmpDir, { recursive: true, force: true }).catch(() => {});
+    }
+  }
+
+  /** Resolve a plan's base to a commit, refusing anything git could read as an option. */
+  private async verifyRebaseBase(worktreePath: string, base: string): Promise<string> {
+    if (typeof base !== 'string' || base.startsWith('-')) {
+      throw new AppError('INVALID_REBASE_PLAN', { detail: `invalid base ${base}` });
+    }
+    try {
+      const { stdout } = await execFileAsync(
+        'git',
+        ['rev-parse', '--verify', '--end-of-options', `${base}^{commit}`],
+        { cwd: worktreePath }
+      );
+      return stdout.trim();
+    } catch {
+      throw new AppError('INVALID_REBASE_PLAN', { detail: `unknown base ${base}` });
+    }
+  }
+
+  /**
+   * Every non-merge commit in `base..HEAD` must appear in the plan exactly once (dropping one
+   * takes an explicit `drop`), so a stale or partial plan cannot silently lose commits.
+   */
+  private async checkRebasePlanCovers(
+    worktreePath: string,
+    baseSha: string,
+    entries: RebasePlanEntry[]
+  ) {
+    const { stdout } = await execFileAsync('git', ['rev-list', '--no-merges', `${baseSha}..HEAD`], {
+      cwd: worktreePath,
+    });
+    const pending = new Set(stdout.split('\n').filter(Boolean));
+    const total = pending.size;
+    for (const entry of entries) {
+      const sha = String(entry?.sha ?? '').toLowerCase();
+      const matches = sha ? Array.from(pending).filter((c) => c.startsWith(sha)) : [];
+      if (matches.length !== 1) {
+        throw new AppError('INVALID_REBASE_PLAN', {
+          detail: `${entry?.sha} is not a commit left to rebase onto ${baseSha.slice(0, 7)}`,
+        });
+      }
+      pending.delete(matches[0]);
+    }
+    if (pending.size > 0) {
+      throw new AppError('INVALID_REBASE_PLAN', {
+        detail: `plan lists ${total - pending.size} of ${total} commits since the base`,
+      });
+    }
+  }
+
+  private runWithProgress(
+    args: string[],
+    cwd: string,
+    onLine: (line: string) => void
+  ): Promise<void> {
+    return new Promise((resolve, reject) => {
+      const child = spawn('git', args, { cwd });
+      let stderr = '';
+      const handle = (buf: Buffer) => {
+        const text = buf.toString();
+        stderr += text;
+        for (const line of text.split(/[\r\n]+/)) {
+          if (line.trim()) onLine(line.trim());
+        }
+      };
+      child.stdout.on('data', handle);
+      child.stderr.on('data', handle);
+      child.on('error', reject);
+      child.on('close', (code) => {
+        if (code === 0) resolve();
+        else reject(new Error(stderr.trim() || `git ${args[0]} exited with code ${code}`));
+      });
+    });
+  }
+
+  private async resolveWorktreeBranch(options: {
+    worktreeId?: string;
+    worktreePath?: string;
+    branch?: string;
+  }): Promise<string | null> {
+    if (options.branch) return options.branch;
+    const tracked = options.worktreeId ? this.worktrees.get(options.worktreeId) : undefined;
+    if (tracked) return tracked.branch;
+    if (options.worktreePath) {
+      try {
+        const { stdout } = await execFileAsync('git', ['rev-parse', '--abbrev-ref', 'HEAD'], {
+          cwd: options.worktreePath,
+        });
+        const b = stdout.trim();
+        return b && b !== 'HEAD' ? b : null;
+      } catch {
+        return null;
+      }
+    }
+    return null;
+  }
+
+  private async listConflictedFiles(cwd: string): Promise<string[]> {
+    try {
+      const { stdout } = await execFileAsync('git', ['diff', '--name-only', '--diff-filter=U'], {
+        cwd,
+      });
+      return stdout
+        .split('\n')
+        .map((l) => l.trim())
+        .filter(Boolean);
+    } catch {
+      return [];
+    }
+  }
+
   /**
    * Get worktree by ID
    */
@@ -466,7 +1373,105 @@ export class WorktreeService {
     return Array.from(this.worktrees.values());
   }
 
+  /**
+   * Copy files that git does not track (e.g. .env, .envrc) from the source checkout.
+   * Paths are relative to the project root; existing files in the worktree are left alone.
+   */
+  private copyUntrackedFiles(projectPath: string, worktreePath: string, files: string[]): string[] {
+    const copied: string[] = [];
+    const root = path.resolve(projectPath);
+    for (const rel of files) {
+      try {
+        const src = path.resolve(root, rel);
+        if (!src.startsWith(root + path.sep)) continue;
+        const dest = path.join(worktreePath, path.relative(root, src));
+        if (!fs.existsSync(src) || fs.existsSync(dest)) continue;
+        fs.mkdirSync(path.dirname(dest), { recursive: true });
+        fs.cpSync(src, dest, { recursive: true });
+        copied.push(rel);
+      } catch (err) {
+        log.warn(`Failed to copy ${rel} into worktree:`, err);
+      }
+    }
+    if (copied.length) log.info(`Copied untracked files into worktree: ${copied.join(', ')}`);
+    return copied;
+  }
+
+  /**
+   * Fast path for large projects: register the worktree without a checkout, clone the golden
+   * checkout's files (including ignored ones such as node_modules) copy-on-write, then reset
+   * tracked files to HEAD and drop untracked, non-ignored leftovers. Returns false (after
+   * cleaning up) when the filesystem cannot clone or anything fails, so the caller can fall
+   * back to a regular checkout.
+   */
+  private async cloneWorktreeFromGolden(
+    projectPath: string,
+    goldenPath: string,
+    worktreePath: string,
+    branchName: string
+  ): Promise<boolean> {
+    const worktreesDir = path.dirname(worktreePath);
+    if (!fs.existsSync(goldenPath) || !(await supportsCopyOnWrite(goldenPath, worktreesDir))) {
+      log.info('Copy-on-write clone unavailable; using regular worktree checkout');
+      return false;
+    }
+
+    const started = Date.now();
+    let registered = false;
+    try {
+      await execFileAsync(
+        'git',
+        ['worktree', 'add', '--no-checkout', '-b', branchName, worktreePath],
+        { cwd: projectPath }
+      );
+      registered = true;
+      const files = await cloneTree(goldenPath, worktreePath, { exclude: ['.git'] });
+      await execFileAsync('git', ['reset', '--hard', '-q', 'HEAD'], { cwd: worktreePath });
+      await execFileAsync('git', ['clean', '-fdq'], { cwd: worktreePath });
+      log.info(
+        `Cloned ${files} files copy-on-write from ${goldenPath} in ${Date.now() - started}ms`
+      );
+      return true;
+    } catch (error) {
+      log.warn('Copy-on-write worktree clone failed; falling back to checkout', error);
+      if (registered) {
+        try {
+          await execFileAsync('git', ['worktree', 'remove', '--force', worktreePath], {
+            cwd: projectPath,
+          });
+        } catch {}
+        try {
+          await execFileAsync('git', ['branch', '-D', branchName], { cwd: projectPath });
+        } catch {}
+      }
+      await fs.promises.rm(worktreePath, { recursive: true, force: true }).catch(() => {});
+      return false;
+    }
+  }
+
+  /**
+   * Symlink the project's shared dependency caches into the worktree and keep the
+   * links out of git status (a `node_modules/` ignore rule does not match a symlink).
+   */
+  private linkDependencyCaches(
+    projectPath: string,
+    worktreePath: string,
+    caches: DependencyCacheConfig[]
+  ) {
+    const linked = dependencyCacheService.linkIntoWorktree(projectPath, worktreePath, caches);
+    for (const rel of linked) {
+      this.addGitExclude(worktreePath, '/' + rel.split(path.sep).join('/'));
+    }
+    if (linked.length > 0) {
+      log.info(`Linked dependency caches into ${worktreePath}: ${linked.join(', ')}`);
+    }
+  }
+
   private ensureCodexLogIgnored(worktreePath: string) {
+    this.addGitExclude(worktreePath, 'codex-stream.log');
+  }
+
+  private addGitExclude(worktreePath: string, pattern: string) {
     try {
       const gitMeta = path.join(worktreePath, '.git');
       let gitDir = gitMeta;
@@ -487,22 +1492,61 @@ export class WorktreeService {
         try {
           current = fs.readFileSync(excludePath, 'utf8');
         } catch {}
-        if (!current.includes('codex-stream.log')) {
+        if (!current.split(/\r?\n/).includes(pattern)) {
           fs.appendFileSync(
             excludePath,
-            (current.endsWith('\n') || current === '' ? '' : '\n') + 'codex-stream.log\n'
+            (current.endsWith('\n') || current === '' ? '' : '\n') + pattern + '\n'
           );
         }
       } catch {}
     } catch {}
   }
 
+  /**
+   * Check out an existing local branch, or create a tracking branch from the remote
+   * that has it (explicit remote first, then the detected base remote, then origin).
+   */
+  private async worktreeAddArgsForBranch(
+    projectPath: string,
+    worktreePath: string,
+    branchName: string,
+    remote?: string
+  ): Promise<string[]> {
+    const refExists = async (ref: string) => {
+      try {
+        await execFileAsync('git', ['rev-parse', '--verify', '--quiet', ref], {
+          cwd: projectPath,
+        });
+        return true;
+      } catch {
+        return false;
+      }
+    };
+
+    if (!remote && (await refExists(`refs/heads/${branchName}`))) {
+      return ['worktree', 'add', worktreePath, branchName];
+    }
+
+    const candidates = remote
+      ? [remote]
+      : Array.from(new Set([await this.resolveBaseRemote(projectPath), 'origin']));
+    for (const r of candidates) {
+      if (await refExists(`refs/remotes/${r}/${branchName}`)) {
+        if (await refExists(`refs/heads/${branchName}`)) {
+          return ['worktree', 'add', worktreePath, branchName];
+        }
+        return ['worktree', 'add', '--track', '-b', branchName, worktreePath, `${r}/${branchName}`];
+      }
+    }
+    return ['worktree', 'add', worktreePath, branchName];
+  }
+
   async createWorktreeFromBranch(
     projectPath: string,
     workspaceName: string,
     branchName: string,
     projectId: string,
-    options?: { worktreePath?: string }
+    options?: { worktreePath?: string; copyFiles?: string[]; remote?: string }
   ): Promise<WorktreeInfo> {
     const normalizedName = workspaceName || branchName.replace(/\//g, '-');
     const sluggedName = this.slugify(normalizedName) || 'workspace';
@@ -512,7 +1556,7 @@ export class WorktreeService {
     const worktreePath = path.resolve(targetPath);
 
     if (fs.existsSync(worktreePath)) {
-      throw new Error(`Worktree directory already exists: ${worktreePath}`);
+      throw new AppError('WORKTREE_EXISTS', { path: worktreePath });
     }
 
     const worktreesDir = path.dirname(worktreePath);
@@ -521,9 +1565,13 @@ export class WorktreeService {
     }
 
     try {
-      await execFileAsync('git', ['worktree', 'add', worktreePath, branchName], {
-        cwd: projectPath,
-      });
+      const addArgs = await this.worktreeAddArgsForBranch(
+        projectPath,
+        worktreePath,
+        branchName,
+        options?.remote
+      );
+      await execFileAsync('git', addArgs, { cwd: projectPath });
     } catch (error) {
       throw new Error(
         `Failed to create worktree for branch ${branchName}: ${error instanceof Error ? error.message : String(error)}`
@@ -536,6 +1584,17 @@ export class WorktreeService {
 
     this.ensureCodexLogIgnored(worktreePath);
 
+    try {
+      const { getAppSettings } = await import('../settings');
+      const repoSettings = getAppSettings()?.repository;
+      this.copyUntrackedFiles(
+        projectPath,
+        worktreePath,
+        options?.copyFiles ?? repoSettings?.copyFiles ?? []
+      );
+      this.linkDependencyCaches(projectPath, worktreePath, repoSettings?.dependencyCaches ?? []);
+    } catch {}
+
     const worktreeInfo: WorktreeInfo = {
       id: this.stableIdFromPath(worktreePath),
       name: normalizedName,
@@ -547,6 +1606,7 @@ export class WorktreeService {
     };
 
     this.worktrees.set(worktreeInfo.id, worktreeInfo);
+    this.emit('worktree:created', { worktree: worktreeInfo });
 
     return worktreeInfo;
   }
@@ -1,6 +1,7 @@
 import { ipcMain } from 'electron';
 import { log } from '../lib/logger';
 import { codexService } from './CodexService';
+import type { KillOptions } from '../lib/processKill';
 
 export function setupCodexIpc() {
   // Check if Codex is installed
@@ -68,10 +69,10 @@ export function setupCodexIpc() {
     }
   });
 
-  ipcMain.handle('codex:stop-stream', async (event, workspaceId: string) => {
+  ipcMain.handle('codex:stop-stream', async (event, workspaceId: string, kill?: KillOptions) => {
     try {
       log.debug('[codex:stop-stream] request received', workspaceId);
-      const stopped = await codexService.stopMessageStream(workspaceId);
+      const stopped = await codexService.stopMessageStream(workspaceId, kill);
       log.debug('[codex:stop-stream] result', { workspaceId, stopped });
       return { success: stopped, stopped };
     } catch (error) {
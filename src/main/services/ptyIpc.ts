@@ -1,11 +1,410 @@
 import { ipcMain, WebContents } from 'electron';
-import { startPty, writePty, resizePty, killPty, getPty } from './ptyManager';
+import type { IPty } from 'node-pty';
+import {
+  startPty,
+  writePty,
+  resizePty,
+  killPty,
+  getPty,
+  signalPty,
+  getPtyStats,
+  listPtys,
+  recordDroppedChunk,
+  setPtyTitle,
+  ForwardableSignal,
+} from './ptyManager';
 import { log } from '../lib/logger';
 import { terminalSnapshotService } from './TerminalSnapshotService';
+import { scratchService } from './ScratchService';
+import { dependencyCacheService } from './DependencyCacheService';
+import { resolveEnvProfile } from './EnvProfiles';
+import { sendCritical } from './DeadLetterStore';
+import { AgentOutputBuffer } from './AgentOutputBuffer';
+import { eventLog } from './EventLog';
+import { getAppSettings } from '../settings';
+import { scanOscTitles } from '../lib/oscTitle';
+import { TokenBucket } from '../lib/tokenBucket';
+import { metricsRegistry } from '../lib/metrics';
+import type { KillOptions } from '../lib/processKill';
 import type { TerminalSnapshotPayload } from '../types/terminalSnapshot';
 
-const owners = new Map<string, WebContents>();
+// readOnly clients are observers: they receive output but cannot type, resize or kill.
+// paused clients have asked us to stop reading the PTY until they catch up; a paused
+// observer cannot stall the session for everyone, so its output is dropped instead.
+type PtyClient = {
+  wc: WebContents;
+  cols?: number;
+  rows?: number;
+  readOnly?: boolean;
+  paused?: boolean;
+};
+
+// Every renderer attached to a PTY receives its output; input from any of them is merged.
+// key: PTY id -> (webContents id -> client)
+const clients = new Map<string, Map<number, PtyClient>>();
+// Renderers that attached at least once; each is detached everywhere when it goes away
+const trackedClients = new Set<number>();
+// Optional explicit controller whose size wins over the smallest-client rule
+const controllers = new Map<string, number>();
 const listeners = new Set<string>();
+// Recent output per PTY, numbered by seq so a renderer can resume after a reload or blip
+const outputs = new Map<string, AgentOutputBuffer>();
+
+const MAX_LABELS = 32;
+
+// IPC transport health, exported through the metrics endpoint (agent:metrics)
+const metrics = {
+  clients: metricsRegistry.gauge('emdash_pty_clients', 'Renderers attached to PTYs.'),
+  messages: metricsRegistry.counter(
+    'emdash_pty_messages_total',
+    'PTY data messages, by direction (in = input, out = output).'
+  ),
+  bytes: metricsRegistry.counter('emdash_pty_bytes_total', 'PTY data bytes, by direction.'),
+  drops: metricsRegistry.counter(
+    'emdash_pty_drops_total',
+    'PTY messages dropped, by reason (no-client, input-limited, observer-paused).'
+  ),
+  startFailures: metricsRegistry.counter(
+    'emdash_pty_start_failures_total',
+    'Failed pty:start requests, by code.'
+  ),
+};
+
+function updateClientGauge() {
+  let total = 0;
+  for (const attached of clients.values()) total += attached.size;
+  metrics.clients.set({}, total);
+}
+
+function sanitizeLabels(input: unknown): Record<string, string> | null {
+  if (input === undefined || input === null) return {};
+  if (typeof input !== 'object' || Array.isArray(input)) return null;
+  const entries = Object.entries(input as Record<string, unknown>);
+  if (entries.length > MAX_LABELS) return null;
+  const out: Record<string, string> = {};
+  for (const [k, v] of entries) {
+    if (!k || typeof v !== 'string') return null;
+    out[k] = v;
+  }
+  return out;
+}
+
+function attachClient(
+  id: string,
+  wc: WebContents,
+  cols?: number,
+  rows?: number,
+  readOnly?: boolean
+) {
+  let attached = clients.get(id);
+  if (!attached) {
+    attached = new Map();
+    clients.set(id, attached);
+  }
+  if (!trackedClients.has(wc.id)) {
+    const wcId = wc.id;
+    trackedClients.add(wcId);
+    wc.once('destroyed', () => {
+      trackedClients.delete(wcId);
+      for (const ptyId of Array.from(clients.keys())) detachClient(ptyId, wcId);
+    });
+  }
+  const existing = attached.get(wc.id);
+  if (existing) {
+    // Re-attaching never lifts read-only (detach first) and keeps the client's pause state
+    existing.cols = cols ?? existing.cols;
+    existing.rows = rows ?? existing.rows;
+    if (readOnly) existing.readOnly = true;
+  } else {
+    attached.set(wc.id, { wc, cols, rows, readOnly: !!readOnly });
+  }
+  updateClientGauge();
+}
+
+function isObserver(id: string, wcId: number): boolean {
+  return !!clients.get(id)?.get(wcId)?.readOnly;
+}
+
+function detachClient(id: string, wcId: number) {
+  const attached = clients.get(id);
+  if (!attached?.delete(wcId)) return;
+  updateClientGauge();
+  if (controllers.get(id) === wcId) controllers.delete(id);
+  applyEffectiveSize(id);
+  applyFlowControl(id);
+}
+
+/**
+ * Stop reading from the PTY master while any attached client is saturated, so output
+ * backs up in the kernel (throttling the program) instead of being dropped.
+ */
+function applyFlowControl(id: string) {
+  const proc = getPty(id);
+  if (!proc) return;
+  const attached = clients.get(id);
+  const paused = !!attached && Array.from(attached.values()).some((c) => c.paused && !c.readOnly);
+  if (paused || throttles.get(id)?.timer) proc.pause();
+  else proc.resume();
+}
+
+// Token bucket per rate-limited PTY: refills at `rate` bytes/sec up to one second's worth
+type Throttle = { rate: number; tokens: number; last: number; timer?: NodeJS.Timeout };
+const throttles = new Map<string, Throttle>();
+
+function setupThrottle(id: string, override?: number) {
+  const rate = Math.floor(Number(override ?? getAppSettings().terminal.maxOutputBytesPerSec));
+  if (Number.isFinite(rate) && rate > 0) {
+    throttles.set(id, { rate, tokens: rate, last: Date.now() });
+  }
+}
+
+/**
+ * Charge output against the PTY's budget. Once it is overdrawn we stop reading the master
+ * until the deficit has refilled, so a runaway process is slowed down rather than flooding
+ * every renderer; larger bursts back off proportionally longer.
+ */
+function chargeOutput(id: string, bytes: number) {
+  const t = throttles.get(id);
+  if (!t) return;
+  const now = Date.now();
+  t.tokens = Math.min(t.rate, t.tokens + ((now - t.last) / 1000) * t.rate) - bytes;
+  t.last = now;
+  if (t.tokens >= 0 || t.timer) return;
+  const waitMs = Math.ceil((-t.tokens / t.rate) * 1000);
+  t.timer = setTimeout(() => {
+    t.timer = undefined;
+    applyFlowControl(id);
+  }, waitMs);
+  applyFlowControl(id);
+}
+
+function clearThrottle(id: string) {
+  const t = throttles.get(id);
+  if (t?.timer) clearTimeout(t.timer);
+  throttles.delete(id);
+}
+
+// Inbound input limits per renderer, across every PTY it writes to (settings.terminal)
+type InputLimit = { messages: TokenBucket | null; bytes: TokenBucket | null; notifiedAt: number };
+const inputLimits = new Map<number, InputLimit>();
+const INPUT_LIMIT_NOTICE_MS = 1000;
+
+/**
+ * Charge a pty:input message against the sending renderer's budget. Over-limit input is
+ * dropped and the renderer is told (at most once a second) via pty:input-limited, so a
+ * misbehaving renderer cannot flood the PTYs with writes.
+ */
+function allowInput(wc: WebContents, id: string, data: string): boolean {
+  const { maxInputMessagesPerSec, maxInputBytesPerSec } = getAppSettings().terminal;
+  if (!maxInputMessagesPerSec && !maxInputBytesPerSec) return true;
+  let limit = inputLimits.get(wc.id);
+  if (!limit) {
+    limit = {
+      messages: maxInputMessagesPerSec ? new TokenBucket(maxInputMessagesPerSec) : null,
+      bytes: maxInputBytesPerSec ? new TokenBucket(maxInputBytesPerSec) : null,
+      notifiedAt: 0,
+    };
+    inputLimits.set(wc.id, limit);
+    wc.once('destroyed', () => inputLimits.delete(wc.id));
+  }
+  const now = Date.now();
+  let reason: 'messages' | 'bytes' | null = null;
+  if (limit.messages && !limit.messages.take(1, now)) reason = 'messages';
+  else if (limit.bytes && !limit.bytes.take(Buffer.byteLength(data ?? ''), now)) reason = 'bytes';
+  if (!reason) return true;
+  metrics.drops.inc({ reason: 'input-limited' });
+  if (now - limit.notifiedAt >= INPUT_LIMIT_NOTICE_MS) {
+    limit.notifiedAt = now;
+    log.warn('pty:input rate limited', { id, sender: wc.id, reason });
+    try {
+      wc.send('pty:input-limited', { id, code: 'rate-limited', reason });
+    } catch {}
+  }
+  return false;
+}
+
+function clearClients(id: string) {
+  clients.delete(id);
+  controllers.delete(id);
+  outputs.delete(id);
+  updateClientGauge();
+}
+
+/**
+ * Re-send buffered output with seq >= fromSeq to one renderer, ahead of live data. Returns
+ * true when part of that range was already evicted and the renderer should redraw instead.
+ */
+function replayOutput(id: string, wc: WebContents, fromSeq: number): boolean {
+  const buffer = outputs.get(id);
+  if (!buffer) return fromSeq > 0;
+  const page = buffer.read(fromSeq, Number.MAX_SAFE_INTEGER);
+  for (const chunk of page.chunks) wc.send(`pty:data:${id}`, chunk.text, chunk.seq);
+  return fromSeq < page.firstSeq;
+}
+
+/**
+ * Shared sessions follow the explicit controller's size when one is set, otherwise the
+ * smallest attached client so output fits every viewer.
+ */
+function applyEffectiveSize(id: string) {
+  const attached = clients.get(id);
+  if (!attached || attached.size === 0) return;
+  const controller = controllers.get(id);
+  const sized = Array.from(attached.values()).filter((c) => !c.readOnly && c.cols && c.rows);
+  if (sized.length === 0) return;
+  const lead = controller !== undefined ? attached.get(controller) : undefined;
+  if (lead?.cols && lead.rows) {
+    resizePty(id, lead.cols, lead.rows);
+    return;
+  }
+  const cols = Math.min(...sized.map((c) => c.cols!));
+  const rows = Math.min(...sized.map((c) => c.rows!));
+  resizePty(id, cols, rows);
+}
+
+type StartOptions = Parameters<typeof startPty>[0];
+
+// PTYs started with restartOnExit: how to respawn them and crash-loop bookkeeping
+const restartSpecs = new Map<
+  string,
+  { options: StartOptions; startedAt: number; restarts: number; fastFailures: number }
+>();
+// A shell that dies sooner than this after (re)starting counts towards the crash-loop limit
+const RESTART_MIN_UPTIME_MS = 2000;
+const MAX_FAST_FAILURES = 5;
+
+// Stable codes for failed pty:* requests, so renderers can react without parsing messages
+export type PtyErrorCode =
+  | 'invalid-request'
+  | 'session-not-found'
+  | 'spawn-failed'
+  | 'not-attached'
+  | 'read-only';
+// Why a session ended, sent as `reason` with pty:exit
+export type PtyCloseReason = 'exited' | 'killed' | 'crash-loop' | 'restart-failed';
+
+function ptyError(code: PtyErrorCode, error: string) {
+  return { ok: false, code, error };
+}
+
+function startFailed(code: PtyErrorCode, error: string) {
+  metrics.startFailures.inc({ code });
+  return ptyError(code, error);
+}
+
+// Renderers still attached when another one killed the PTY; they are told on exit
+const killedClients = new Map<string, PtyClient[]>();
+
+function notifyExit(
+  id: string,
+  exitCode: number,
+  signal: number | undefined,
+  reason: PtyCloseReason
+) {
+  const killedBy = killedClients.get(id);
+  killedClients.delete(id);
+  // The renderer that killed the PTY already knows; only the others are told
+  const attached = killedBy ?? Array.from(clients.get(id)?.values() ?? []);
+  const payload = { exitCode, signal, reason: killedBy ? 'killed' : reason };
+  if (attached.length === 0 && !killedBy) {
+    sendCritical(undefined, `pty:exit:${id}`, payload);
+  }
+  for (const client of attached) {
+    sendCritical(client.wc, `pty:exit:${id}`, payload);
+  }
+}
+
+/**
+ * Respawn a restartOnExit PTY under the same id, cwd and env. Returns why the session
+ * should be torn down instead (no restart spec, crash loop or spawn failure), or
+ * 'restarted'.
+ */
+function respawn(
+  id: string,
+  proc: IPty,
+  exitCode: number,
+  signal?: number
+): PtyCloseReason | 'restarted' {
+  const spec = restartSpecs.get(id);
+  if (!spec) return 'exited';
+  const uptime = Date.now() - spec.startedAt;
+  spec.fastFailures = uptime < RESTART_MIN_UPTIME_MS ? spec.fastFailures + 1 : 0;
+  if (spec.fastFailures >= MAX_FAST_FAILURES) {
+    log.warn('pty:restart giving up after repeated fast exits', { id, restarts: spec.restarts });
+    restartSpecs.delete(id);
+    return 'crash-loop';
+  }
+  try {
+    const next = startPty({ ...spec.options, cols: proc.cols, rows: proc.rows });
+    spec.startedAt = Date.now();
+    spec.restarts += 1;
+    wirePty(id, next, spec.options.cwd);
+  } catch (error: any) {
+    log.error('pty:restart failed', { id, error: error?.message || error });
+    restartSpecs.delete(id);
+    return 'restart-failed';
+  }
+  for (const client of clients.get(id)?.values() ?? []) {
+    if (!client.wc.isDestroyed()) {
+      client.wc.send(`pty:restarted:${id}`, { exitCode, signal, restarts: spec.restarts });
+    }
+  }
+  return 'restarted';
+}
+
+function wirePty(id: string, proc: IPty, cwd?: string) {
+  let titlePending = '';
+  proc.onData((data) => {
+    const scan = scanOscTitles(titlePending, data);
+    titlePending = scan.pending;
+    if (scan.titles.length > 0) {
+      const title = scan.titles[scan.titles.length - 1];
+      setPtyTitle(id, title);
+      for (const client of clients.get(id)?.values() ?? []) {
+        if (!client.wc.isDestroyed()) client.wc.send(`pty:title:${id}`, title);
+      }
+    }
+    const seq = outputs.get(id)?.append(data);
+    let delivered = false;
+    for (const client of clients.get(id)?.values() ?? []) {
+      if (client.wc.isDestroyed()) continue;
+      if (client.paused && client.readOnly) {
+        // The observer catches up from `seq` after resuming
+        metrics.drops.inc({ reason: 'observer-paused' });
+        continue;
+      }
+      client.wc.send(`pty:data:${id}`, data, seq);
+      metrics.messages.inc({ direction: 'out' });
+      metrics.bytes.inc({ direction: 'out' }, Buffer.byteLength(data));
+      delivered = true;
+    }
+    if (!delivered) {
+      recordDroppedChunk(id);
+      metrics.drops.inc({ reason: 'no-client' });
+    }
+    chargeOutput(id, Buffer.byteLength(data));
+  });
+
+  proc.onExit(({ exitCode, signal }) => {
+    if (exitCode !== 0 || signal) {
+      eventLog.record({
+        kind: 'session-exited',
+        severity: 'warn',
+        message: signal
+          ? `Terminal exited with code ${exitCode} (signal ${signal})`
+          : `Terminal exited with code ${exitCode}`,
+        scope: { ptyId: id, worktreePath: cwd },
+        detail: { exitCode, signal, restarting: restartSpecs.has(id) },
+      });
+    }
+    const outcome = respawn(id, proc, exitCode, signal);
+    if (outcome === 'restarted') return;
+    notifyExit(id, exitCode, signal, outcome);
+    clearClients(id);
+    clearThrottle(id);
+    listeners.delete(id);
+  });
+}
 
 export function registerPtyIpc(): void {
   ipcMain.handle(
@@ -16,42 +415,96 @@ export function registerPtyIpc(): void {
         id: string;
         cwd?: string;
         shell?: string;
+        command?: string;
+        args?: string[];
         env?: Record<string, string>;
         cols?: number;
         rows?: number;
+        readOnly?: boolean;
+        envProfile?: string;
+        labels?: Record<string, string>;
+        // Respawn the shell (same id, cwd and env) when it exits instead of ending the session
+        restartOnExit?: boolean;
+        // Output cap in bytes/sec; overrides settings.terminal.maxOutputBytesPerSec, 0 disables
+        maxOutputBytesPerSec?: number;
+        // Last seq + 1 this renderer received; buffered output from there is replayed on reuse
+        resumeFrom?: number;
       }
     ) => {
       try {
-        const { id, cwd, shell, env, cols, rows } = args;
+        const { id, cwd, shell, command, env, cols, rows, readOnly } = args;
+        if (args.args && !Array.isArray(args.args)) {
+          return startFailed('invalid-request', 'args must be an array of strings');
+        }
+        const commandArgs = args.args?.map((a) => String(a));
+        const labels = sanitizeLabels(args.labels);
+        if (!labels) {
+          return startFailed(
+            'invalid-request',
+            `labels must map up to ${MAX_LABELS} keys to strings`
+          );
+        }
+        let profileEnv: Record<string, string>;
+        try {
+          profileEnv = resolveEnvProfile(args.envProfile);
+        } catch (err: any) {
+          return startFailed('invalid-request', String(err?.message || err));
+        }
         // Reuse existing PTY if present; otherwise create new
         const existing = getPty(id);
-        const proc = existing ?? startPty({ id, cwd, shell, env, cols, rows });
+        if (readOnly && !existing) {
+          return startFailed('session-not-found', 'Cannot observe a PTY that is not running');
+        }
+        const options: StartOptions = {
+          id,
+          cwd,
+          shell,
+          command,
+          args: commandArgs,
+          env: {
+            ...dependencyCacheService.envFor(cwd),
+            ...scratchService.envFor(cwd),
+            ...profileEnv,
+            ...(env || {}),
+          },
+          cols,
+          rows,
+          labels,
+        };
+        const proc = existing ?? startPty(options);
+        if (!existing) {
+          setupThrottle(id, args.maxOutputBytesPerSec);
+          outputs.set(id, new AgentOutputBuffer());
+        }
+        if (!existing && args.restartOnExit) {
+          restartSpecs.set(id, { options, startedAt: Date.now(), restarts: 0, fastFailures: 0 });
+        }
         const envKeys = env ? Object.keys(env) : [];
         const planEnv = env && (env.EMDASH_PLAN_MODE || env.EMDASH_PLAN_FILE) ? true : false;
         log.debug('pty:start OK', {
           id,
           cwd,
           shell,
+          command,
           cols,
           rows,
           reused: !!existing,
+          envProfile: args.envProfile,
+          readOnly: !!readOnly,
           envKeys,
           planEnv,
         });
-        const wc = event.sender;
-        owners.set(id, wc);
+        attachClient(id, event.sender, cols, rows, readOnly);
+        if (existing) applyEffectiveSize(id);
+        const resumeFrom = Math.floor(Number(args.resumeFrom));
+        const truncated =
+          existing && Number.isFinite(resumeFrom) && resumeFrom >= 0
+            ? replayOutput(id, event.sender, resumeFrom)
+            : undefined;
 
         // Attach listeners once per PTY id
         if (!listeners.has(id)) {
-          proc.onData((data) => {
-            owners.get(id)?.send(`pty:data:${id}`, data);
-          });
-
-          proc.onExit(({ exitCode, signal }) => {
-            owners.get(id)?.send(`pty:exit:${id}`, { exitCode, signal });
-            owners.delete(id);
-            listeners.delete(id);
-          });
+          wirePty(id, proc, cwd);
           listeners.add(id);
         }
 
@@ -62,20 +515,34 @@ export function registerPtyIpc(): void {
           windows.forEach((w: any) => w.webContents.send('pty:started', { id }));
         } catch {}
 
-        return { ok: true };
+        return { ok: true, truncated };
       } catch (err: any) {
+        eventLog.record({
+          kind: 'session-failed',
+          severity: 'error',
+          message: `Terminal failed to start: ${err?.message || err}`,
+          scope: { ptyId: args.id, worktreePath: args.cwd },
+          detail: { shell: args.shell },
+        });
         log.error('pty:start FAIL', {
           id: args.id,
           cwd: args.cwd,
           shell: args.shell,
           error: err?.message || err,
         });
-        return { ok: false, error: String(err?.message || err) };
+        return startFailed('spawn-failed', String(err?.message || err));
       }
     }
   );
 
-  ipcMain.on('pty:input', (_event, args: { id: string; data: string }) => {
+  ipcMain.on('pty:input', (event, args: { id: string; data: string }) => {
+    if (isObserver(args.id, event.sender.id)) {
+      log.warn('pty:input rejected from read-only observer', { id: args.id });
+      return;
+    }
+    if (!allowInput(event.sender, args.id, args.data)) return;
+    metrics.messages.inc({ direction: 'in' });
+    metrics.bytes.inc({ direction: 'in' }, Buffer.byteLength(args.data ?? ''));
     try {
       writePty(args.id, args.data);
     } catch (e) {
@@ -83,24 +550,110 @@ export function registerPtyIpc(): void {
     }
   });
 
-  ipcMain.on('pty:resize', (_event, args: { id: string; cols: number; rows: number }) => {
+  ipcMain.on('pty:resize', (event, args: { id: string; cols: number; rows: number }) => {
     try {
-      resizePty(args.id, args.cols, args.rows);
+      const client = clients.get(args.id)?.get(event.sender.id);
+      if (client?.readOnly) return;
+      if (client) {
+        client.cols = args.cols;
+        client.rows = args.rows;
+        applyEffectiveSize(args.id);
+      } else {
+        resizePty(args.id, args.cols, args.rows);
+      }
     } catch (e) {
       log.error('pty:resize error', { id: args.id, cols: args.cols, rows: args.rows, error: e });
     }
   });
 
-  ipcMain.on('pty:kill', (_event, args: { id: string }) => {
+  ipcMain.on('pty:kill', (event, args: { id: string; kill?: KillOptions }) => {
+    if (isObserver(args.id, event.sender.id)) {
+      log.warn('pty:kill rejected from read-only observer', { id: args.id });
+      return;
+    }
     try {
-      killPty(args.id);
-      owners.delete(args.id);
+      // An explicit kill ends the session even when it was started with restartOnExit
+      restartSpecs.delete(args.id);
+      const others = Array.from(clients.get(args.id)?.values() ?? []).filter(
+        (c) => c.wc.id !== event.sender.id
+      );
+      if (getPty(args.id)) killedClients.set(args.id, others);
+      killPty(args.id, args.kill);
+      clearClients(args.id);
+      clearThrottle(args.id);
       listeners.delete(args.id);
     } catch (e) {
       log.error('pty:kill error', { id: args.id, error: e });
     }
   });
 
+  ipcMain.on('pty:pause', (event, args: { id: string }) => {
+    const client = clients.get(args.id)?.get(event.sender.id);
+    if (!client || client.paused) return;
+    client.paused = true;
+    applyFlowControl(args.id);
+  });
+
+  ipcMain.on('pty:resume', (event, args: { id: string }) => {
+    const client = clients.get(args.id)?.get(event.sender.id);
+    if (!client?.paused) return;
+    client.paused = false;
+    applyFlowControl(args.id);
+  });
+
+  // Stop receiving a shared PTY's output without killing it
+  ipcMain.on('pty:detach', (event, args: { id: string }) => {
+    detachClient(args.id, event.sender.id);
+  });
+
+  // Make the calling renderer (or nobody) the size controller of a shared PTY
+  ipcMain.handle('pty:set-controller', (event, args: { id: string; controller: boolean }) => {
+    if (!clients.get(args.id)?.has(event.sender.id)) {
+      return ptyError('not-attached', 'Not attached to this PTY');
+    }
+    if (isObserver(args.id, event.sender.id)) {
+      return ptyError('read-only', 'Read-only observers cannot control the PTY');
+    }
+    if (args.controller) controllers.set(args.id, event.sender.id);
+    else if (controllers.get(args.id) === event.sender.id) controllers.delete(args.id);
+    applyEffectiveSize(args.id);
+    return { ok: true };
+  });
+
+  ipcMain.handle('pty:signal', async (event, args: { id: string; signal: ForwardableSignal }) => {
+    if (isObserver(args.id, event.sender.id)) {
+      return ptyError('read-only', 'Read-only observers cannot signal the PTY');
+    }
+    if (!getPty(args.id)) {
+      return ptyError('session-not-found', `PTY not found: ${args.id}`);
+    }
+    try {
+      await signalPty(args.id, args.signal);
+      return { ok: true };
+    } catch (e: any) {
+      log.error('pty:signal error', { id: args.id, signal: args.signal, error: e });
+      // Unsupported signals throw while the PTY is still there; otherwise it exited meanwhile
+      const code = getPty(args.id) ? 'invalid-request' : 'session-not-found';
+      return ptyError(code, String(e?.message || e));
+    }
+  });
+
+  ipcMain.handle('pty:list', (_event, args?: { labels?: Record<string, string> }) => {
+    const labels = sanitizeLabels(args?.labels);
+    if (!labels) return ptyError('invalid-request', 'labels must map keys to strings');
+    return { ok: true, ptys: listPtys({ labels }) };
+  });
+
+  // Traffic counters per PTY (all PTYs when no id is given) for diagnosing slow consumers
+  ipcMain.handle('pty:stats', (_event, args?: { id?: string }) => {
+    const stats = getPtyStats(args?.id).map((s) => ({
+      ...s,
+      clients: clients.get(s.id)?.size ?? 0,
+      paused: Array.from(clients.get(s.id)?.values() ?? []).some((c) => c.paused && !c.readOnly),
+    }));
+    return { ok: true, stats };
+  });
+
   ipcMain.handle('pty:snapshot:get', async (_event, args: { id: string }) => {
     try {
       const snapshot = await terminalSnapshotService.getSnapshot(args.id);
@@ -123,6 +676,25 @@ export function registerPtyIpc(): void {
     }
   );
 
+  ipcMain.handle(
+    'pty:transcript:get',
+    async (_event, args: { id: string; format?: 'ansi' | 'plain' }) => {
+      try {
+        const transcript = await terminalSnapshotService.getTranscript(
+          args.id,
+          args.format === 'ansi' ? 'ansi' : 'plain'
+        );
+        if (!transcript) {
+          return ptyError('session-not-found', 'No transcript recorded for this terminal');
+        }
+        return { ok: true, ...transcript };
+      } catch (error: any) {
+        log.error('pty:transcript:get failed', { id: args.id, error });
+        return { ok: false, error: error?.message || String(error) };
+      }
+    }
+  );
+
   ipcMain.handle('pty:snapshot:clear', async (_event, args: { id: string }) => {
     await terminalSnapshotService.deleteSnapshot(args.id);
     return { ok: true };
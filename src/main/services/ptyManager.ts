@@ -1,17 +1,40 @@
+import { execFile } from 'child_process';
 import os from 'os';
+import { promisify } from 'util';
 // Important: only import node-pty types, not the runtime module, at load time.
 // Lazy-require the native module inside startPty to avoid app-start crashes
 // when the native binary is missing or incompatible on some systems.
 import type { IPty } from 'node-pty';
 import { log } from '../lib/logger';
+import { resolveKillOptions, terminateProcess, type KillOptions } from '../lib/processKill';
+
+const execFileAsync = promisify(execFile);
+
+export type PtyStats = {
+  bytesIn: number;
+  bytesOut: number;
+  framesIn: number;
+  framesOut: number;
+  // Output chunks that reached no renderer (none attached, or the window was gone)
+  droppedChunks: number;
+  startedAt: number;
+  exitedAt?: number;
+};
 
 type PtyRecord = {
   id: string;
   proc: IPty;
+  cwd: string;
+  labels: Record<string, string>; // e.g. { workspaceId: '…', purpose: 'agent' }
+  title?: string; // latest OSC 0/2 title set by the shell or program
+  stats: PtyStats;
 };
 
 const ptys = new Map<string, PtyRecord>();
 
+export const FORWARDABLE_SIGNALS = ['SIGINT', 'SIGTSTP', 'SIGQUIT', 'SIGHUP', 'SIGWINCH'] as const;
+export type ForwardableSignal = (typeof FORWARDABLE_SIGNALS)[number];
+
 function getDefaultShell(): string {
   if (process.platform === 'win32') {
     // Prefer ComSpec (usually cmd.exe) or fallback to PowerShell
@@ -24,11 +47,16 @@ export function startPty(options: {
   id: string;
   cwd?: string;
   shell?: string;
+  // Run this program directly instead of an interactive shell (e.g. 'npm' with ['test'])
+  command?: string;
+  args?: string[];
   env?: NodeJS.ProcessEnv;
   cols?: number;
   rows?: number;
+  labels?: Record<string, string>;
 }): IPty {
-  const { id, cwd, shell, env, cols = 80, rows = 24 } = options;
+  const { id, cwd, command, env, cols = 80, rows = 24 } = options;
+  const shell = command || options.shell;
 
   let useShell = shell || getDefaultShell();
   const useCwd = cwd || process.cwd() || os.homedir();
@@ -85,8 +113,8 @@ export function startPty(options: {
   const pty: typeof import('node-pty') = require('node-pty');
 
   // Provide sensible defaults for interactive shells so they render prompts
-  const args: string[] = [];
-  if (process.platform !== 'win32') {
+  const args: string[] = command ? [...(options.args ?? [])] : [];
+  if (!command && process.platform !== 'win32') {
     try {
       const base = String(useShell).split('/').pop() || '';
       if (base === 'zsh') args.push('-il');
@@ -105,7 +133,27 @@ export function startPty(options: {
     env: useEnv,
   });
 
-  const rec: PtyRecord = { id, proc };
+  const rec: PtyRecord = {
+    id,
+    proc,
+    cwd: useCwd,
+    labels: { ...(options.labels ?? {}) },
+    stats: {
+      bytesIn: 0,
+      bytesOut: 0,
+      framesIn: 0,
+      framesOut: 0,
+      droppedChunks: 0,
+      startedAt: Date.now(),
+    },
+  };
+  proc.onData((data) => {
+    rec.stats.bytesOut += Buffer.byteLength(data);
+    rec.stats.framesOut += 1;
+  });
+  proc.onExit(() => {
+    rec.stats.exitedAt = Date.now();
+  });
   ptys.set(id, rec);
   return proc;
 }
@@ -116,6 +164,8 @@ export function writePty(id: string, data: string): void {
     log.warn('ptyManager:writeMissing', { id, bytes: data.length });
     return;
   }
+  rec.stats.bytesIn += Buffer.byteLength(data);
+  rec.stats.framesIn += 1;
   rec.proc.write(data);
 }
 
@@ -143,18 +193,71 @@ export function resizePty(id: string, cols: number, rows: number): void {
   }
 }
 
-export function killPty(id: string): void {
+export function killPty(id: string, options?: KillOptions): void {
   const rec = ptys.get(id);
   if (!rec) {
     return;
   }
   try {
-    rec.proc.kill();
+    if (process.platform === 'win32') {
+      // node-pty on Windows does not accept signals
+      rec.proc.kill();
+    } else {
+      terminateProcess(
+        {
+          pid: rec.proc.pid,
+          kill: (signal) => rec.proc.kill(signal),
+          exited: () => rec.stats.exitedAt !== undefined,
+        },
+        resolveKillOptions('SIGHUP', options)
+      );
+    }
   } finally {
     ptys.delete(id);
   }
 }
 
+/**
+ * Deliver a signal to the PTY's foreground process group (the running command,
+ * not just the shell), falling back to the shell process itself.
+ */
+export async function signalPty(id: string, signal: ForwardableSignal): Promise<void> {
+  const rec = ptys.get(id);
+  if (!rec) {
+    throw new Error(`PTY not found: ${id}`);
+  }
+  if (!FORWARDABLE_SIGNALS.includes(signal)) {
+    throw new Error(`Unsupported signal: ${signal}`);
+  }
+  if (process.platform === 'win32') {
+    // No process groups on Windows; Ctrl+C is the only portable equivalent
+    if (signal !== 'SIGINT') throw new Error(`${signal} is not supported on Windows`);
+    rec.proc.write('\x03');
+    return;
+  }
+
+  const pgid = await getForegroundProcessGroup(rec.proc.pid);
+  if (pgid) {
+    try {
+      process.kill(-pgid, signal);
+      return;
+    } catch (error) {
+      log.warn('ptyManager:signalGroupFailed', { id, pgid, signal, error: String(error) });
+    }
+  }
+  rec.proc.kill(signal);
+}
+
+async function getForegroundProcessGroup(pid: number): Promise<number | null> {
+  try {
+    const { stdout } = await execFileAsync('ps', ['-o', 'tpgid=', '-p', String(pid)]);
+    const pgid = parseInt(stdout.trim(), 10);
+    return Number.isFinite(pgid) && pgid > 0 ? pgid : null;
+  } catch {
+    return null;
+  }
+}
+
 export function hasPty(id: string): boolean {
   return ptys.has(id);
 }
@@ -162,3 +265,49 @@ export function hasPty(id: string): boolean {
 export function getPty(id: string): IPty | undefined {
   return ptys.get(id)?.proc;
 }
+
+export function setPtyTitle(id: string, title: string): void {
+  const rec = ptys.get(id);
+  if (rec) rec.title = title;
+}
+
+export function recordDroppedChunk(id: string): void {
+  const rec = ptys.get(id);
+  if (rec) rec.stats.droppedChunks += 1;
+}
+
+export function getPtyStats(
+  id?: string
+): Array<PtyStats & { id: string; pid: number; uptimeMs: number }> {
+  const recs = id ? [ptys.get(id)].filter((r): r is PtyRecord => !!r) : Array.from(ptys.values());
+  return recs.map((rec) => ({
+    id: rec.id,
+    pid: rec.proc.pid,
+    ...rec.stats,
+    uptimeMs: (rec.stats.exitedAt ?? Date.now()) - rec.stats.startedAt,
+  }));
+}
+
+/**
+ * List PTYs, optionally only those carrying every given label with the same value.
+ */
+export function listPtys(
+  filter: { labels?: Record<string, string> } = {}
+): Array<{
+  id: string;
+  cwd: string;
+  pid: number;
+  labels: Record<string, string>;
+  title?: string;
+}> {
+  const wanted = Object.entries(filter.labels ?? {});
+  return Array.from(ptys.values())
+    .filter((rec) => wanted.every(([k, v]) => rec.labels[k] === v))
+    .map((rec) => ({
+      id: rec.id,
+      cwd: rec.cwd,
+      pid: rec.proc.pid,
+      labels: { ...rec.labels },
+      title: rec.title,
+    }));
+}
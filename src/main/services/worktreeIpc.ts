@@ -1,5 +1,26 @@
-import { ipcMain } from 'electron';
-import { worktreeService, WorktreeInfo } from './WorktreeService';
+import { ipcMain, BrowserWindow } from 'electron';
+import {
+  worktreeService,
+  WorktreeInfo,
+  MergeStrategy,
+  SyncStrategy,
+  RebasePlan,
+  WorktreeQuery,
+} from './WorktreeService';
+import { mergeGateService } from './MergeGateService';
+import { worktreeReaper } from './WorktreeReaper';
+import { artifactWatcher } from './ArtifactWatcher';
+import { toUserError } from '../lib/errorCatalog';
+import { eventLog, ServerEventScope } from './EventLog';
+
+function recordGitError(action: string, error: unknown, scope: ServerEventScope) {
+  eventLog.record({
+    kind: 'git-error',
+    severity: 'error',
+    message: `${action}: ${error instanceof Error ? error.message : String(error)}`,
+    scope,
+  });
+}
 
 export function registerWorktreeIpc(): void {
   // Create a new worktree
@@ -11,32 +32,67 @@ export function registerWorktreeIpc(): void {
         projectPath: string;
         workspaceName: string;
         projectId: string;
+        copyFiles?: string[];
+        ticket?: string;
       }
     ) => {
       try {
         const worktree = await worktreeService.createWorktree(
           args.projectPath,
           args.workspaceName,
-          args.projectId
+          args.projectId,
+          { copyFiles: args.copyFiles, ticket: args.ticket }
         );
         return { success: true, worktree };
       } catch (error) {
         console.error('Failed to create worktree:', error);
-        return { success: false, error: (error as Error).message };
+        recordGitError('Create worktree', error, {});
+        return { success: false, ...toUserError(error) };
+      }
+    }
+  );
+
+  // Create several worktrees in one call
+  ipcMain.handle(
+    'worktree:create-batch',
+    async (
+      event,
+      args: {
+        projectPath: string;
+        workspaceNames: string[];
+        projectId: string;
+        concurrency?: number;
+        copyFiles?: string[];
+      }
+    ) => {
+      try {
+        const results = await worktreeService.createWorktrees(
+          args.projectPath,
+          args.workspaceNames || [],
+          args.projectId,
+          { concurrency: args.concurrency, copyFiles: args.copyFiles }
+        );
+        return { success: true, results };
+      } catch (error) {
+        console.error('Failed to create worktrees:', error);
+        return { success: false, ...toUserError(error) };
       }
     }
   );
 
   // List worktrees for a project
-  ipcMain.handle('worktree:list', async (event, args: { projectPath: string }) => {
-    try {
-      const worktrees = await worktreeService.listWorktrees(args.projectPath);
-      return { success: true, worktrees };
-    } catch (error) {
-      console.error('Failed to list worktrees:', error);
-      return { success: false, error: (error as Error).message };
+  ipcMain.handle(
+    'worktree:list',
+    async (event, args: WorktreeQuery) => {
+      try {
+        const { worktrees, nextPageToken } = await worktreeService.queryWorktrees(args);
+        return { success: true, worktrees, nextPageToken };
+      } catch (error) {
+        console.error('Failed to list worktrees:', error);
+        return { success: false, ...toUserError(error) };
+      }
     }
-  });
+  );
 
   // Remove a worktree
   ipcMain.handle(
@@ -48,19 +104,26 @@ export function registerWorktreeIpc(): void {
         worktreeId: string;
         worktreePath?: string;
         branch?: string;
+        deleteBranch?: boolean;
+        force?: boolean;
       }
     ) => {
       try {
-        await worktreeService.removeWorktree(
+        const { branchDeleted } = await worktreeService.removeWorktree(
           args.projectPath,
           args.worktreeId,
           args.worktreePath,
-          args.branch
+          args.branch,
+          { deleteBranch: args.deleteBranch, force: args.force }
         );
-        return { success: true };
+        return { success: true, branchDeleted };
       } catch (error) {
         console.error('Failed to remove worktree:', error);
-        return { success: false, error: (error as Error).message };
+        recordGitError('Remove worktree', error, {
+          workspaceId: args.worktreeId,
+          worktreePath: args.worktreePath,
+        });
+        return { success: false, ...toUserError(error) };
       }
     }
   );
@@ -72,7 +135,7 @@ export function registerWorktreeIpc(): void {
       return { success: true, status };
     } catch (error) {
       console.error('Failed to get worktree status:', error);
-      return { success: false, error: (error as Error).message };
+      return { success: false, ...toUserError(error) };
     }
   });
 
@@ -91,7 +154,128 @@ export function registerWorktreeIpc(): void {
         return { success: true };
       } catch (error) {
         console.error('Failed to merge worktree changes:', error);
-        return { success: false, error: (error as Error).message };
+        return { success: false, ...toUserError(error) };
+      }
+    }
+  );
+
+  // Approve a workspace branch's current tip for the approval merge gate
+  ipcMain.handle(
+    'worktree:approve-merge',
+    async (
+      event,
+      args: { projectPath: string; worktreeId?: string; worktreePath?: string; branch?: string }
+    ) => {
+      try {
+        const { projectPath, ...options } = args;
+        const result = await worktreeService.approveMerge(projectPath, options);
+        return { success: true, ...result };
+      } catch (error) {
+        return { success: false, ...toUserError(error) };
+      }
+    }
+  );
+
+  // Land a workspace branch into the base branch
+  ipcMain.handle(
+    'worktree:merge-to-base',
+    async (
+      event,
+      args: {
+        projectPath: string;
+        worktreeId?: string;
+        worktreePath?: string;
+        branch?: string;
+        baseBranch?: string;
+        strategy?: MergeStrategy;
+        commitMessage?: string;
+      }
+    ) => {
+      try {
+        const { projectPath, ...options } = args;
+        const result = await worktreeService.mergeToBase(projectPath, options);
+        return { success: true, result };
+      } catch (error) {
+        console.error('Failed to merge worktree into base branch:', error);
+        recordGitError('Merge into base', error, {
+          workspaceId: args.worktreeId,
+          worktreePath: args.worktreePath,
+        });
+        return { success: false, ...toUserError(error) };
+      }
+    }
+  );
+
+  // Bring a workspace branch up to date with its base branch
+  ipcMain.handle(
+    'worktree:sync',
+    async (
+      event,
+      args: {
+        worktreePath: string;
+        baseBranch?: string;
+        strategy?: SyncStrategy;
+        remote?: string;
+      }
+    ) => {
+      try {
+        const { worktreePath, ...options } = args;
+        const result = await worktreeService.syncWorktree(worktreePath, options, (progress) => {
+          if (!event.sender.isDestroyed()) {
+            event.sender.send('worktree:sync-progress', { worktreePath, ...progress });
+          }
+        });
+        return { success: true, result };
+      } catch (error) {
+        console.error('Failed to sync worktree:', error);
+        recordGitError('Sync worktree', error, { worktreePath: args.worktreePath });
+        return { success: false, ...toUserError(error) };
+      }
+    }
+  );
+
+  // Rebase plan for cleaning up workspace branch history
+  ipcMain.handle(
+    'worktree:rebase-plan:get',
+    async (event, args: { worktreePath: string; base?: string }) => {
+      try {
+        const plan = await worktreeService.getRebasePlan(args.worktreePath, args.base);
+        return { success: true, plan };
+      } catch (error) {
+        console.error('Failed to get rebase plan:', error);
+        return { success: false, ...toUserError(error) };
+      }
+    }
+  );
+
+  ipcMain.handle(
+    'worktree:rebase-plan:execute',
+    async (event, args: { worktreePath: string; plan: RebasePlan }) => {
+      try {
+        const result = await worktreeService.executeRebasePlan(args.worktreePath, args.plan);
+        return { success: true, result };
+      } catch (error) {
+        console.error('Failed to execute rebase plan:', error);
+        recordGitError('Rebase', error, { worktreePath: args.worktreePath });
+        return { success: false, ...toUserError(error) };
+      }
+    }
+  );
+
+  // Preview the branch name a workspace would get under the configured (or given) template
+  ipcMain.handle(
+    'worktree:preview-branch-name',
+    async (event, args: { projectPath: string; workspaceName: string; template?: string }) => {
+      try {
+        const preview = await worktreeService.previewBranchName(
+          args.projectPath,
+          args.workspaceName,
+          args.template
+        );
+        return { success: true, ...preview };
+      } catch (error) {
+        console.error('Failed to preview branch name:', error);
+        return { success: false, ...toUserError(error) };
       }
     }
   );
@@ -103,7 +287,7 @@ export function registerWorktreeIpc(): void {
       return { success: true, worktree };
     } catch (error) {
       console.error('Failed to get worktree:', error);
-      return { success: false, error: (error as Error).message };
+      return { success: false, ...toUserError(error) };
     }
   });
 
@@ -114,7 +298,62 @@ export function registerWorktreeIpc(): void {
       return { success: true, worktrees };
     } catch (error) {
       console.error('Failed to get all worktrees:', error);
-      return { success: false, error: (error as Error).message };
+      return { success: false, ...toUserError(error) };
     }
   });
+
+  // Stale worktree GC: list candidates without removing anything
+  ipcMain.handle('worktree:gc:dry-run', async (event, args?: { staleAfterDays?: number }) => {
+    try {
+      const stale = await worktreeReaper.findStale(args?.staleAfterDays);
+      return { success: true, stale };
+    } catch (error) {
+      console.error('Failed to list stale worktrees:', error);
+      return { success: false, ...toUserError(error) };
+    }
+  });
+
+  // Stale worktree GC: run a sweep now using the configured policy
+  ipcMain.handle('worktree:gc:sweep', async () => {
+    try {
+      const events = await worktreeReaper.sweep();
+      return { success: true, events };
+    } catch (error) {
+      console.error('Failed to sweep stale worktrees:', error);
+      return { success: false, ...toUserError(error) };
+    }
+  });
+
+  // Keep every window in sync with worktree lifecycle changes
+  for (const channel of ['worktree:created', 'worktree:removed', 'worktree:dirty-changed']) {
+    worktreeService.on(channel, (data: any) => {
+      const windows = BrowserWindow.getAllWindows();
+      windows.forEach((w) => w.webContents.send(channel, data));
+    });
+  }
+
+  // Surface per-gate progress while a merge is being evaluated
+  mergeGateService.on('gate:status', (data: any) => {
+    const windows = BrowserWindow.getAllWindows();
+    windows.forEach((w) => w.webContents.send('worktree:merge-gate', data));
+  });
+
+  worktreeReaper.on('gc:audit', (data: any) => {
+    const windows = BrowserWindow.getAllWindows();
+    windows.forEach((w) => w.webContents.send('worktree:gc-audit', data));
+  });
+  worktreeReaper.start();
+  worktreeService.startDirtyWatch();
+
+  // Surface agent result markers (PLAN.md, test reports, ...) as they appear in worktrees
+  worktreeService.on('worktree:created', (data: any) => {
+    if (data?.worktree?.path) artifactWatcher.watch(data.worktree.path, data.worktree.id);
+  });
+  worktreeService.on('worktree:removed', (data: any) => {
+    if (data?.path) artifactWatcher.unwatch(data.path);
+  });
+  artifactWatcher.on('artifact:detected', (data: any) => {
+    const windows = BrowserWindow.getAllWindows();
+    windows.forEach((w) => w.webContents.send('workspace:artifact-detected', data));
+  });
 }
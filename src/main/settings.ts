@@ -1,10 +1,53 @@
 import { app } from 'electron';
 import { existsSync, readFileSync, writeFileSync, mkdirSync } from 'fs';
-import { dirname, join } from 'path';
+import { dirname, isAbsolute, join } from 'path';
 
 export interface RepositorySettings {
-  branchTemplate: string; // e.g., 'agent/{slug}-{timestamp}'
+  branchTemplate: string; // e.g., 'agent/{slug}-{timestamp}', 'feat/{user}/{ticket}-{slug}'
+  slugStrategy: string; // 'ascii' | 'transliterate' | 'unicode' or a registered strategy
   pushOnCreate: boolean; // default true
+  copyFiles: string[]; // untracked files copied into new worktrees, e.g. ['.env', '.envrc']
+  dependencyCaches: DependencyCacheConfig[];
+  // Clone new worktrees copy-on-write from a prepared checkout (falls back to git checkout)
+  cloneFromGolden: boolean;
+  goldenWorktreePath: string; // empty uses the project checkout itself
+}
+
+export interface DependencyCacheConfig {
+  name: string; // e.g., 'node_modules', 'go-mod', 'pip'
+  link?: string; // path inside the worktree to symlink to the shared cache, e.g. 'node_modules'
+  env?: string; // env var pointed at the shared cache, e.g. 'GOMODCACHE', 'PIP_CACHE_DIR'
+}
+
+export interface EnvProfileConfig {
+  name: string; // e.g., 'corp-proxy', 'gpu-tools'
+  env: Record<string, string>; // e.g., { HTTPS_PROXY: 'http://proxy:3128' }
+  pathPrepend: string[]; // directories placed in front of PATH
+}
+
+export interface McpServerConfig {
+  name: string; // e.g., 'github', 'postgres'
+  command: string;
+  args: string[];
+  env: Record<string, string>;
+  providers: string[]; // provider ids it applies to; empty means all
+  workspaces: string[]; // workspace ids it applies to; empty means all
+}
+
+export interface AgentPresetConfig {
+  name: string; // e.g., 'code-review'
+  providerId: string; // 'codex' | 'claude'
+  args: string[]; // extra CLI args
+  model: string; // empty keeps the CLI default
+  systemPromptFile: string; // absolute path; appended to Claude's system prompt
+  envProfile: string; // name of an envProfiles entry; empty for none
+}
+
+export interface MergeGateConfig {
+  id: string; // e.g., 'lint', 'tests', 'vuln-scan', 'review'
+  kind: 'command' | 'approval';
+  command?: string; // shell command run inside the worktree (kind === 'command')
+  timeoutMs?: number;
 }
 
 export interface AppSettings {
@@ -12,16 +55,161 @@ export interface AppSettings {
   projectPrep: {
     autoInstallOnOpenInEditor: boolean;
   };
+  mergeGates: {
+    gates: MergeGateConfig[];
+  };
+  agentGuardrails: {
+    // Cumulative changed lines/files per agent run; 0 disables a threshold
+    warnLines: number;
+    warnFiles: number;
+    stopLines: number;
+    stopFiles: number;
+  };
+  worktreeGc: {
+    enabled: boolean;
+    staleAfterDays: number; // no commits and no PTY/agent sessions for this long
+    action: 'flag' | 'remove';
+  };
+  commitMessage: {
+    // Shell command that receives the staged diff on stdin and prints a commit message;
+    // empty disables generation
+    generatorCommand: string;
+    timeoutMs: number;
+  };
+  // Named environments that PTY and agent sessions can reference instead of raw env maps
+  envProfiles: {
+    profiles: EnvProfileConfig[];
+  };
+  terminal: {
+    // Per-PTY output cap; reads pause once exceeded. 0 disables the limit
+    maxOutputBytesPerSec: number;
+    // Per-renderer input caps across all PTYs; excess input is dropped. 0 disables each limit.
+    // A single write larger than maxInputBytesPerSec is always rejected.
+    maxInputMessagesPerSec: number;
+    maxInputBytesPerSec: number;
+  };
+  // How PTY and agent sessions are stopped
+  processKill: {
+    signal: string; // first signal; empty keeps each session type's default
+    graceMs: number; // then SIGKILL if still running; 0 never escalates
+    processGroup: boolean; // signal the whole process group where possible
+  };
+  // Which agent providers may be started, keyed by provider id ('codex' | 'claude')
+  agentProviders: {
+    allowed: string[];
+    defaultArgs: Record<string, string[]>; // extra CLI args added to every run
+    requiredEnv: Record<string, string[]>; // variables that must be set before starting
+  };
+  // Named agent run configurations that requests can reference instead of spelling out
+  agentPresets: {
+    presets: AgentPresetConfig[];
+  };
+  // MCP servers wired into every matching agent run
+  mcp: {
+    servers: McpServerConfig[];
+  };
+  // Flag agent runs that go quiet; 0 disables detection
+  agentStall: {
+    timeoutMs: number; // no output for this long marks the run stalled
+    autoTerminate: boolean; // stop stalled runs instead of only reporting them
+  };
+  // Raw per-session agent output kept on disk for post-mortems
+  agentLogs: {
+    directory: string; // absolute path; empty uses <userData>/logs/agent
+    maxFileMb: number; // rotate the current file once it grows past this
+    maxFiles: number; // rotated files kept per session besides the current one
+    retentionDays: number; // delete log files not written for this long; 0 keeps them
+  };
+  // Cap on agent runs executing at once; further starts wait in a FIFO queue
+  agentConcurrency: {
+    maxRunning: number; // 0 means unlimited
+  };
+  // Prometheus endpoint for agent metrics, served on localhost only; read at startup
+  metrics: {
+    enabled: boolean;
+    port: number;
+  };
+  // Tools that wait for the user's approval in runs started with requireApproval
+  agentApproval: {
+    tools: string[]; // Claude tool names, e.g. 'Bash'
+  };
 }
 
 const DEFAULT_SETTINGS: AppSettings = {
   repository: {
     branchTemplate: 'agent/{slug}-{timestamp}',
+    slugStrategy: 'ascii',
     pushOnCreate: true,
+    copyFiles: [],
+    dependencyCaches: [],
+    cloneFromGolden: false,
+    goldenWorktreePath: '',
   },
   projectPrep: {
     autoInstallOnOpenInEditor: true,
   },
+  mergeGates: {
+    gates: [],
+  },
+  agentGuardrails: {
+    warnLines: 0,
+    warnFiles: 0,
+    stopLines: 0,
+    stopFiles: 0,
+  },
+  worktreeGc: {
+    enabled: false,
+    staleAfterDays: 14,
+    action: 'flag',
+  },
+  commitMessage: {
+    generatorCommand: '',
+    timeoutMs: 60_000,
+  },
+  envProfiles: {
+    profiles: [],
+  },
+  terminal: {
+    maxOutputBytesPerSec: 0,
+    maxInputMessagesPerSec: 0,
+    maxInputBytesPerSec: 0,
+  },
+  processKill: {
+    signal: '',
+    graceMs: 5000,
+    processGroup: false,
+  },
+  agentProviders: {
+    allowed: ['codex', 'claude'],
+    defaultArgs: {},
+    requiredEnv: {},
+  },
+  agentStall: {
+    timeoutMs: 0,
+    autoTerminate: false,
+  },
+  mcp: {
+    servers: [],
+  },
+  agentPresets: {
+    presets: [],
+  },
+  agentLogs: {
+    directory: '',
+    maxFileMb: 10,
+    maxFiles: 5,
+    retentionDays: 14,
+  },
+  agentApproval: {
+    tools: ['Bash'],
+  },
+  agentConcurrency: {
+    maxRunning: 0,
+  },
+  metrics: {
+    enabled: false,
+    port: 9464,
+  },
 };
 
 function getSettingsPath(): string {
@@ -64,6 +252,14 @@ export function getAppSettings(): AppSettings {
   return cached;
 }
 
+/**
+ * Drop the in-memory copy and re-read settings from disk.
+ */
+export function reloadAppSettings(): AppSettings {
+  cached = null;
+  return getAppSettings();
+}
+
 /**
  * Update settings and persist to disk. Partial updates are deeply merged.
  */
@@ -94,11 +290,43 @@ function normalizeSettings(input: AppSettings): AppSettings {
   const out: AppSettings = {
     repository: {
       branchTemplate: DEFAULT_SETTINGS.repository.branchTemplate,
+      slugStrategy: DEFAULT_SETTINGS.repository.slugStrategy,
       pushOnCreate: DEFAULT_SETTINGS.repository.pushOnCreate,
+      copyFiles: [],
+      dependencyCaches: [],
+      cloneFromGolden: DEFAULT_SETTINGS.repository.cloneFromGolden,
+      goldenWorktreePath: '',
     },
     projectPrep: {
       autoInstallOnOpenInEditor: DEFAULT_SETTINGS.projectPrep.autoInstallOnOpenInEditor,
     },
+    mergeGates: {
+      gates: [],
+    },
+    agentGuardrails: { ...DEFAULT_SETTINGS.agentGuardrails },
+    worktreeGc: { ...DEFAULT_SETTINGS.worktreeGc },
+    commitMessage: { ...DEFAULT_SETTINGS.commitMessage },
+    envProfiles: {
+      profiles: [],
+    },
+    terminal: { ...DEFAULT_SETTINGS.terminal },
+    processKill: { ...DEFAULT_SETTINGS.processKill },
+    agentProviders: {
+      allowed: [],
+      defaultArgs: {},
+      requiredEnv: {},
+    },
+    agentStall: { ...DEFAULT_SETTINGS.agentStall },
+    mcp: {
+      servers: [],
+    },
+    agentPresets: {
+      presets: [],
+    },
+    agentLogs: { ...DEFAULT_SETTINGS.agentLogs },
+    agentApproval: { tools: [] },
+    agentConcurrency: { ...DEFAULT_SETTINGS.agentConcurrency },
+    metrics: { ...DEFAULT_SETTINGS.metrics },
   };
 
   // Repository
@@ -111,11 +339,219 @@ function normalizeSettings(input: AppSettings): AppSettings {
   const push = Boolean(repo?.pushOnCreate ?? DEFAULT_SETTINGS.repository.pushOnCreate);
 
   out.repository.branchTemplate = template;
+  const strategy = String(repo?.slugStrategy ?? '').trim();
+  out.repository.slugStrategy = strategy || DEFAULT_SETTINGS.repository.slugStrategy;
   out.repository.pushOnCreate = push;
+  if (Array.isArray(repo?.copyFiles)) {
+    out.repository.copyFiles = Array.from(
+      new Set(
+        repo.copyFiles
+          .map((f: unknown) => String(f ?? '').trim())
+          .filter((f: string) => f && !isAbsolute(f) && !f.split(/[\\/]/).includes('..'))
+      )
+    );
+  }
+  if (Array.isArray(repo?.dependencyCaches)) {
+    const seen = new Set<string>();
+    for (const c of repo.dependencyCaches as any[]) {
+      const name = String(c?.name ?? '').trim();
+      if (!name || seen.has(name)) continue;
+      const link = typeof c?.link === 'string' ? c.link.trim() : '';
+      const env = typeof c?.env === 'string' ? c.env.trim() : '';
+      const linkOk = link && !isAbsolute(link) && !link.split(/[\\/]/).includes('..');
+      const envOk = /^[A-Za-z_][A-Za-z0-9_]*$/.test(env);
+      if (!linkOk && !envOk) continue;
+      seen.add(name);
+      out.repository.dependencyCaches.push({
+        name,
+        ...(linkOk ? { link } : {}),
+        ...(envOk ? { env } : {}),
+      });
+    }
+  }
+  out.repository.cloneFromGolden = Boolean(
+    repo?.cloneFromGolden ?? DEFAULT_SETTINGS.repository.cloneFromGolden
+  );
+  const golden = typeof repo?.goldenWorktreePath === 'string' ? repo.goldenWorktreePath.trim() : '';
+  out.repository.goldenWorktreePath = golden && isAbsolute(golden) ? golden : '';
   // Project prep
   const prep = (input as any)?.projectPrep || {};
   out.projectPrep.autoInstallOnOpenInEditor = Boolean(
     prep?.autoInstallOnOpenInEditor ?? DEFAULT_SETTINGS.projectPrep.autoInstallOnOpenInEditor
   );
+  // Merge gates
+  const gates = (input as any)?.mergeGates?.gates;
+  if (Array.isArray(gates)) {
+    for (const g of gates) {
+      const id = String(g?.id ?? '').trim();
+      if (!id) continue;
+      const kind = g?.kind === 'approval' ? 'approval' : 'command';
+      const command = typeof g?.command === 'string' ? g.command.trim() : '';
+      if (kind === 'command' && !command) continue;
+      const timeoutMs = Number(g?.timeoutMs);
+      out.mergeGates.gates.push({
+        id,
+        kind,
+        ...(kind === 'command' ? { command } : {}),
+        ...(Number.isFinite(timeoutMs) && timeoutMs > 0 ? { timeoutMs } : {}),
+      });
+    }
+  }
+  // Agent guardrails
+  const guard = (input as any)?.agentGuardrails || {};
+  for (const key of ['warnLines', 'warnFiles', 'stopLines', 'stopFiles'] as const) {
+    const n = Math.floor(Number(guard?.[key] ?? DEFAULT_SETTINGS.agentGuardrails[key]));
+    out.agentGuardrails[key] = Number.isFinite(n) && n > 0 ? n : 0;
+  }
+  // Stale worktree GC
+  const gc = (input as any)?.worktreeGc || {};
+  out.worktreeGc.enabled = Boolean(gc?.enabled ?? DEFAULT_SETTINGS.worktreeGc.enabled);
+  const staleDays = Number(gc?.staleAfterDays);
+  if (Number.isFinite(staleDays) && staleDays >= 1) out.worktreeGc.staleAfterDays = staleDays;
+  out.worktreeGc.action = gc?.action === 'remove' ? 'remove' : 'flag';
+  // Commit message generator
+  const cm = (input as any)?.commitMessage || {};
+  out.commitMessage.generatorCommand =
+    typeof cm?.generatorCommand === 'string' ? cm.generatorCommand.trim() : '';
+  const cmTimeout = Number(cm?.timeoutMs);
+  if (Number.isFinite(cmTimeout) && cmTimeout > 0) out.commitMessage.timeoutMs = cmTimeout;
+  // Env profiles
+  const profiles = (input as any)?.envProfiles?.profiles;
+  if (Array.isArray(profiles)) {
+    const seen = new Set<string>();
+    for (const p of profiles) {
+      const name = String(p?.name ?? '').trim();
+      if (!name || seen.has(name)) continue;
+      seen.add(name);
+      const env: Record<string, string> = {};
+      if (p?.env && typeof p.env === 'object' && !Array.isArray(p.env)) {
+        for (const [k, v] of Object.entries(p.env)) {
+          if (/^[A-Za-z_][A-Za-z0-9_]*$/.test(k) && v !== undefined && v !== null) {
+            env[k] = String(v);
+          }
+        }
+      }
+      const pathPrepend = Array.isArray(p?.pathPrepend)
+        ? p.pathPrepend.map((d: unknown) => String(d ?? '').trim()).filter(Boolean)
+        : [];
+      out.envProfiles.profiles.push({ name, env, pathPrepend });
+    }
+  }
+  // Terminal
+  const maxRate = Math.floor(Number((input as any)?.terminal?.maxOutputBytesPerSec));
+  out.terminal.maxOutputBytesPerSec = Number.isFinite(maxRate) && maxRate > 0 ? maxRate : 0;
+  const inputMessages = Math.floor(Number((input as any)?.terminal?.maxInputMessagesPerSec));
+  out.terminal.maxInputMessagesPerSec =
+    Number.isFinite(inputMessages) && inputMessages > 0 ? inputMessages : 0;
+  const inputBytes = Math.floor(Number((input as any)?.terminal?.maxInputBytesPerSec));
+  out.terminal.maxInputBytesPerSec = Number.isFinite(inputBytes) && inputBytes > 0 ? inputBytes : 0;
+  // Process kill
+  const pk = (input as any)?.processKill || {};
+  const killSignal = typeof pk?.signal === 'string' ? pk.signal.trim().toUpperCase() : '';
+  out.processKill.signal = /^SIG[A-Z]+$/.test(killSignal) ? killSignal : '';
+  const grace = Number(pk?.graceMs ?? DEFAULT_SETTINGS.processKill.graceMs);
+  out.processKill.graceMs = Number.isFinite(grace) && grace > 0 ? grace : 0;
+  out.processKill.processGroup = Boolean(pk?.processGroup ?? false);
+  // Agent providers
+  const ap = (input as any)?.agentProviders || {};
+  const toList = (v: unknown): string[] =>
+    Array.isArray(v) ? v.map((x) => String(x ?? '').trim()).filter(Boolean) : [];
+  out.agentProviders.allowed = Array.isArray(ap?.allowed)
+    ? Array.from(new Set(toList(ap.allowed)))
+    : [...DEFAULT_SETTINGS.agentProviders.allowed];
+  for (const field of ['defaultArgs', 'requiredEnv'] as const) {
+    const src = ap?.[field];
+    if (!src || typeof src !== 'object' || Array.isArray(src)) continue;
+    for (const [id, list] of Object.entries(src)) {
+      const values = toList(list);
+      if (field === 'requiredEnv') {
+        out.agentProviders.requiredEnv[id] = values.filter((k) =>
+          /^[A-Za-z_][A-Za-z0-9_]*$/.test(k)
+        );
+      } else {
+        out.agentProviders.defaultArgs[id] = values;
+      }
+    }
+  }
+  // Agent stall detection
+  const stall = (input as any)?.agentStall || {};
+  const stallMs = Math.floor(Number(stall?.timeoutMs));
+  out.agentStall.timeoutMs = Number.isFinite(stallMs) && stallMs > 0 ? stallMs : 0;
+  out.agentStall.autoTerminate = Boolean(stall?.autoTerminate ?? false);
+  // MCP servers
+  const mcpServers = (input as any)?.mcp?.servers;
+  if (Array.isArray(mcpServers)) {
+    const seen = new Set<string>();
+    for (const m of mcpServers) {
+      const name = String(m?.name ?? '').trim();
+      const command = String(m?.command ?? '').trim();
+      if (!/^[A-Za-z0-9_-]+$/.test(name) || !command || seen.has(name)) continue;
+      seen.add(name);
+      const env: Record<string, string> = {};
+      if (m?.env && typeof m.env === 'object' && !Array.isArray(m.env)) {
+        for (const [k, v] of Object.entries(m.env)) {
+          if (/^[A-Za-z_][A-Za-z0-9_]*$/.test(k) && v !== undefined && v !== null) {
+            env[k] = String(v);
+          }
+        }
+      }
+      out.mcp.servers.push({
+        name,
+        command,
+        args: Array.isArray(m?.args) ? m.args.map((a: unknown) => String(a ?? '')) : [],
+        env,
+        providers: toList(m?.providers),
+        workspaces: toList(m?.workspaces),
+      });
+    }
+  }
+  // Agent presets
+  const presets = (input as any)?.agentPresets?.presets;
+  if (Array.isArray(presets)) {
+    const seen = new Set<string>();
+    for (const p of presets) {
+      const name = String(p?.name ?? '').trim();
+      const providerId = String(p?.providerId ?? '').trim();
+      if (!name || !providerId || seen.has(name)) continue;
+      seen.add(name);
+      const promptFile = String(p?.systemPromptFile ?? '').trim();
+      out.agentPresets.presets.push({
+        name,
+        providerId,
+        args: Array.isArray(p?.args) ? p.args.map((a: unknown) => String(a ?? '')) : [],
+        model: String(p?.model ?? '').trim(),
+        systemPromptFile: promptFile && isAbsolute(promptFile) ? promptFile : '',
+        envProfile: String(p?.envProfile ?? '').trim(),
+      });
+    }
+  }
+  // Agent output logs
+  const logs = (input as any)?.agentLogs || {};
+  const logDir = String(logs?.directory ?? '').trim();
+  out.agentLogs.directory = logDir && isAbsolute(logDir) ? logDir : '';
+  const maxFileMb = Number(logs?.maxFileMb);
+  out.agentLogs.maxFileMb =
+    Number.isFinite(maxFileMb) && maxFileMb > 0 ? maxFileMb : DEFAULT_SETTINGS.agentLogs.maxFileMb;
+  const maxFiles = Math.floor(Number(logs?.maxFiles));
+  out.agentLogs.maxFiles =
+    Number.isFinite(maxFiles) && maxFiles >= 0 ? maxFiles : DEFAULT_SETTINGS.agentLogs.maxFiles;
+  const retention = Number(logs?.retentionDays);
+  out.agentLogs.retentionDays =
+    Number.isFinite(retention) && retention >= 0
+      ? retention
+      : DEFAULT_SETTINGS.agentLogs.retentionDays;
+  // Approval-gated tools
+  const approvalTools = (input as any)?.agentApproval?.tools;
+  out.agentApproval.tools = Array.isArray(approvalTools)
+    ? Array.from(new Set(approvalTools.map((t: unknown) => String(t ?? '').trim()).filter(Boolean)))
+    : [...DEFAULT_SETTINGS.agentApproval.tools];
+  // Agent concurrency
+  const maxRunning = Math.floor(Number((input as any)?.agentConcurrency?.maxRunning));
+  out.agentConcurrency.maxRunning = Number.isFinite(maxRunning) && maxRunning > 0 ? maxRunning : 0;
+  // Metrics endpoint
+  const metrics = (input as any)?.metrics || {};
+  out.metrics.enabled = Boolean(metrics?.enabled ?? DEFAULT_SETTINGS.metrics.enabled);
+  const port = Math.floor(Number(metrics?.port));
+  out.metrics.port = Number.isFinite(port) && port > 0 && port < 65536 ? port : 9464;
   return out;
 }
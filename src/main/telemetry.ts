@@ -32,20 +32,28 @@ type TelemetryEvent =
   | 'error'
   // Aggregates (privacy-safe)
   | 'workspace_snapshot'
-  // Session summary (duration only)
+  // Session summary (duration, error rate and feature usage counts)
   | 'app_session';
 
 interface InitOptions {
   installSource?: string;
 }
 
-let enabled = true;
+// Env switch: TELEMETRY_ENABLED=false vetoes sending even after the user opted in
+let enabled = false;
 let apiKey: string | undefined;
 let host: string | undefined;
 let instanceId: string | undefined;
 let installSource: string | undefined;
-let userOptOut: boolean | undefined; // persisted user setting
+let userOptIn = false; // persisted user setting; nothing is sent until it is set
 let sessionStartMs: number = Date.now();
+// Per-session aggregates reported with app_session; only counted while telemetry is enabled
+let sessionErrorCount = 0;
+const sessionFeatureCounts = new Map<string, number>();
+const MAX_SESSION_FEATURES = 20;
+// Last payloads actually sent, kept for the transparency view (api key omitted)
+const recentPayloads: Array<{ sentAt: string; event: TelemetryEvent; properties: any }> = [];
+const MAX_RECENT_PAYLOADS = 20;
 
 const libName = 'emdash';
 
@@ -100,7 +108,7 @@ function cryptoRandomId(): string {
 function isEnabled(): boolean {
   return (
     enabled === true &&
-    userOptOut !== true &&
+    userOptIn === true &&
     !!apiKey &&
     !!host &&
     typeof instanceId === 'string' &&
@@ -128,6 +136,10 @@ function sanitizeEventAndProps(event: TelemetryEvent, props: Record<string, any>
     'type',
     // session
     'session_duration_ms',
+    'error_count',
+    'errors_per_hour',
+    'feature_use_count',
+    'features_used',
     // aggregates (counts + buckets only)
     'workspace_count',
     'workspace_count_bucket',
@@ -163,14 +175,37 @@ function sanitizeEventAndProps(event: TelemetryEvent, props: Record<string, any>
       if (typeof p.type !== 'string') delete p.type;
       break;
     case 'app_session':
-      // Only duration
+      // Only duration and aggregate counts
       if (p.session_duration_ms != null) {
         const v = clampInt(p.session_duration_ms, 0, 1000 * 60 * 60 * 24); // up to 24h
         if (v == null) delete p.session_duration_ms;
         else p.session_duration_ms = v;
       }
+      for (const k of ['error_count', 'feature_use_count']) {
+        if (p[k] == null) continue;
+        const v = clampInt(p[k], 0, 1_000_000);
+        if (v == null) delete p[k];
+        else p[k] = v;
+      }
+      if (typeof p.errors_per_hour !== 'number' || !Number.isFinite(p.errors_per_hour)) {
+        delete p.errors_per_hour;
+      }
+      // Comma-separated feature names, each matching the feature_used name shape
+      if (typeof p.features_used !== 'string' || !/^[\w.-]*(,[\w.-]+)*$/.test(p.features_used)) {
+        delete p.features_used;
+      }
       // strip any other keys
-      for (const k of Object.keys(p)) if (k !== 'session_duration_ms') delete p[k];
+      for (const k of Object.keys(p)) {
+        if (
+          k !== 'session_duration_ms' &&
+          k !== 'error_count' &&
+          k !== 'errors_per_hour' &&
+          k !== 'feature_use_count' &&
+          k !== 'features_used'
+        ) {
+          delete p[k];
+        }
+      }
       break;
     case 'workspace_snapshot':
       // Allow only counts and very coarse buckets
@@ -211,6 +246,14 @@ function sanitizeEventAndProps(event: TelemetryEvent, props: Record<string, any>
   return p;
 }
 
+function buildProperties(event: TelemetryEvent, properties?: Record<string, any>) {
+  return {
+    distinct_id: instanceId,
+    ...getBaseProps(),
+    ...sanitizeEventAndProps(event, properties),
+  };
+}
+
 async function posthogCapture(
   event: TelemetryEvent,
   properties?: Record<string, any>
@@ -224,12 +267,10 @@ async function posthogCapture(
     const body = {
       api_key: apiKey,
       event,
-      properties: {
-        distinct_id: instanceId,
-        ...getBaseProps(),
-        ...sanitizeEventAndProps(event, properties),
-      },
+      properties: buildProperties(event, properties),
     };
+    recentPayloads.push({ sentAt: new Date().toISOString(), event, properties: body.properties });
+    if (recentPayloads.length > MAX_RECENT_PAYLOADS) recentPayloads.shift();
     await f(u, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
@@ -242,7 +283,8 @@ async function posthogCapture(
 
 export function init(options?: InitOptions) {
   const env = process.env;
-  const enabledEnv = (env.TELEMETRY_ENABLED ?? 'true').toString().toLowerCase();
+  // The env var can only veto; sending additionally requires the user's explicit opt-in
+  const enabledEnv = (env.TELEMETRY_ENABLED ?? '').toString().toLowerCase();
   enabled = enabledEnv !== 'false' && enabledEnv !== '0' && enabledEnv !== 'no';
   apiKey =
     env.POSTHOG_PROJECT_API_KEY || (appConfig?.posthogKey as string | undefined) || undefined;
@@ -254,9 +296,10 @@ export function init(options?: InitOptions) {
   const state = loadOrCreateState();
   instanceId = state.instanceId;
   sessionStartMs = Date.now();
-  // If enabledOverride is explicitly false, user opted out; otherwise leave undefined
-  userOptOut =
-    typeof state.enabledOverride === 'boolean' ? state.enabledOverride === false : undefined;
+  // Only an explicitly stored `enabled: true` counts as opting in
+  userOptIn = state.enabledOverride === true;
+  sessionErrorCount = 0;
+  sessionFeatureCounts.clear();
 
   // Fire lifecycle start
   void posthogCapture('app_started');
@@ -264,13 +307,40 @@ export function init(options?: InitOptions) {
 
 export function capture(event: TelemetryEvent, properties?: Record<string, any>) {
   if (event === 'app_session') {
-    const dur = Math.max(0, Date.now() - (sessionStartMs || Date.now()));
-    void posthogCapture(event, { session_duration_ms: dur });
+    void posthogCapture(event, sessionSummary());
     return;
   }
+  if (isEnabled()) recordSessionUsage(event, properties);
   void posthogCapture(event, properties);
 }
 
+function recordSessionUsage(event: TelemetryEvent, properties?: Record<string, any>) {
+  if (event === 'error') {
+    sessionErrorCount++;
+  } else if (event === 'feature_used') {
+    const feature = properties?.feature;
+    if (typeof feature !== 'string' || !/^[\w.-]{1,64}$/.test(feature)) return;
+    const count = sessionFeatureCounts.get(feature);
+    if (count !== undefined) sessionFeatureCounts.set(feature, count + 1);
+    else if (sessionFeatureCounts.size < MAX_SESSION_FEATURES) sessionFeatureCounts.set(feature, 1);
+  }
+}
+
+/** Duration plus error rate and which features were used this session (names and counts only). */
+function sessionSummary() {
+  const dur = Math.max(0, Date.now() - (sessionStartMs || Date.now()));
+  const hours = dur / (1000 * 60 * 60);
+  let featureUses = 0;
+  for (const n of sessionFeatureCounts.values()) featureUses += n;
+  return {
+    session_duration_ms: dur,
+    error_count: sessionErrorCount,
+    errors_per_hour: hours > 0 ? Math.round((sessionErrorCount / hours) * 100) / 100 : 0,
+    feature_use_count: featureUses,
+    features_used: [...sessionFeatureCounts.keys()].sort().join(','),
+  };
+}
+
 export function shutdown() {
   // No-op for now (no batching). Left for future posthog-node integration.
 }
@@ -283,13 +353,25 @@ export function getTelemetryStatus() {
   return {
     enabled: isEnabled(),
     envDisabled: !enabled,
-    userOptOut: userOptOut === true,
+    userOptIn,
     hasKeyAndHost: !!apiKey && !!host,
   };
 }
 
+/**
+ * Exactly what telemetry sends: the sanitized properties of an example event plus the most
+ * recently sent payloads. Works while telemetry is disabled so users can inspect it first.
+ */
+export function getTelemetryPayloadPreview() {
+  return {
+    endpoint: host ? host + '/capture/' : null,
+    example: { event: 'app_started' as TelemetryEvent, properties: buildProperties('app_started') },
+    recent: recentPayloads.map((p) => ({ ...p })),
+  };
+}
+
 export function setTelemetryEnabledViaUser(enabledFlag: boolean) {
-  userOptOut = !enabledFlag;
+  userOptIn = enabledFlag;
   // Persist alongside instanceId
   try {
     const file = getInstanceIdPath();
@@ -3,9 +3,9 @@ import { Switch } from './ui/switch';
 import { Button } from './ui/button';
 
 const TelemetryCard: React.FC = () => {
-  // Represents the user's telemetry preference (env + opt-out),
+  // Represents the user's telemetry preference (env + opt-in),
   // not whether telemetry is currently sending (which also depends on keys).
-  const [prefEnabled, setPrefEnabled] = React.useState<boolean>(true);
+  const [prefEnabled, setPrefEnabled] = React.useState<boolean>(false);
   const [loading, setLoading] = React.useState<boolean>(true);
   const [envDisabled, setEnvDisabled] = React.useState<boolean>(false);
   const [hasKeyAndHost, setHasKeyAndHost] = React.useState<boolean>(true);
@@ -14,11 +14,11 @@ const TelemetryCard: React.FC = () => {
     try {
       const res = await window.electronAPI.getTelemetryStatus();
       if (res.success && res.status) {
-        const { envDisabled: envOff, userOptOut, hasKeyAndHost } = res.status;
+        const { envDisabled: envOff, userOptIn, hasKeyAndHost } = res.status;
         setEnvDisabled(Boolean(envOff));
         setHasKeyAndHost(Boolean(hasKeyAndHost));
-        // Preference is enabled if env allows and user hasn't opted out.
-        setPrefEnabled(!Boolean(envOff) && userOptOut !== true);
+        // Preference is enabled only if env allows and the user opted in.
+        setPrefEnabled(!Boolean(envOff) && userOptIn === true);
       }
     } finally {
       setLoading(false);
@@ -221,15 +221,8 @@ const useClaudeStream = (options?: Options | null): Result => {
         sender: 'agent',
         timestamp: new Date(),
       };
+      // The main process persists the response (see AgentService.persistTranscript)
       setMessages((prev) => [...prev, agentMsg]);
-      try {
-        await window.electronAPI.saveMessage({
-          id: agentMsg.id,
-          conversationId: convoId,
-          content: agentMsg.content,
-          sender: agentMsg.sender,
-        });
-      } catch {}
       bufferRef.current = '';
       setStreamingOutput('');
     });
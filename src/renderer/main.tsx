@@ -3,6 +3,9 @@ import ReactDOM from 'react-dom/client';
 import App from './App';
 import './index.css';
 
+// Let the main process localize error messages to the user's languages
+window.electronAPI?.setLocale?.(navigator.languages?.join(',') || navigator.language || null);
+
 const root = ReactDOM.createRoot(document.getElementById('root') as HTMLElement);
 
 // Avoid double-mount in dev which can duplicate PTY sessions
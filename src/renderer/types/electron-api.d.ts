@@ -1,6 +1,80 @@
 // Updated for Codex integration
 import type { ResolvedContainerConfig, RunnerEvent, RunnerMode } from '../../shared/container';
 
+type AgentDiffSummary = {
+  baseRef: string;
+  files: Array<{
+    path: string;
+    previousPath?: string;
+    status: 'added' | 'modified' | 'deleted' | 'renamed';
+    insertions: number;
+    deletions: number;
+    binary?: boolean;
+  }>;
+  added: number;
+  modified: number;
+  deleted: number;
+  renamed: number;
+  insertions: number;
+  deletions: number;
+};
+
+type AgentRunResult = {
+  status: 'stopped' | 'error' | 'timed-out';
+  exitCode?: number | null;
+  error?: string;
+  finishedAt: string;
+  diff?: AgentDiffSummary;
+  diffError?: string;
+};
+
+type PtyErrorCode =
+  | 'invalid-request'
+  | 'session-not-found'
+  | 'spawn-failed'
+  | 'not-attached'
+  | 'read-only';
+
+type PtyExitInfo = {
+  exitCode: number;
+  signal?: number;
+  reason?: 'exited' | 'killed' | 'crash-loop' | 'restart-failed';
+};
+
+type AppNotification = {
+  id: string;
+  at: string;
+  kind: 'shutdown-pending' | 'worktree-removed' | 'agent-finished' | 'disk-nearly-full';
+  severity: 'info' | 'warn' | 'error';
+  title: string;
+  message: string;
+  scope: { workspaceId?: string; worktreePath?: string };
+};
+
+type FanOutBatch = {
+  id: string;
+  prompt: string;
+  createdAt: string;
+  members: Array<{
+    workspaceName: string;
+    providerId: 'codex' | 'claude';
+    preset?: string;
+    workspaceId?: string;
+    worktreePath?: string;
+    branch?: string;
+    status:
+      | 'creating'
+      | 'starting'
+      | 'running'
+      | 'stalled'
+      | 'stopping'
+      | 'stopped'
+      | 'error'
+      | 'timed-out';
+    error?: string;
+  }>;
+};
+
 export {};
 
 declare global {
@@ -10,6 +84,8 @@ declare global {
       getAppVersion: () => Promise<string>;
       getElectronVersion: () => Promise<string>;
       getPlatform: () => Promise<string>;
+      getCapabilities: () => Promise<{ signals: Record<string, string> }>;
+      setLocale: (acceptLanguage: string | null) => Promise<{ success: boolean }>;
       // Updater
       checkForUpdates: () => Promise<{ success: boolean; result?: any; error?: string }>;
       downloadUpdate: () => Promise<{ success: boolean; error?: string }>;
@@ -45,14 +121,68 @@ declare global {
         id: string;
         cwd?: string;
         shell?: string;
+        command?: string;
+        args?: string[];
         env?: Record<string, string>;
         cols?: number;
         rows?: number;
-      }) => Promise<{ ok: boolean; error?: string }>;
+        readOnly?: boolean;
+        envProfile?: string;
+        labels?: Record<string, string>;
+        restartOnExit?: boolean;
+        maxOutputBytesPerSec?: number;
+        resumeFrom?: number;
+      }) => Promise<{ ok: boolean; truncated?: boolean; code?: PtyErrorCode; error?: string }>;
       ptyInput: (args: { id: string; data: string }) => void;
       ptyResize: (args: { id: string; cols: number; rows?: number }) => void;
-      ptyKill: (id: string) => void;
-      onPtyData: (id: string, listener: (data: string) => void) => () => void;
+      ptyKill: (
+        id: string,
+        kill?: {
+          signal?: 'SIGTERM' | 'SIGINT' | 'SIGHUP' | 'SIGQUIT' | 'SIGKILL';
+          graceMs?: number;
+          processGroup?: boolean;
+        }
+      ) => void;
+      ptyDetach: (id: string) => void;
+      ptyPause: (id: string) => void;
+      ptyResume: (id: string) => void;
+      ptyList: (args?: { labels?: Record<string, string> }) => Promise<{
+        ok: boolean;
+        ptys?: Array<{
+          id: string;
+          cwd: string;
+          pid: number;
+          labels: Record<string, string>;
+          title?: string;
+        }>;
+        error?: string;
+      }>;
+      ptyStats: (args?: { id?: string }) => Promise<{
+        ok: boolean;
+        stats?: Array<{
+          id: string;
+          pid: number;
+          bytesIn: number;
+          bytesOut: number;
+          framesIn: number;
+          framesOut: number;
+          droppedChunks: number;
+          startedAt: number;
+          exitedAt?: number;
+          uptimeMs: number;
+          clients: number;
+          paused: boolean;
+        }>;
+      }>;
+      ptySetController: (args: {
+        id: string;
+        controller: boolean;
+      }) => Promise<{ ok: boolean; error?: string }>;
+      ptySignal: (args: {
+        id: string;
+        signal: 'SIGINT' | 'SIGTSTP' | 'SIGQUIT' | 'SIGHUP' | 'SIGWINCH';
+      }) => Promise<{ ok: boolean; error?: string }>;
+      onPtyData: (id: string, listener: (data: string, seq?: number) => void) => () => void;
       ptyGetSnapshot: (args: { id: string }) => Promise<{
         ok: boolean;
         snapshot?: any;
@@ -63,9 +193,27 @@ declare global {
         error?: string;
       }>;
       ptyClearSnapshot: (args: { id: string }) => Promise<{ ok: boolean }>;
+      ptyGetTranscript: (args: { id: string; format?: 'ansi' | 'plain' }) => Promise<{
+        ok: boolean;
+        text?: string;
+        createdAt?: string;
+        error?: string;
+      }>;
       onPtyExit: (
         id: string,
-        listener: (info: { exitCode: number; signal?: number }) => void
+        listener: (info: PtyExitInfo) => void
+      ) => () => void;
+      onPtyRestarted: (
+        id: string,
+        listener: (info: { exitCode: number; signal?: number; restarts: number }) => void
+      ) => () => void;
+      onPtyTitle: (id: string, listener: (title: string) => void) => () => void;
+      onPtyInputLimited: (
+        listener: (data: {
+          id: string;
+          code: 'rate-limited';
+          reason: 'messages' | 'bytes';
+        }) => void
       ) => () => void;
       onPtyStarted: (listener: (data: { id: string }) => void) => () => void;
 
@@ -74,16 +222,47 @@ declare global {
         projectPath: string;
         workspaceName: string;
         projectId: string;
+        copyFiles?: string[];
+        ticket?: string;
       }) => Promise<{ success: boolean; worktree?: any; error?: string }>;
-      worktreeList: (args: {
+      worktreeCreateBatch: (args: {
         projectPath: string;
-      }) => Promise<{ success: boolean; worktrees?: any[]; error?: string }>;
+        workspaceNames: string[];
+        projectId: string;
+        concurrency?: number;
+        copyFiles?: string[];
+      }) => Promise<{
+        success: boolean;
+        results?: Array<{
+      workspaceName: string;
+      success: boolean;
+      worktree?: any;
+      error?: string;
+    }>;
+        error?: string;
+      }>;
+      worktreeList: (args: {
+        projectPath?: string;
+        projectId?: string;
+        name?: string;
+        branch?: string;
+        includeStatus?: boolean;
+        pageSize?: number;
+        pageToken?: string;
+      }) => Promise<{
+        success: boolean;
+        worktrees?: any[];
+        nextPageToken?: string;
+        error?: string;
+      }>;
       worktreeRemove: (args: {
         projectPath: string;
         worktreeId: string;
         worktreePath?: string;
         branch?: string;
-      }) => Promise<{ success: boolean; error?: string }>;
+        deleteBranch?: boolean;
+        force?: boolean;
+      }) => Promise<{ success: boolean; branchDeleted?: boolean; error?: string }>;
       worktreeStatus: (args: {
         worktreePath: string;
       }) => Promise<{ success: boolean; status?: any; error?: string }>;
@@ -91,6 +270,132 @@ declare global {
         projectPath: string;
         worktreeId: string;
       }) => Promise<{ success: boolean; error?: string }>;
+      worktreeApproveMerge: (args: {
+        projectPath: string;
+        worktreeId?: string;
+        worktreePath?: string;
+        branch?: string;
+      }) => Promise<{ success: boolean; branch?: string; commit?: string; error?: string }>;
+      worktreeMergeToBase: (args: {
+        projectPath: string;
+        worktreeId?: string;
+        worktreePath?: string;
+        branch?: string;
+        baseBranch?: string;
+        strategy?: 'fast-forward' | 'merge' | 'squash';
+        commitMessage?: string;
+      }) => Promise<{
+        success: boolean;
+        result?: {
+          merged: boolean;
+          branch: string;
+          baseBranch: string;
+          strategy: 'fast-forward' | 'merge' | 'squash';
+          conflicts: string[];
+          gates: Array<{
+            id: string;
+            kind: 'command' | 'approval';
+            status: 'pending' | 'running' | 'passed' | 'failed';
+            output?: string;
+            durationMs?: number;
+          }>;
+          output?: string;
+        };
+        error?: string;
+      }>;
+      worktreeSync: (args: {
+        worktreePath: string;
+        baseBranch?: string;
+        strategy?: 'rebase' | 'merge';
+        remote?: string;
+      }) => Promise<{
+        success: boolean;
+        result?: {
+          synced: boolean;
+          strategy: 'rebase' | 'merge';
+          upstream: string;
+          conflicts: string[];
+          output?: string;
+        };
+        error?: string;
+      }>;
+      onWorktreeSyncProgress: (
+        listener: (data: {
+          worktreePath: string;
+          step: 'fetch' | 'rebase' | 'merge' | 'done';
+          message: string;
+        }) => void
+      ) => () => void;
+      worktreeRebasePlanGet: (args: { worktreePath: string; base?: string }) => Promise<{
+        success: boolean;
+        plan?: {
+          base: string;
+          entries: Array<{
+            action: 'pick' | 'reword' | 'squash' | 'fixup' | 'drop';
+            sha: string;
+            subject: string;
+            message?: string;
+          }>;
+        };
+        error?: string;
+      }>;
+      worktreeRebasePlanExecute: (args: {
+        worktreePath: string;
+        plan: {
+          base: string;
+          entries: Array<{
+            action: 'pick' | 'reword' | 'squash' | 'fixup' | 'drop';
+            sha: string;
+            subject: string;
+            message?: string;
+          }>;
+        };
+      }) => Promise<{
+        success: boolean;
+        result?: { success: boolean; conflicts: string[]; output?: string };
+        error?: string;
+      }>;
+      worktreeGcDryRun: (args?: { staleAfterDays?: number }) => Promise<{
+        success: boolean;
+        stale?: Array<{
+          workspaceId: string;
+          projectId: string;
+          projectPath: string;
+          name: string;
+          branch: string;
+          path: string;
+          lastActivityAt: string;
+          dirty: boolean;
+        }>;
+        error?: string;
+      }>;
+      worktreeGcSweep: () => Promise<{ success: boolean; events?: any[]; error?: string }>;
+      onWorktreeGcAudit: (
+        listener: (event: {
+          action: 'flagged' | 'removed' | 'skipped' | 'failed';
+          workspaceId: string;
+          path: string;
+          branch: string;
+          lastActivityAt: string;
+          reason?: string;
+          at: string;
+        }) => void
+      ) => () => void;
+      onWorkspaceArtifactDetected: (
+        listener: (event: {
+          worktreePath: string;
+          workspaceId?: string;
+          kind: 'plan' | 'test-report' | 'agent-result' | 'coverage';
+          path: string;
+          metadata: Record<string, unknown>;
+          detectedAt: string;
+        }) => void
+      ) => () => void;
+      worktreePreviewBranchName: (args: {
+        projectPath: string;
+        workspaceName: string;
+        template?: string;
+      }) => Promise<{ success: boolean; branch?: string; valid?: boolean; error?: string }>;
       worktreeGet: (args: {
         worktreeId: string;
       }) => Promise<{ success: boolean; worktree?: any; error?: string }>;
@@ -99,6 +404,25 @@ declare global {
         worktrees?: any[];
         error?: string;
       }>;
+      onWorktreeEvent: (
+        listener: (event: {
+          type: 'created' | 'removed' | 'dirty-changed';
+          payload: any;
+        }) => void
+      ) => () => void;
+      onWorktreeMergeGate: (
+        listener: (data: {
+          worktreePath?: string;
+          branch: string;
+          gate: {
+            id: string;
+            kind: 'command' | 'approval';
+            status: 'pending' | 'running' | 'passed' | 'failed';
+            output?: string;
+            durationMs?: number;
+          };
+        }) => void
+      ) => () => void;
 
       // Project management
       openProject: () => Promise<{
@@ -151,10 +475,13 @@ declare global {
         commitMessage?: string;
         createBranchIfOnDefault?: boolean;
         branchPrefix?: string;
+        autoCommitGenerated?: boolean;
       }) => Promise<{
         success: boolean;
         branch?: string;
         output?: string;
+        proposedMessage?: string;
+        committed?: boolean;
         error?: string;
       }>;
       createPullRequest: (args: {
@@ -266,7 +593,7 @@ declare global {
         status?: {
           enabled: boolean;
           envDisabled: boolean;
-          userOptOut: boolean;
+          userOptIn: boolean;
           hasKeyAndHost: boolean;
         };
         error?: string;
@@ -276,11 +603,50 @@ declare global {
         status?: {
           enabled: boolean;
           envDisabled: boolean;
-          userOptOut: boolean;
+          userOptIn: boolean;
           hasKeyAndHost: boolean;
         };
         error?: string;
       }>;
+      showTelemetryPayload: () => Promise<{
+        success: boolean;
+        status?: {
+          enabled: boolean;
+          envDisabled: boolean;
+          userOptIn: boolean;
+          hasKeyAndHost: boolean;
+        };
+        endpoint?: string | null;
+        example?: { event: string; properties: Record<string, any> };
+        recent?: Array<{ sentAt: string; event: string; properties: Record<string, any> }>;
+        error?: string;
+      }>;
+      // Dead letters
+      deadLetterList: () => Promise<{
+        success: boolean;
+        letters?: Array<{
+          id: string;
+          channel: string;
+          payload: any;
+          reason: string;
+          failedAt: string;
+          attempts: number;
+        }>;
+        error?: string;
+      }>;
+      deadLetterRedeliver: (args?: { ids?: string[] }) => Promise<{
+        success: boolean;
+        delivered?: number;
+        remaining?: number;
+        error?: string;
+      }>;
+      deadLetterDiscard: (args?: {
+        ids?: string[];
+      }) => Promise<{ success: boolean; discarded?: number; error?: string }>;
+      notificationsRecent: (args?: {
+        limit?: number;
+      }) => Promise<{ success: boolean; notifications?: AppNotification[]; error?: string }>;
+      onAppNotification: (listener: (notification: AppNotification) => void) => () => void;
 
       // Filesystem helpers
       fsList: (
@@ -451,6 +817,32 @@ declare global {
         content: string,
         options?: { reset?: boolean }
       ) => Promise<{ success: boolean; error?: string }>;
+      debugRecentEvents: (args?: {
+        limit?: number;
+        workspaceId?: string;
+        worktreePath?: string;
+        ptyId?: string;
+        kinds?: Array<
+          'session-failed' | 'session-exited' | 'client-dropped' | 'git-error' | 'agent-failed'
+        >;
+      }) => Promise<{
+        success: boolean;
+        events?: Array<{
+          seq: number;
+          at: string;
+          kind:
+        | 'session-failed'
+        | 'session-exited'
+        | 'client-dropped'
+        | 'git-error'
+        | 'agent-failed';
+          severity: 'info' | 'warn' | 'error';
+          message: string;
+          scope: { workspaceId?: string; worktreePath?: string; ptyId?: string };
+          detail?: Record<string, unknown>;
+        }>;
+        error?: string;
+      }>;
 
       // Codex
       codexCheckInstallation: () => Promise<{
@@ -472,7 +864,12 @@ declare global {
         conversationId?: string
       ) => Promise<{ success: boolean; error?: string }>;
       codexStopStream: (
-        workspaceId: string
+        workspaceId: string,
+        kill?: {
+          signal?: 'SIGTERM' | 'SIGINT' | 'SIGHUP' | 'SIGQUIT' | 'SIGKILL';
+          graceMs?: number;
+          processGroup?: boolean;
+        }
       ) => Promise<{ success: boolean; stopped?: boolean; error?: string }>;
       codexGetAgentStatus: (
         workspaceId: string
@@ -497,22 +894,304 @@ declare global {
         isInstalled?: boolean;
         error?: string;
       }>;
+      agentDiscoverProviders: () => Promise<{
+        success: boolean;
+        providers?: Array<{
+          name: string;
+          path: string;
+          version: string | null;
+          supported: boolean;
+          allowed: boolean;
+        }>;
+        error?: string;
+      }>;
       agentGetInstallationInstructions: (providerId: 'codex' | 'claude') => Promise<{
         success: boolean;
         instructions?: string;
         error?: string;
       }>;
       agentSendMessageStream: (args: {
-        providerId: 'codex' | 'claude';
+        providerId?: 'codex' | 'claude';
         workspaceId: string;
         worktreePath: string;
         message: string;
         conversationId?: string;
+        envProfile?: string;
+        agentId?: string;
+        restartPolicy?: {
+          mode: 'never' | 'on-failure';
+          maxRetries?: number;
+          backoffMs?: number;
+        };
+        usePty?: boolean;
+        resourceLimits?: { maxMemoryMb?: number; maxCpuPercent?: number };
+        queue?: boolean;
+        maxRuntimeMs?: number;
+        secrets?: Record<string, string>;
+        preset?: string;
+        requireApproval?: boolean;
+      }) => Promise<{ success: boolean; error?: string; messageId?: string; queued?: boolean }>;
+      agentStopStream: (args: {
+        providerId: 'codex' | 'claude';
+        workspaceId: string;
+        kill?: {
+          signal?: 'SIGTERM' | 'SIGINT' | 'SIGHUP' | 'SIGQUIT' | 'SIGKILL';
+          graceMs?: number;
+          processGroup?: boolean;
+        };
+        agentId?: string;
+      }) => Promise<{
+        success: boolean;
+        error?: string;
+      }>;
+      agentInterrupt: (args: {
+        providerId: 'codex' | 'claude';
+        workspaceId: string;
+        agentId?: string;
+      }) => Promise<{ success: boolean; interrupted?: boolean; error?: string }>;
+      agentGetLogs: (args: {
+        providerId: 'codex' | 'claude';
+        workspaceId: string;
+        offset?: number;
+        limit?: number;
+        agentId?: string;
+      }) => Promise<{
+        success: boolean;
+        chunks?: Array<{
+        seq: number;
+        at: string;
+        stream: 'stdout' | 'stderr';
+        text: string;
+      }>;
+        firstSeq?: number;
+        nextOffset?: number;
+        done?: boolean;
+        error?: string;
+      }>;
+      agentAttach: (args: {
+        providerId: 'codex' | 'claude';
+        workspaceId: string;
+        fromSeq?: number;
+        agentId?: string;
+      }) => Promise<{
+        success: boolean;
+        replay?: {
+          chunks: Array<{ seq: number; at: string; stream: 'stdout' | 'stderr'; text: string }>;
+          firstSeq: number;
+          nextOffset: number;
+          done: boolean;
+        };
+        attachedClients?: number;
+        error?: string;
+      }>;
+      agentDetach: (args: {
+        providerId: 'codex' | 'claude';
+        workspaceId: string;
+        agentId?: string;
+      }) => Promise<{ success: boolean; attachedClients?: number; error?: string }>;
+      agentList: (args?: { workspaceId?: string }) => Promise<{
+        success: boolean;
+        agents?: Array<{
+          providerId: 'codex' | 'claude';
+          workspaceId: string;
+          agentId?: string;
+          running: boolean;
+          restarts: number;
+          queued: number;
+          status?:
+            | 'starting'
+            | 'running'
+            | 'stalled'
+            | 'stopping'
+            | 'stopped'
+            | 'error'
+            | 'timed-out';
+          stalled: boolean;
+          timedOut: boolean;
+          remainingMs?: number;
+          attachedClients: number;
+          pendingApprovals: number;
+          startQueuePosition?: number;
+        }>;
+        error?: string;
+      }>;
+      agentListLogFiles: (args?: {
+        providerId?: 'codex' | 'claude';
+        workspaceId?: string;
+      }) => Promise<{
+        success: boolean;
+        files?: Array<{
+          id: string;
+          providerId: string;
+          workspaceId: string;
+          agentId?: string;
+          name: string;
+          size: number;
+          modifiedAt: string;
+        }>;
+        error?: string;
+      }>;
+      agentExportLogFile: (args: {
+        id: string;
+      }) => Promise<{ success: boolean; path?: string; error?: string }>;
+      agentApproveAction: (args: {
+        approvalId: string;
+        approved: boolean;
+        message?: string;
       }) => Promise<{ success: boolean; error?: string }>;
-      agentStopStream: (args: { providerId: 'codex' | 'claude'; workspaceId: string }) => Promise<{
+      agentListPresets: () => Promise<{
         success: boolean;
+        presets?: Array<{ name: string; providerId: string; model?: string }>;
         error?: string;
       }>;
+      secretsList: () => Promise<{ success: boolean; names?: string[]; error?: string }>;
+      secretsSet: (args: {
+        name: string;
+        value: string;
+      }) => Promise<{ success: boolean; error?: string }>;
+      secretsDelete: (args: {
+        name: string;
+      }) => Promise<{ success: boolean; deleted?: boolean; error?: string }>;
+      onAgentGuardrail: (
+        listener: (data: {
+          providerId: 'codex' | 'claude';
+          workspaceId: string;
+          level: 'warn' | 'stop';
+          stats: { files: number; insertions: number; deletions: number };
+          limits: { warnLines: number; warnFiles: number; stopLines: number; stopFiles: number };
+        }) => void
+      ) => () => void;
+      onAgentRestarting: (
+        listener: (data: {
+          providerId: 'codex' | 'claude';
+          workspaceId: string;
+          agentId?: string;
+          attempt: number;
+          maxRetries: number;
+          delayMs: number;
+        }) => void
+      ) => () => void;
+      onAgentEvent: (
+        listener: (data: {
+          providerId: 'codex' | 'claude';
+          workspaceId: string;
+          agentId?: string;
+          event:
+            | { type: 'assistant-text'; text: string; partial?: boolean }
+            | { type: 'tool-call'; id?: string; name: string; input?: unknown }
+            | { type: 'tool-result'; id?: string; content?: unknown; isError?: boolean }
+            | { type: 'usage'; inputTokens?: number; outputTokens?: number; costUsd?: number }
+            | { type: 'result'; text?: string; isError?: boolean };
+        }) => void
+      ) => () => void;
+      onAgentMessageStatus: (
+        listener: (data: {
+          providerId: 'codex' | 'claude';
+          workspaceId: string;
+          agentId?: string;
+          messageId: string;
+          status: 'queued' | 'delivered' | 'dropped';
+          position?: number;
+          reason?: 'concurrency'; // waiting for a slot rather than for the session
+          error?: string;
+        }) => void
+      ) => () => void;
+      onAgentStall: (
+        listener: (data: {
+          providerId: 'codex' | 'claude';
+          workspaceId: string;
+          agentId?: string;
+          stalled: boolean;
+          silentMs: number;
+        }) => void
+      ) => () => void;
+      onAgentTimedOut: (
+        listener: (data: {
+          providerId: 'codex' | 'claude';
+          workspaceId: string;
+          agentId?: string;
+          maxRuntimeMs: number;
+        }) => void
+      ) => () => void;
+      onAgentInterrupted: (
+        listener: (data: {
+          providerId: 'codex' | 'claude';
+          workspaceId: string;
+          agentId?: string;
+        }) => void
+      ) => () => void;
+      onAgentStatus: (
+        listener: (data: {
+          providerId: 'codex' | 'claude';
+          workspaceId: string;
+          agentId?: string;
+          status:
+            | 'starting'
+            | 'running'
+            | 'stalled'
+            | 'stopping'
+            | 'stopped'
+            | 'error'
+            | 'timed-out';
+          previous?:
+            | 'starting'
+            | 'running'
+            | 'stalled'
+            | 'stopping'
+            | 'stopped'
+            | 'error'
+            | 'timed-out';
+          exitCode?: number | null;
+          error?: string;
+          silentMs?: number; // with 'stalled'
+          reason?: string; // with 'stopping'
+        }) => void
+      ) => () => void;
+      agentFanOut: (args: {
+        projectPath: string;
+        projectId: string;
+        prompt: string;
+        targets: Array<{ workspaceName: string; providerId?: 'codex' | 'claude'; preset?: string }>;
+        concurrency?: number;
+      }) => Promise<{ success: boolean; batch?: FanOutBatch; error?: string }>;
+      agentFanOutStatus: (args: {
+        batchId: string;
+      }) => Promise<{ success: boolean; batch?: FanOutBatch; error?: string }>;
+      onAgentFanOutProgress: (listener: (batch: FanOutBatch) => void) => () => void;
+      agentGetResult: (args: {
+        providerId: 'codex' | 'claude';
+        workspaceId: string;
+        agentId?: string;
+      }) => Promise<{ success: boolean; result?: AgentRunResult; error?: string }>;
+      agentMetrics: () => Promise<{ success: boolean; text?: string; error?: string }>;
+      onAgentResult: (
+        listener: (
+          data: AgentRunResult & {
+            providerId: 'codex' | 'claude';
+            workspaceId: string;
+            agentId?: string;
+          }
+        ) => void
+      ) => () => void;
+      onAgentApprovalRequired: (
+        listener: (data: {
+          providerId: 'codex' | 'claude';
+          workspaceId: string;
+          agentId?: string;
+          approvalId: string;
+          toolName: string;
+          input: unknown;
+        }) => void
+      ) => () => void;
+      onAgentApprovalResolved: (
+        listener: (data: {
+          providerId: 'codex' | 'claude';
+          workspaceId: string;
+          agentId?: string;
+          approvalId: string;
+          approved: boolean;
+        }) => void
+      ) => () => void;
 
       // Streaming event listeners
       onCodexStreamOutput: (
@@ -533,6 +1212,8 @@ export interface ElectronAPI {
   // App info
   getVersion: () => Promise<string>;
   getPlatform: () => Promise<string>;
+  getCapabilities: () => Promise<{ signals: Record<string, string> }>;
+  setLocale: (acceptLanguage: string | null) => Promise<{ success: boolean }>;
   // Updater
   checkForUpdates: () => Promise<{ success: boolean; result?: any; error?: string }>;
   downloadUpdate: () => Promise<{ success: boolean; error?: string }>;
@@ -545,14 +1226,68 @@ export interface ElectronAPI {
     id: string;
     cwd?: string;
     shell?: string;
+    command?: string;
+    args?: string[];
     env?: Record<string, string>;
     cols?: number;
     rows?: number;
-  }) => Promise<{ ok: boolean; error?: string }>;
+    readOnly?: boolean;
+    envProfile?: string;
+    labels?: Record<string, string>;
+    restartOnExit?: boolean;
+    maxOutputBytesPerSec?: number;
+    resumeFrom?: number;
+  }) => Promise<{ ok: boolean; truncated?: boolean; code?: PtyErrorCode; error?: string }>;
   ptyInput: (args: { id: string; data: string }) => void;
   ptyResize: (args: { id: string; cols: number; rows?: number }) => void;
-  ptyKill: (id: string) => void;
-  onPtyData: (id: string, listener: (data: string) => void) => () => void;
+  ptyKill: (
+    id: string,
+    kill?: {
+      signal?: 'SIGTERM' | 'SIGINT' | 'SIGHUP' | 'SIGQUIT' | 'SIGKILL';
+      graceMs?: number;
+      processGroup?: boolean;
+    }
+  ) => void;
+  ptyDetach: (id: string) => void;
+  ptyPause: (id: string) => void;
+  ptyResume: (id: string) => void;
+  ptyList: (args?: { labels?: Record<string, string> }) => Promise<{
+    ok: boolean;
+    ptys?: Array<{
+      id: string;
+      cwd: string;
+      pid: number;
+      labels: Record<string, string>;
+      title?: string;
+    }>;
+    error?: string;
+  }>;
+  ptyStats: (args?: { id?: string }) => Promise<{
+    ok: boolean;
+    stats?: Array<{
+      id: string;
+      pid: number;
+      bytesIn: number;
+      bytesOut: number;
+      framesIn: number;
+      framesOut: number;
+      droppedChunks: number;
+      startedAt: number;
+      exitedAt?: number;
+      uptimeMs: number;
+      clients: number;
+      paused: boolean;
+    }>;
+  }>;
+  ptySetController: (args: {
+    id: string;
+    controller: boolean;
+  }) => Promise<{ ok: boolean; error?: string }>;
+  ptySignal: (args: {
+    id: string;
+    signal: 'SIGINT' | 'SIGTSTP' | 'SIGQUIT' | 'SIGHUP' | 'SIGWINCH';
+  }) => Promise<{ ok: boolean; error?: string }>;
+  onPtyData: (id: string, listener: (data: string, seq?: number) => void) => () => void;
   ptyGetSnapshot: (args: { id: string }) => Promise<{
     ok: boolean;
     snapshot?: any;
@@ -563,9 +1298,23 @@ export interface ElectronAPI {
     error?: string;
   }>;
   ptyClearSnapshot: (args: { id: string }) => Promise<{ ok: boolean }>;
+  ptyGetTranscript: (args: { id: string; format?: 'ansi' | 'plain' }) => Promise<{
+    ok: boolean;
+    text?: string;
+    createdAt?: string;
+    error?: string;
+  }>;
   onPtyExit: (
     id: string,
-    listener: (info: { exitCode: number; signal?: number }) => void
+    listener: (info: PtyExitInfo) => void
+  ) => () => void;
+  onPtyRestarted: (
+    id: string,
+    listener: (info: { exitCode: number; signal?: number; restarts: number }) => void
+  ) => () => void;
+  onPtyTitle: (id: string, listener: (title: string) => void) => () => void;
+  onPtyInputLimited: (
+    listener: (data: { id: string; code: 'rate-limited'; reason: 'messages' | 'bytes' }) => void
   ) => () => void;
   onPtyStarted: (listener: (data: { id: string }) => void) => () => void;
 
@@ -574,16 +1323,47 @@ export interface ElectronAPI {
     projectPath: string;
     workspaceName: string;
     projectId: string;
+    copyFiles?: string[];
+    ticket?: string;
   }) => Promise<{ success: boolean; worktree?: any; error?: string }>;
-  worktreeList: (args: {
+  worktreeCreateBatch: (args: {
     projectPath: string;
-  }) => Promise<{ success: boolean; worktrees?: any[]; error?: string }>;
+    workspaceNames: string[];
+    projectId: string;
+    concurrency?: number;
+    copyFiles?: string[];
+  }) => Promise<{
+    success: boolean;
+    results?: Array<{
+      workspaceName: string;
+      success: boolean;
+      worktree?: any;
+      error?: string;
+    }>;
+    error?: string;
+  }>;
+  worktreeList: (args: {
+    projectPath?: string;
+    projectId?: string;
+    name?: string;
+    branch?: string;
+    includeStatus?: boolean;
+    pageSize?: number;
+    pageToken?: string;
+  }) => Promise<{
+    success: boolean;
+    worktrees?: any[];
+    nextPageToken?: string;
+    error?: string;
+  }>;
   worktreeRemove: (args: {
     projectPath: string;
     worktreeId: string;
     worktreePath?: string;
     branch?: string;
-  }) => Promise<{ success: boolean; error?: string }>;
+    deleteBranch?: boolean;
+    force?: boolean;
+  }) => Promise<{ success: boolean; branchDeleted?: boolean; error?: string }>;
   worktreeStatus: (args: {
     worktreePath: string;
   }) => Promise<{ success: boolean; status?: any; error?: string }>;
@@ -591,6 +1371,132 @@ export interface ElectronAPI {
     projectPath: string;
     worktreeId: string;
   }) => Promise<{ success: boolean; error?: string }>;
+  worktreeApproveMerge: (args: {
+    projectPath: string;
+    worktreeId?: string;
+    worktreePath?: string;
+    branch?: string;
+  }) => Promise<{ success: boolean; branch?: string; commit?: string; error?: string }>;
+  worktreeMergeToBase: (args: {
+    projectPath: string;
+    worktreeId?: string;
+    worktreePath?: string;
+    branch?: string;
+    baseBranch?: string;
+    strategy?: 'fast-forward' | 'merge' | 'squash';
+    commitMessage?: string;
+  }) => Promise<{
+    success: boolean;
+    result?: {
+      merged: boolean;
+      branch: string;
+      baseBranch: string;
+      strategy: 'fast-forward' | 'merge' | 'squash';
+      conflicts: string[];
+      gates: Array<{
+        id: string;
+        kind: 'command' | 'approval';
+        status: 'pending' | 'running' | 'passed' | 'failed';
+        output?: string;
+        durationMs?: number;
+      }>;
+      output?: string;
+    };
+    error?: string;
+  }>;
+  worktreeSync: (args: {
+    worktreePath: string;
+    baseBranch?: string;
+    strategy?: 'rebase' | 'merge';
+    remote?: string;
+  }) => Promise<{
+    success: boolean;
+    result?: {
+      synced: boolean;
+      strategy: 'rebase' | 'merge';
+      upstream: string;
+      conflicts: string[];
+      output?: string;
+    };
+    error?: string;
+  }>;
+  onWorktreeSyncProgress: (
+    listener: (data: {
+      worktreePath: string;
+      step: 'fetch' | 'rebase' | 'merge' | 'done';
+      message: string;
+    }) => void
+  ) => () => void;
+  worktreeRebasePlanGet: (args: { worktreePath: string; base?: string }) => Promise<{
+    success: boolean;
+    plan?: {
+      base: string;
+      entries: Array<{
+        action: 'pick' | 'reword' | 'squash' | 'fixup' | 'drop';
+        sha: string;
+        subject: string;
+        message?: string;
+      }>;
+    };
+    error?: string;
+  }>;
+  worktreeRebasePlanExecute: (args: {
+    worktreePath: string;
+    plan: {
+      base: string;
+      entries: Array<{
+        action: 'pick' | 'reword' | 'squash' | 'fixup' | 'drop';
+        sha: string;
+        subject: string;
+        message?: string;
+      }>;
+    };
+  }) => Promise<{
+    success: boolean;
+    result?: { success: boolean; conflicts: string[]; output?: string };
+    error?: string;
+  }>;
+  worktreeGcDryRun: (args?: { staleAfterDays?: number }) => Promise<{
+    success: boolean;
+    stale?: Array<{
+      workspaceId: string;
+      projectId: string;
+      projectPath: string;
+      name: string;
+      branch: string;
+      path: string;
+      lastActivityAt: string;
+      dirty: boolean;
+    }>;
+    error?: string;
+  }>;
+  worktreeGcSweep: () => Promise<{ success: boolean; events?: any[]; error?: string }>;
+  onWorktreeGcAudit: (
+    listener: (event: {
+      action: 'flagged' | 'removed' | 'skipped' | 'failed';
+      workspaceId: string;
+      path: string;
+      branch: string;
+      lastActivityAt: string;
+      reason?: string;
+      at: string;
+    }) => void
+  ) => () => void;
+  onWorkspaceArtifactDetected: (
+    listener: (event: {
+      worktreePath: string;
+      workspaceId?: string;
+      kind: 'plan' | 'test-report' | 'agent-result' | 'coverage';
+      path: string;
+      metadata: Record<string, unknown>;
+      detectedAt: string;
+    }) => void
+  ) => () => void;
+  worktreePreviewBranchName: (args: {
+    projectPath: string;
+    workspaceName: string;
+    template?: string;
+  }) => Promise<{ success: boolean; branch?: string; valid?: boolean; error?: string }>;
   worktreeGet: (args: {
     worktreeId: string;
   }) => Promise<{ success: boolean; worktree?: any; error?: string }>;
@@ -599,6 +1505,25 @@ export interface ElectronAPI {
     worktrees?: any[];
     error?: string;
   }>;
+  onWorktreeEvent: (
+    listener: (event: {
+      type: 'created' | 'removed' | 'dirty-changed';
+      payload: any;
+    }) => void
+  ) => () => void;
+  onWorktreeMergeGate: (
+    listener: (data: {
+      worktreePath?: string;
+      branch: string;
+      gate: {
+        id: string;
+        kind: 'command' | 'approval';
+        status: 'pending' | 'running' | 'passed' | 'failed';
+        output?: string;
+        durationMs?: number;
+      };
+    }) => void
+  ) => () => void;
 
   // Project management
   openProject: () => Promise<{
@@ -657,7 +1582,7 @@ export interface ElectronAPI {
     status?: {
       enabled: boolean;
       envDisabled: boolean;
-      userOptOut: boolean;
+      userOptIn: boolean;
       hasKeyAndHost: boolean;
     };
     error?: string;
@@ -667,11 +1592,50 @@ export interface ElectronAPI {
     status?: {
       enabled: boolean;
       envDisabled: boolean;
-      userOptOut: boolean;
+      userOptIn: boolean;
       hasKeyAndHost: boolean;
     };
     error?: string;
   }>;
+  showTelemetryPayload: () => Promise<{
+    success: boolean;
+    status?: {
+      enabled: boolean;
+      envDisabled: boolean;
+      userOptIn: boolean;
+      hasKeyAndHost: boolean;
+    };
+    endpoint?: string | null;
+    example?: { event: string; properties: Record<string, any> };
+    recent?: Array<{ sentAt: string; event: string; properties: Record<string, any> }>;
+    error?: string;
+  }>;
+  // Dead letters
+  deadLetterList: () => Promise<{
+    success: boolean;
+    letters?: Array<{
+      id: string;
+      channel: string;
+      payload: any;
+      reason: string;
+      failedAt: string;
+      attempts: number;
+    }>;
+    error?: string;
+  }>;
+  deadLetterRedeliver: (args?: { ids?: string[] }) => Promise<{
+    success: boolean;
+    delivered?: number;
+    remaining?: number;
+    error?: string;
+  }>;
+  deadLetterDiscard: (args?: {
+    ids?: string[];
+  }) => Promise<{ success: boolean; discarded?: number; error?: string }>;
+  notificationsRecent: (args?: {
+    limit?: number;
+  }) => Promise<{ success: boolean; notifications?: AppNotification[]; error?: string }>;
+  onAppNotification: (listener: (notification: AppNotification) => void) => () => void;
 
   // Filesystem
   fsList: (
@@ -803,6 +1767,32 @@ export interface ElectronAPI {
     content: string,
     options?: { reset?: boolean }
   ) => Promise<{ success: boolean; error?: string }>;
+  debugRecentEvents: (args?: {
+    limit?: number;
+    workspaceId?: string;
+    worktreePath?: string;
+    ptyId?: string;
+    kinds?: Array<
+      'session-failed' | 'session-exited' | 'client-dropped' | 'git-error' | 'agent-failed'
+    >;
+  }) => Promise<{
+    success: boolean;
+    events?: Array<{
+      seq: number;
+      at: string;
+      kind:
+        | 'session-failed'
+        | 'session-exited'
+        | 'client-dropped'
+        | 'git-error'
+        | 'agent-failed';
+      severity: 'info' | 'warn' | 'error';
+      message: string;
+      scope: { workspaceId?: string; worktreePath?: string; ptyId?: string };
+      detail?: Record<string, unknown>;
+    }>;
+    error?: string;
+  }>;
 
   // Codex
   codexCheckInstallation: () => Promise<{
@@ -824,7 +1814,12 @@ export interface ElectronAPI {
     conversationId?: string
   ) => Promise<{ success: boolean; error?: string }>;
   codexStopStream: (
-    workspaceId: string
+    workspaceId: string,
+    kill?: {
+      signal?: 'SIGTERM' | 'SIGINT' | 'SIGHUP' | 'SIGQUIT' | 'SIGKILL';
+      graceMs?: number;
+      processGroup?: boolean;
+    }
   ) => Promise<{ success: boolean; stopped?: boolean; error?: string }>;
   codexGetStreamTail: (workspaceId: string) => Promise<{
     success: boolean;
@@ -856,6 +1851,8 @@ export interface ElectronAPI {
       output: string;
       agentId: string;
       conversationId?: string;
+      seq?: number;
+      stream?: 'stdout';
     }) => void
   ) => () => void;
   onCodexStreamError: (
@@ -864,6 +1861,8 @@ export interface ElectronAPI {
       error: string;
       agentId: string;
       conversationId?: string;
+      seq?: number;
+      stream?: 'stderr';
     }) => void
   ) => () => void;
   onCodexStreamComplete: (
@@ -0,0 +1,54 @@
+import { describe, expect, it } from 'vitest';
+import { parseAgentEvents, registerAgentEventParser } from '../../main/services/AgentEventParsers';
+
+describe('parseAgentEvents', () => {
+  it('maps Claude stream-json messages to typed events', () => {
+    const assistant = parseAgentEvents('claude', {
+      type: 'assistant',
+      message: {
+        content: [
+          { type: 'text', text: 'Reading the file' },
+          { type: 'tool_use', id: 't1', name: 'Read', input: { file_path: 'a.ts' } },
+        ],
+      },
+    });
+    expect(assistant).toEqual([
+      { type: 'assistant-text', text: 'Reading the file' },
+      { type: 'tool-call', id: 't1', name: 'Read', input: { file_path: 'a.ts' } },
+    ]);
+
+    const result = parseAgentEvents('claude', {
+      type: 'result',
+      result: 'done',
+      usage: { input_tokens: 10, output_tokens: 4 },
+      total_cost_usd: 0.01,
+    });
+    expect(result).toEqual([
+      { type: 'result', text: 'done', isError: false },
+      { type: 'usage', inputTokens: 10, outputTokens: 4, costUsd: 0.01 },
+    ]);
+  });
+
+  it('maps Codex JSONL items and turn usage', () => {
+    expect(
+      parseAgentEvents('codex', {
+        type: 'item.completed',
+        item: { id: 'i2', type: 'command_execution', aggregated_output: 'ok', exit_code: 1 },
+      })
+    ).toEqual([{ type: 'tool-result', id: 'i2', content: 'ok', isError: true }]);
+    expect(
+      parseAgentEvents('codex', {
+        type: 'turn.completed',
+        usage: { input_tokens: 3, output_tokens: 2 },
+      })
+    ).toEqual([{ type: 'usage', inputTokens: 3, outputTokens: 2, costUsd: undefined }]);
+  });
+
+  it('ignores unknown providers and uses registered parsers', () => {
+    expect(parseAgentEvents('other', { type: 'x' })).toEqual([]);
+    registerAgentEventParser('other', () => [{ type: 'assistant-text', text: 'hi' }]);
+    expect(parseAgentEvents('other', { type: 'x' })).toEqual([
+      { type: 'assistant-text', text: 'hi' },
+    ]);
+  });
+});
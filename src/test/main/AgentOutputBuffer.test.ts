@@ -0,0 +1,48 @@
+import { describe, expect, it } from 'vitest';
+import { AgentOutputBuffer } from '../../main/services/AgentOutputBuffer';
+
+describe('AgentOutputBuffer', () => {
+  it('pages through buffered output by offset', () => {
+    const buffer = new AgentOutputBuffer();
+    ['a', 'b', 'c', 'd'].forEach((t) => buffer.append(t));
+
+    const first = buffer.read(0, 3);
+    expect(first.chunks.map((c) => c.text)).toEqual(['a', 'b', 'c']);
+    expect(first.nextOffset).toBe(3);
+    expect(first.done).toBe(false);
+
+    const rest = buffer.read(first.nextOffset, 3);
+    expect(rest.chunks.map((c) => c.text)).toEqual(['d']);
+    expect(rest.done).toBe(true);
+  });
+
+  it('evicts the oldest chunks once over the byte budget', () => {
+    const buffer = new AgentOutputBuffer();
+    const big = 'x'.repeat(1024 * 1024);
+    buffer.append(big);
+    buffer.append(big);
+    buffer.append('tail');
+
+    const page = buffer.read(0);
+    expect(page.firstSeq).toBe(1);
+    expect(page.chunks.map((c) => c.seq)).toEqual([1, 2]);
+  });
+
+  it('continues seqs across runs and keeps stream labels', () => {
+    const first = new AgentOutputBuffer();
+    first.append('out');
+    first.append('warn', 'stderr');
+
+    const next = new AgentOutputBuffer(first.nextSequence());
+    expect(next.append('again')).toBe(2);
+    expect(next.append('jump', 'stdout', 10)).toBe(10);
+    expect(next.append('stale', 'stdout', 4)).toBe(11);
+
+    const page = next.read(3);
+    expect(page.chunks.map((c) => [c.seq, c.stream, c.text])).toEqual([
+      [10, 'stdout', 'jump'],
+      [11, 'stdout', 'stale'],
+    ]);
+    expect(first.read(0).chunks[1].stream).toBe('stderr');
+  });
+});
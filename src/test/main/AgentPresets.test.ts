@@ -0,0 +1,57 @@
+import { mkdtempSync, writeFileSync } from 'fs';
+import { tmpdir } from 'os';
+import { join } from 'path';
+import { describe, expect, it, vi } from 'vitest';
+
+const dir = mkdtempSync(join(tmpdir(), 'emdash-presets-'));
+const promptFile = join(dir, 'review.md');
+writeFileSync(promptFile, 'Review only; do not edit files.');
+
+const presets = [
+  {
+    name: 'code-review',
+    providerId: 'claude',
+    args: ['--max-turns', '5'],
+    model: 'opus',
+    systemPromptFile: promptFile,
+    envProfile: 'work',
+  },
+  {
+    name: 'codex-prompted',
+    providerId: 'codex',
+    args: [],
+    model: '',
+    systemPromptFile: promptFile,
+    envProfile: '',
+  },
+];
+
+vi.mock('../../main/settings', () => ({
+  getAppSettings: () => ({ agentPresets: { presets } }),
+}));
+
+// eslint-disable-next-line import/first
+import { findAgentPreset, presetCliArgs } from '../../main/services/AgentPresets';
+
+describe('AgentPresets', () => {
+  it('looks presets up by name and rejects unknown ones', () => {
+    expect(findAgentPreset(undefined)).toBeNull();
+    expect(findAgentPreset(' code-review ')?.providerId).toBe('claude');
+    expect(() => findAgentPreset('nope')).toThrow('Unknown agent preset: nope');
+  });
+
+  it('builds CLI args with the model and system prompt file contents', () => {
+    expect(presetCliArgs(presets[0])).toEqual([
+      '--max-turns',
+      '5',
+      '--model',
+      'opus',
+      '--append-system-prompt',
+      'Review only; do not edit files.',
+    ]);
+  });
+
+  it('rejects system prompt files for providers without a flag for them', () => {
+    expect(() => presetCliArgs(presets[1])).toThrow('only supported for Claude');
+  });
+});
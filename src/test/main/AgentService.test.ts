@@ -0,0 +1,69 @@
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+
+const userData = fs.mkdtempSync(path.join(os.tmpdir(), 'emdash-agent-test-'));
+
+vi.mock('electron', () => ({
+  app: { getPath: () => userData, getLocale: () => 'en', on: () => {} },
+  BrowserWindow: { getAllWindows: () => [] },
+}));
+
+vi.mock('../../main/services/DatabaseService', () => ({
+  databaseService: { getProjects: async () => [], getWorkspaces: async () => [] },
+}));
+
+// eslint-disable-next-line import/first
+import { AgentService } from '../../main/services/AgentService';
+
+const sleep = (ms: number) => new Promise((resolve) => setTimeout(resolve, ms));
+
+describe.skipIf(process.platform === 'win32')('AgentService', () => {
+  let binDir: string;
+  let workspace: string;
+  let originalPath: string | undefined;
+
+  beforeEach(() => {
+    // Stand-in for the claude CLI that runs until it is signalled
+    binDir = fs.mkdtempSync(path.join(os.tmpdir(), 'emdash-agent-bin-'));
+    fs.writeFileSync(path.join(binDir, 'claude'), '#!/bin/sh\nexec sleep 30\n', { mode: 0o755 });
+    workspace = fs.mkdtempSync(path.join(os.tmpdir(), 'emdash-agent-ws-'));
+    originalPath = process.env.PATH;
+    process.env.PATH = `${binDir}${path.delimiter}${originalPath ?? ''}`;
+  });
+
+  afterEach(() => {
+    process.env.PATH = originalPath;
+    fs.rmSync(binDir, { recursive: true, force: true });
+    fs.rmSync(workspace, { recursive: true, force: true });
+  });
+
+  it('keeps the replacing run registered when the replaced run exits', async () => {
+    const service = new AgentService();
+    const completes: any[] = [];
+    service.on('agent:complete', (data) => completes.push(data));
+    const start = (message: string) =>
+      service.startStream({
+        providerId: 'claude',
+        workspaceId: 'ws',
+        worktreePath: workspace,
+        message,
+      });
+
+    await start('first');
+    expect(service.isActive('ws')).toBe(true);
+    await start('second');
+    // Give the replaced child time to exit and fire its close handler
+    await sleep(500);
+
+    expect(completes).toEqual([]);
+    expect(service.isActive('ws')).toBe(true);
+    expect(service.listAgents('ws').map((a) => a.running)).toEqual([true]);
+
+    await service.stopStream('claude', 'ws', { signal: 'SIGKILL' });
+    await sleep(200);
+    expect(service.isActive('ws')).toBe(false);
+    expect(completes).toHaveLength(1);
+  });
+});
@@ -0,0 +1,37 @@
+import { describe, expect, it } from 'vitest';
+import {
+  evaluateDiffGuardrails,
+  guardrailsEnabled,
+  DiffGuardrailLimits,
+} from '../../main/services/DiffGuardrails';
+
+const limits: DiffGuardrailLimits = { warnLines: 100, warnFiles: 10, stopLines: 500, stopFiles: 0 };
+
+describe('evaluateDiffGuardrails', () => {
+  it('stays ok below every threshold', () => {
+    expect(evaluateDiffGuardrails({ files: 2, insertions: 30, deletions: 10 }, limits)).toBe('ok');
+  });
+
+  it('warns once changed lines or files reach the warn threshold', () => {
+    expect(evaluateDiffGuardrails({ files: 1, insertions: 60, deletions: 40 }, limits)).toBe(
+      'warn'
+    );
+    expect(evaluateDiffGuardrails({ files: 10, insertions: 1, deletions: 0 }, limits)).toBe('warn');
+  });
+
+  it('stops at the hard limit and ignores disabled thresholds', () => {
+    expect(evaluateDiffGuardrails({ files: 3, insertions: 400, deletions: 100 }, limits)).toBe(
+      'stop'
+    );
+    expect(evaluateDiffGuardrails({ files: 5000, insertions: 0, deletions: 0 }, limits)).toBe(
+      'warn'
+    );
+  });
+
+  it('treats all-zero limits as disabled', () => {
+    expect(guardrailsEnabled({ warnLines: 0, warnFiles: 0, stopLines: 0, stopFiles: 0 })).toBe(
+      false
+    );
+    expect(guardrailsEnabled(limits)).toBe(true);
+  });
+});
@@ -0,0 +1,53 @@
+import { describe, expect, it } from 'vitest';
+import { buildDiffSummary } from '../../main/services/DiffSummary';
+
+describe('buildDiffSummary', () => {
+  it('classifies changes and attaches line counts, including renames', () => {
+    const nameStatus = [
+      'M\tsrc/a.ts',
+      'A\tsrc/b.ts',
+      'D\told.txt',
+      'R087\tsrc/lib/x.ts\tsrc/util/x.ts',
+    ].join('\n');
+    const numstat = [
+      '3\t1\tsrc/a.ts',
+      '10\t0\tsrc/b.ts',
+      '0\t5\told.txt',
+      '2\t2\tsrc/{lib => util}/x.ts',
+    ].join('\n');
+    const summary = buildDiffSummary('abc123', nameStatus, numstat);
+
+    expect(summary.files.find((f) => f.path === 'src/util/x.ts')).toEqual({
+      path: 'src/util/x.ts',
+      previousPath: 'src/lib/x.ts',
+      status: 'renamed',
+      insertions: 2,
+      deletions: 2,
+    });
+    expect(summary).toMatchObject({
+      baseRef: 'abc123',
+      added: 1,
+      modified: 1,
+      deleted: 1,
+      renamed: 1,
+      insertions: 15,
+      deletions: 8,
+    });
+  });
+
+  it('adds untracked files and skips the codex stream log', () => {
+    const summary = buildDiffSummary(
+      'abc123',
+      'M\tcodex-stream.log\nM\timg.png',
+      '-\t-\timg.png',
+      [
+        { path: 'notes.md', lines: 4 },
+        { path: 'codex-stream.log', lines: 100 },
+      ]
+    );
+    expect(summary.files).toEqual([
+      { path: 'img.png', status: 'modified', insertions: 0, deletions: 0, binary: true },
+      { path: 'notes.md', status: 'added', insertions: 4, deletions: 0 },
+    ]);
+  });
+});
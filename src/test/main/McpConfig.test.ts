@@ -0,0 +1,57 @@
+import { describe, expect, it, vi } from 'vitest';
+
+const servers = [
+  {
+    name: 'github',
+    command: 'github-mcp',
+    args: ['--stdio'],
+    env: { GITHUB_HOST: 'github.com' },
+    providers: [],
+    workspaces: [],
+  },
+  {
+    name: 'db',
+    command: 'pg-mcp',
+    args: [],
+    env: {},
+    providers: ['codex'],
+    workspaces: ['ws-1'],
+  },
+];
+
+vi.mock('../../main/settings', () => ({
+  getAppSettings: () => ({ mcp: { servers } }),
+}));
+
+// eslint-disable-next-line import/first
+import { claudeMcpArgs, codexMcpArgs, mcpServersFor } from '../../main/services/McpConfig';
+
+describe('McpConfig', () => {
+  it('filters servers by provider and workspace', () => {
+    expect(mcpServersFor('claude', 'ws-1').map((s) => s.name)).toEqual(['github']);
+    expect(mcpServersFor('codex', 'ws-1').map((s) => s.name)).toEqual(['github', 'db']);
+    expect(mcpServersFor('codex', 'ws-2').map((s) => s.name)).toEqual(['github']);
+  });
+
+  it('renders Claude --mcp-config JSON', () => {
+    const args = claudeMcpArgs([servers[0]]);
+    expect(args[0]).toBe('--mcp-config');
+    expect(JSON.parse(args[1])).toEqual({
+      mcpServers: {
+        github: { command: 'github-mcp', args: ['--stdio'], env: { GITHUB_HOST: 'github.com' } },
+      },
+    });
+    expect(claudeMcpArgs([])).toEqual([]);
+  });
+
+  it('renders Codex -c overrides as TOML values', () => {
+    expect(codexMcpArgs([servers[0]])).toEqual([
+      '-c',
+      'mcp_servers.github.command="github-mcp"',
+      '-c',
+      'mcp_servers.github.args=["--stdio"]',
+      '-c',
+      'mcp_servers.github.env={ GITHUB_HOST="github.com" }',
+    ]);
+  });
+});
@@ -0,0 +1,95 @@
+import { execFileSync } from 'child_process';
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+
+const gates: any[] = [];
+
+vi.mock('../../main/settings', () => ({
+  getAppSettings: () => ({ mergeGates: { gates } }),
+}));
+
+// eslint-disable-next-line import/first
+import { MergeGateService } from '../../main/services/MergeGateService';
+
+function git(cwd: string, ...args: string[]): string {
+  return execFileSync('git', args, { cwd, encoding: 'utf8' }).trim();
+}
+
+function commitFile(cwd: string, file: string, content: string) {
+  fs.writeFileSync(path.join(cwd, file), content);
+  git(cwd, 'add', file);
+  git(cwd, 'commit', '-q', '-m', `update ${file}`);
+  return git(cwd, 'rev-parse', 'HEAD');
+}
+
+describe('MergeGateService', () => {
+  let tempDir: string;
+  let commit: string;
+
+  beforeEach(() => {
+    tempDir = fs.mkdtempSync(path.join(os.tmpdir(), 'merge-gate-test-'));
+    git(tempDir, 'init', '-q', '-b', 'agent/demo');
+    git(tempDir, 'config', 'user.email', 'test@example.com');
+    git(tempDir, 'config', 'user.name', 'Test');
+    commit = commitFile(tempDir, 'a.txt', 'one\n');
+    gates.length = 0;
+  });
+
+  afterEach(() => {
+    fs.rmSync(tempDir, { recursive: true, force: true });
+  });
+
+  it('passes when no gates are configured', async () => {
+    const service = new MergeGateService();
+    const run = await service.evaluate(tempDir, { branch: 'agent/demo', commit });
+    expect(run.passed).toBe(true);
+    expect(run.gates).toEqual([]);
+  });
+
+  it('stops at the first failing gate and leaves the rest pending', async () => {
+    gates.push(
+      { id: 'lint', kind: 'command', command: 'node -e "process.exit(0)"' },
+      { id: 'tests', kind: 'command', command: 'node -e "process.exit(3)"' },
+      { id: 'review', kind: 'approval' }
+    );
+    const service = new MergeGateService();
+    const events: any[] = [];
+    service.on('gate:status', (e) => events.push(e));
+
+    await service.approve(tempDir, 'agent/demo');
+    const run = await service.evaluate(tempDir, { branch: 'agent/demo', commit });
+
+    expect(run.passed).toBe(false);
+    expect(run.gates.map((g) => g.status)).toEqual(['passed', 'failed', 'pending']);
+    expect(events.some((e) => e.gate.id === 'tests' && e.gate.status === 'running')).toBe(true);
+  });
+
+  it('requires a recorded approval of the commit being merged', async () => {
+    gates.push({ id: 'review', kind: 'approval' });
+    const service = new MergeGateService();
+
+    const blocked = await service.evaluate(tempDir, { branch: 'agent/demo', commit });
+    expect(blocked.passed).toBe(false);
+    expect(blocked.gates[0].status).toBe('failed');
+
+    expect(await service.approve(tempDir, 'agent/demo')).toBe(commit);
+    const approved = await service.evaluate(tempDir, { branch: 'agent/demo', commit });
+    expect(approved.passed).toBe(true);
+
+    const next = commitFile(tempDir, 'b.txt', 'two\n');
+    const stale = await service.evaluate(tempDir, { branch: 'agent/demo', commit: next });
+    expect(stale.passed).toBe(false);
+  });
+
+  it('fails command gates when the worktree differs from the merged commit', async () => {
+    gates.push({ id: 'lint', kind: 'command', command: 'node -e "process.exit(0)"' });
+    const service = new MergeGateService();
+    fs.writeFileSync(path.join(tempDir, 'a.txt'), 'uncommitted\n');
+
+    const run = await service.evaluate(tempDir, { branch: 'agent/demo', commit });
+    expect(run.passed).toBe(false);
+    expect(run.gates[0].output).toContain('uncommitted changes');
+  });
+});
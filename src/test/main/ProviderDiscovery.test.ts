@@ -0,0 +1,35 @@
+import { chmodSync, mkdirSync, mkdtempSync, writeFileSync } from 'fs';
+import { tmpdir } from 'os';
+import { join } from 'path';
+import { describe, expect, it, vi } from 'vitest';
+
+vi.mock('../../main/settings', () => ({
+  getAppSettings: () => ({
+    agentProviders: { allowed: ['claude'], defaultArgs: {}, requiredEnv: {} },
+  }),
+}));
+
+// eslint-disable-next-line import/first
+import { findOnPath } from '../../main/services/ProviderDiscovery';
+
+describe.skipIf(process.platform === 'win32')('findOnPath', () => {
+  it('returns the first executable match in PATH order', () => {
+    const root = mkdtempSync(join(tmpdir(), 'emdash-discover-'));
+    const first = join(root, 'a');
+    const second = join(root, 'b');
+    mkdirSync(first);
+    mkdirSync(second);
+    writeFileSync(join(first, 'aider'), '#!/bin/sh\n');
+    chmodSync(join(first, 'aider'), 0o644); // not executable
+    writeFileSync(join(second, 'aider'), '#!/bin/sh\n');
+    chmodSync(join(second, 'aider'), 0o755);
+
+    expect(findOnPath('aider', `${first}:${second}`, 'linux')).toBe(join(second, 'aider'));
+  });
+
+  it('ignores directories and missing entries', () => {
+    const root = mkdtempSync(join(tmpdir(), 'emdash-discover-'));
+    mkdirSync(join(root, 'gemini'));
+    expect(findOnPath('gemini', `${root}::/nonexistent`, 'linux')).toBeNull();
+  });
+});
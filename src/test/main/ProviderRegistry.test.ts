@@ -0,0 +1,43 @@
+import { describe, expect, it, vi } from 'vitest';
+
+const agentProviders = {
+  allowed: ['claude'],
+  defaultArgs: { claude: ['--model', 'sonnet'] } as Record<string, string[]>,
+  requiredEnv: { claude: ['ANTHROPIC_API_KEY'] } as Record<string, string[]>,
+};
+
+vi.mock('../../main/settings', () => ({
+  getAppSettings: () => ({ agentProviders }),
+}));
+
+// eslint-disable-next-line import/first
+import {
+  assertRequiredEnv,
+  listAllowedProviders,
+  resolveProvider,
+} from '../../main/services/ProviderRegistry';
+
+describe('ProviderRegistry', () => {
+  it('resolves allowed providers with their built-in binary and configured defaults', () => {
+    expect(resolveProvider('claude')).toEqual({
+      id: 'claude',
+      command: 'claude',
+      defaultArgs: ['--model', 'sonnet'],
+      requiredEnv: ['ANTHROPIC_API_KEY'],
+    });
+    expect(listAllowedProviders().map((p) => p.id)).toEqual(['claude']);
+  });
+
+  it('rejects unknown and disallowed providers', () => {
+    expect(() => resolveProvider('/bin/sh')).toThrow('Unknown agent provider: /bin/sh');
+    expect(() => resolveProvider('codex')).toThrow('Agent provider not allowed: codex');
+  });
+
+  it('reports missing required env', () => {
+    const spec = resolveProvider('claude');
+    expect(() => assertRequiredEnv(spec, {})).toThrow(
+      'Missing required env for claude: ANTHROPIC_API_KEY'
+    );
+    expect(() => assertRequiredEnv(spec, { ANTHROPIC_API_KEY: 'k' })).not.toThrow();
+  });
+});
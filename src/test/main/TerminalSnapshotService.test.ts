@@ -71,4 +71,21 @@ describe('TerminalSnapshotService', () => {
     const loaded = await service.getSnapshot('temp');
     expect(loaded).toBeNull();
   });
+
+  it('exports transcripts with or without ANSI sequences', async () => {
+    const payload: TerminalSnapshotPayload = {
+      version: 1,
+      createdAt: new Date().toISOString(),
+      cols: 80,
+      rows: 24,
+      data: '\x1b]0;title\x07\x1b[32m$ ls\x1b[0m   \r\nfile.txt\r\n',
+    };
+
+    await service.saveSnapshot('transcript', payload);
+    const plain = await service.getTranscript('transcript');
+    expect(plain?.text).toBe('$ ls\nfile.txt\n');
+    const ansi = await service.getTranscript('transcript', 'ansi');
+    expect(ansi?.text).toBe(payload.data);
+    expect(await service.getTranscript('missing')).toBeNull();
+  });
 });
@@ -0,0 +1,250 @@
+import { execFileSync } from 'child_process';
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+
+vi.mock('electron', () => ({
+  app: { getPath: () => os.tmpdir(), getLocale: () => 'en' },
+}));
+
+vi.mock('../../main/settings', () => ({
+  getAppSettings: () => ({ mergeGates: { gates: [] } }),
+}));
+
+vi.mock('../../main/services/DatabaseService', () => ({
+  databaseService: { getProjects: async () => [] },
+}));
+
+// eslint-disable-next-line import/first
+import { WorktreeService } from '../../main/services/WorktreeService';
+
+function git(cwd: string, ...args: string[]): string {
+  return execFileSync('git', args, { cwd, encoding: 'utf8' }).trim();
+}
+
+function commitFile(cwd: string, file: string, content: string, message: string) {
+  fs.writeFileSync(path.join(cwd, file), content);
+  git(cwd, 'add', file);
+  git(cwd, 'commit', '-q', '-m', message);
+}
+
+describe('WorktreeService.mergeToBase', () => {
+  let root: string;
+  let repo: string;
+
+  beforeEach(() => {
+    root = fs.mkdtempSync(path.join(os.tmpdir(), 'emdash-merge-test-'));
+    repo = path.join(root, 'repo');
+    fs.mkdirSync(repo);
+    git(repo, 'init', '-q', '-b', 'main');
+    git(repo, 'config', 'user.email', 'test@example.com');
+    git(repo, 'config', 'user.name', 'Test');
+    commitFile(repo, 'a.txt', 'base\n', 'initial');
+    git(repo, 'checkout', '-q', '-b', 'feature');
+    commitFile(repo, 'b.txt', 'feature\n', 'feature work');
+    git(repo, 'checkout', '-q', 'main');
+  });
+
+  afterEach(() => {
+    fs.rmSync(root, { recursive: true, force: true });
+  });
+
+  it('merges in a temporary worktree and leaves the checkout on its own branch', async () => {
+    git(repo, 'checkout', '-q', '-b', 'work');
+    const service = new WorktreeService();
+    const result = await service.mergeToBase(repo, { branch: 'feature', baseBranch: 'main' });
+
+    expect(result.merged).toBe(true);
+    expect(git(repo, 'rev-parse', '--abbrev-ref', 'HEAD')).toBe('work');
+    expect(git(repo, 'show', 'main:b.txt')).toBe('feature');
+    expect(git(repo, 'worktree', 'list', '--porcelain').match(/^worktree /gm)).toHaveLength(1);
+  });
+
+  it('merges in place when the base branch is checked out and clean', async () => {
+    const service = new WorktreeService();
+    const result = await service.mergeToBase(repo, {
+      branch: 'feature',
+      baseBranch: 'main',
+      strategy: 'fast-forward',
+    });
+
+    expect(result.merged).toBe(true);
+    expect(git(repo, 'rev-parse', '--abbrev-ref', 'HEAD')).toBe('main');
+    expect(git(repo, 'rev-parse', 'main')).toBe(git(repo, 'rev-parse', 'feature'));
+    expect(fs.readFileSync(path.join(repo, 'b.txt'), 'utf8')).toBe('feature\n');
+  });
+
+  it('merges where the base branch is checked out in another worktree', async () => {
+    git(repo, 'checkout', '-q', '-b', 'work');
+    const other = path.join(root, 'main-wt');
+    git(repo, 'worktree', 'add', '-q', other, 'main');
+    const service = new WorktreeService();
+    const result = await service.mergeToBase(repo, { branch: 'feature', baseBranch: 'main' });
+
+    expect(result.merged).toBe(true);
+    expect(git(repo, 'rev-parse', '--abbrev-ref', 'HEAD')).toBe('work');
+    expect(fs.readFileSync(path.join(other, 'b.txt'), 'utf8')).toBe('feature\n');
+  });
+
+  it('reports conflicts without moving the base branch or leaving a worktree behind', async () => {
+    commitFile(repo, 'b.txt', 'main\n', 'conflicting work');
+    const before = git(repo, 'rev-parse', 'main');
+    git(repo, 'checkout', '-q', '-b', 'work');
+    const service = new WorktreeService();
+    const result = await service.mergeToBase(repo, { branch: 'feature', baseBranch: 'main' });
+
+    expect(result.merged).toBe(false);
+    expect(result.conflicts).toEqual(['b.txt']);
+    expect(git(repo, 'rev-parse', 'main')).toBe(before);
+    expect(git(repo, 'worktree', 'list', '--porcelain').match(/^worktree /gm)).toHaveLength(1);
+  });
+
+  it('rejects unknown strategies', async () => {
+    const service = new WorktreeService();
+    await expect(
+      service.mergeToBase(repo, {
+        branch: 'feature',
+        baseBranch: 'main',
+        strategy: 'rebase' as any,
+      })
+    ).rejects.toThrow('Unknown merge strategy: rebase');
+    expect(git(repo, 'rev-parse', 'main')).not.toBe(git(repo, 'rev-parse', 'feature'));
+  });
+});
+
+describe('WorktreeService.executeRebasePlan', () => {
+  let repo: string;
+  let base: string;
+
+  beforeEach(() => {
+    repo = fs.mkdtempSync(path.join(os.tmpdir(), 'emdash-rebase-test-'));
+    git(repo, 'init', '-q', '-b', 'main');
+    git(repo, 'config', 'user.email', 'test@example.com');
+    git(repo, 'config', 'user.name', 'Test');
+    commitFile(repo, 'a.txt', 'base\n', 'initial');
+    base = git(repo, 'rev-parse', 'HEAD');
+    commitFile(repo, 'b.txt', 'one\n', 'first');
+    commitFile(repo, 'c.txt', 'two\n', 'second');
+  });
+
+  afterEach(() => {
+    fs.rmSync(repo, { recursive: true, force: true });
+  });
+
+  it('runs a plan that covers every commit since the base', async () => {
+    const service = new WorktreeService();
+    const plan = await service.getRebasePlan(repo, base);
+    plan.entries[1].action = 'squash';
+    plan.entries[1].message = 'combined';
+
+    const result = await service.executeRebasePlan(repo, plan);
+    expect(result.success).toBe(true);
+    expect(git(repo, 'log', '--format=%s', `${base}..HEAD`)).toBe('combined');
+  });
+
+  it('rejects plans that leave commits out', async () => {
+    const service = new WorktreeService();
+    const plan = await service.getRebasePlan(repo, base);
+    const head = git(repo, 'rev-parse', 'HEAD');
+
+    await expect(
+      service.executeRebasePlan(repo, { ...plan, entries: plan.entries.slice(0, 1) })
+    ).rejects.toThrow('plan lists 1 of 2 commits');
+    expect(git(repo, 'rev-parse', 'HEAD')).toBe(head);
+  });
+
+  it('leaves merge commits out of the plan and rebases the rest linearly', async () => {
+    git(repo, 'checkout', '-q', '-b', 'side', base);
+    commitFile(repo, 'd.txt', 'side\n', 'side');
+    git(repo, 'checkout', '-q', 'main');
+    git(repo, 'merge', '-q', '--no-ff', '-m', 'merge side', 'side');
+    const service = new WorktreeService();
+    const plan = await service.getRebasePlan(repo, base);
+    expect(plan.entries.map((e) => e.subject).sort()).toEqual(['first', 'second', 'side']);
+
+    const result = await service.executeRebasePlan(repo, plan);
+    expect(result.success).toBe(true);
+    expect(git(repo, 'log', '--merges', '--format=%s', `${base}..HEAD`)).toBe('');
+    expect(git(repo, 'log', '--format=%s', `${base}..HEAD`).split('\n')).toHaveLength(3);
+  });
+
+  it('rewords commits when the temp directory path contains shell metacharacters', async () => {
+    const tmp = fs.mkdtempSync(path.join(os.tmpdir(), `emdash it's $(x) `));
+    const originalTmp = process.env.TMPDIR;
+    process.env.TMPDIR = tmp;
+    try {
+      const service = new WorktreeService();
+      const plan = await service.getRebasePlan(repo, base);
+      plan.entries[0].action = 'reword';
+      plan.entries[0].message = 'renamed';
+
+      const result = await service.executeRebasePlan(repo, plan);
+      expect(result.success).toBe(true);
+      expect(git(repo, 'log', '--format=%s', `${base}..HEAD`)).toBe('second\nrenamed');
+    } finally {
+      if (originalTmp === undefined) delete process.env.TMPDIR;
+      else process.env.TMPDIR = originalTmp;
+      fs.rmSync(tmp, { recursive: true, force: true });
+    }
+  });
+
+  it('rejects bases that git would parse as options', async () => {
+    const service = new WorktreeService();
+    const plan = await service.getRebasePlan(repo, base);
+
+    await expect(service.executeRebasePlan(repo, { ...plan, base: '--root' })).rejects.toThrow(
+      'invalid base --root'
+    );
+  });
+});
+
+describe('WorktreeService.removeWorktree', () => {
+  let repo: string;
+
+  beforeEach(() => {
+    repo = fs.mkdtempSync(path.join(os.tmpdir(), 'emdash-remove-test-'));
+    git(repo, 'init', '-q', '-b', 'main');
+    git(repo, 'config', 'user.email', 'test@example.com');
+    git(repo, 'config', 'user.name', 'Test');
+    commitFile(repo, 'a.txt', 'base\n', 'initial');
+  });
+
+  afterEach(() => {
+    fs.rmSync(repo, { recursive: true, force: true });
+  });
+
+  it('deletes a branch merged into the base even when the checkout is elsewhere', async () => {
+    const wtPath = path.join(repo, '..', `${path.basename(repo)}-wt`);
+    git(repo, 'worktree', 'add', '-q', '-b', 'feature', wtPath);
+    commitFile(wtPath, 'b.txt', 'feature\n', 'feature');
+    git(repo, 'merge', '-q', '--no-ff', '-m', 'merge feature', 'feature');
+    // `branch -d` would compare against this HEAD, which lacks the feature commit
+    git(repo, 'checkout', '-q', '-b', 'other', 'HEAD~1');
+    const service = new WorktreeService();
+    const removed: any[] = [];
+    service.on('worktree:removed', (data) => removed.push(data));
+
+    const result = await service.removeWorktree(repo, 'wt', wtPath, 'feature', { force: false });
+    expect(result.branchDeleted).toBe(true);
+    expect(git(repo, 'branch', '--list', 'feature')).toBe('');
+    expect(removed).toHaveLength(1);
+    expect(removed[0].branch).toBe('feature');
+    expect(removed[0].branchDeleted).toBe(true);
+  });
+
+  it('keeps the branch and says so when deletion is not requested', async () => {
+    const wtPath = path.join(repo, '..', `${path.basename(repo)}-wt`);
+    git(repo, 'worktree', 'add', '-q', '-b', 'feature', wtPath);
+    const service = new WorktreeService();
+    const removed: any[] = [];
+    service.on('worktree:removed', (data) => removed.push(data));
+
+    const result = await service.removeWorktree(repo, 'wt', wtPath, 'feature', {
+      deleteBranch: false,
+    });
+    expect(result.branchDeleted).toBe(false);
+    expect(git(repo, 'branch', '--list', 'feature')).not.toBe('');
+    expect(removed[0].branchDeleted).toBe(false);
+  });
+});
@@ -0,0 +1,26 @@
+import { describe, expect, it } from 'vitest';
+import { extractTicket, renderBranchTemplate, slugify } from '../../main/lib/branchNaming';
+
+describe('branchNaming', () => {
+  it('slugifies with the selected strategy', () => {
+    expect(slugify('Fix Café login!')).toBe('fix-caf-login');
+    expect(slugify('Fix Café login!', 'transliterate')).toBe('fix-cafe-login');
+    expect(slugify('修复 登录', 'unicode')).toBe('修复-登录');
+    expect(slugify('Fix login', 'does-not-exist')).toBe('fix-login');
+  });
+
+  it('renders template variables and tidies empty ones', () => {
+    const vars = { slug: 'fix-login', timestamp: '123', user: 'alice' };
+    expect(renderBranchTemplate('feat/{user}/{ticket}-{slug}', vars)).toBe('feat/alice/fix-login');
+    expect(renderBranchTemplate('feat/{user}/{ticket}-{slug}', { ...vars, ticket: 'abc-1' })).toBe(
+      'feat/alice/abc-1-fix-login'
+    );
+    expect(renderBranchTemplate('agent/{slug}-{timestamp}', vars)).toBe('agent/fix-login-123');
+  });
+
+  it('extracts ticket keys from workspace names', () => {
+    expect(extractTicket('ABC-123 fix login')).toBe('ABC-123');
+    expect(extractTicket('fix #42')).toBe('42');
+    expect(extractTicket('no ticket')).toBeUndefined();
+  });
+});
@@ -0,0 +1,52 @@
+import { describe, expect, it } from 'vitest';
+import { MetricsRegistry } from '../../main/lib/metrics';
+
+describe('MetricsRegistry', () => {
+  it('renders labelled counters in exposition format', () => {
+    const registry = new MetricsRegistry();
+    const starts = registry.counter('emdash_agent_starts_total', 'Agent runs started.');
+    starts.inc({ provider: 'claude' });
+    starts.inc({ provider: 'claude' });
+    starts.inc({ provider: 'codex' }, 3);
+    starts.inc({ provider: 'codex' }, -1);
+
+    expect(registry.render()).toBe(
+      [
+        '# HELP emdash_agent_starts_total Agent runs started.',
+        '# TYPE emdash_agent_starts_total counter',
+        'emdash_agent_starts_total{provider="claude"} 2',
+        'emdash_agent_starts_total{provider="codex"} 3',
+        '',
+      ].join('\n')
+    );
+  });
+
+  it('accumulates histogram buckets, sum and count', () => {
+    const registry = new MetricsRegistry();
+    const duration = registry.histogram('run_seconds', 'Run time.', [1, 10]);
+    duration.observe({ provider: 'claude' }, 0.5);
+    duration.observe({ provider: 'claude' }, 5);
+    duration.observe({ provider: 'claude' }, 50);
+
+    const text = registry.render();
+    expect(text).toContain('run_seconds_bucket{provider="claude",le="1"} 1');
+    expect(text).toContain('run_seconds_bucket{provider="claude",le="10"} 2');
+    expect(text).toContain('run_seconds_bucket{provider="claude",le="+Inf"} 3');
+    expect(text).toContain('run_seconds_sum{provider="claude"} 55.5');
+    expect(text).toContain('run_seconds_count{provider="claude"} 3');
+  });
+
+  it('renders the latest gauge value per label set', () => {
+    const registry = new MetricsRegistry();
+    const clients = registry.gauge('emdash_pty_clients', 'Renderers attached to PTYs.');
+    clients.set({}, 3);
+    clients.set({}, 1);
+    expect(registry.render()).toContain('# TYPE emdash_pty_clients gauge\nemdash_pty_clients 1\n');
+  });
+
+  it('escapes label values', () => {
+    const registry = new MetricsRegistry();
+    registry.counter('c', 'h').inc({ name: 'a "b"\\\n' });
+    expect(registry.render()).toContain('c{name="a \\"b\\"\\\\\\n"} 1');
+  });
+});
@@ -0,0 +1,19 @@
+import { describe, expect, it } from 'vitest';
+import { scanOscTitles } from '../../main/lib/oscTitle';
+
+describe('scanOscTitles', () => {
+  it('extracts OSC 0 and 2 titles with BEL or ST terminators', () => {
+    const out = scanOscTitles('', 'a\x1b]0;first\x07b\x1b]2;second\x1b\\c\x1b]1;icon\x07');
+    expect(out.titles).toEqual(['first', 'second']);
+    expect(out.pending).toBe('');
+  });
+
+  it('carries unterminated sequences across chunks', () => {
+    const first = scanOscTitles('', 'prompt$ \x1b]2;vim ma');
+    expect(first.titles).toEqual([]);
+    expect(first.pending).toBe('\x1b]2;vim ma');
+    const second = scanOscTitles(first.pending, 'in.ts\x07more');
+    expect(second.titles).toEqual(['vim main.ts']);
+    expect(second.pending).toBe('');
+  });
+});
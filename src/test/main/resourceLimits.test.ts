@@ -0,0 +1,17 @@
+import { describe, expect, it } from 'vitest';
+import { normalizeResourceLimits } from '../../main/lib/resourceLimits';
+
+describe('normalizeResourceLimits', () => {
+  it('keeps positive limits and floors memory to whole megabytes', () => {
+    expect(normalizeResourceLimits({ maxMemoryMb: 512.7, maxCpuPercent: 150 })).toEqual({
+      maxMemoryMb: 512,
+      maxCpuPercent: 150,
+    });
+  });
+
+  it('returns null when nothing enforceable is left', () => {
+    expect(normalizeResourceLimits(undefined)).toBeNull();
+    expect(normalizeResourceLimits({ maxMemoryMb: 0, maxCpuPercent: -5 })).toBeNull();
+    expect(normalizeResourceLimits({ maxMemoryMb: 'lots' as any })).toBeNull();
+  });
+});
@@ -0,0 +1,54 @@
+import { existsSync, mkdtempSync, readFileSync, utimesSync, writeFileSync } from 'fs';
+import { tmpdir } from 'os';
+import { join } from 'path';
+import { describe, expect, it } from 'vitest';
+import { pruneLogFiles, RotatingLog, rotatedPath } from '../../main/lib/rotatingLog';
+
+describe('RotatingLog', () => {
+  it('rolls over by size and keeps only maxFiles rotated files', async () => {
+    const dir = mkdtempSync(join(tmpdir(), 'emdash-rotlog-'));
+    const file = join(dir, 'session', 'stream.log');
+    const log = new RotatingLog(file, { maxBytes: 10, maxFiles: 2 });
+    for (const chunk of ['aaaaaaaa', 'bbbbbbbb', 'cccccccc', 'dddddddd']) log.write(chunk);
+    await log.end();
+
+    expect(rotatedPath(file, 1)).toBe(join(dir, 'session', 'stream.1.log'));
+    expect(readFileSync(file, 'utf8')).toBe('dddddddd');
+    expect(readFileSync(rotatedPath(file, 1), 'utf8')).toBe('cccccccc');
+    expect(readFileSync(rotatedPath(file, 2), 'utf8')).toBe('bbbbbbbb');
+    expect(existsSync(rotatedPath(file, 3))).toBe(false);
+  });
+
+  it('appends to an existing file across instances', async () => {
+    const dir = mkdtempSync(join(tmpdir(), 'emdash-rotlog-'));
+    const file = join(dir, 'stream.log');
+    const first = new RotatingLog(file, { maxBytes: 1024, maxFiles: 1 });
+    first.write('run 1\n');
+    await first.end();
+    const second = new RotatingLog(file, { maxBytes: 1024, maxFiles: 1 });
+    second.write('run 2\n');
+    await second.end();
+    second.write('ignored after end');
+    expect(readFileSync(file, 'utf8')).toBe('run 1\nrun 2\n');
+  });
+});
+
+describe('pruneLogFiles', () => {
+  it('removes old log files and the directories they leave empty', async () => {
+    const dir = mkdtempSync(join(tmpdir(), 'emdash-rotlog-'));
+    const old = join(dir, 'ws-old', 'stream.log');
+    const fresh = join(dir, 'ws-new', 'stream.log');
+    for (const f of [old, fresh]) {
+      await new RotatingLog(f, { maxBytes: 1024, maxFiles: 1 }).end();
+    }
+    writeFileSync(join(dir, 'notes.txt'), 'kept');
+    const now = Date.now();
+    const longAgo = (now - 10 * 24 * 60 * 60 * 1000) / 1000;
+    utimesSync(old, longAgo, longAgo);
+
+    expect(pruneLogFiles(dir, 7 * 24 * 60 * 60 * 1000, now)).toBe(1);
+    expect(existsSync(join(dir, 'ws-old'))).toBe(false);
+    expect(existsSync(fresh)).toBe(true);
+    expect(existsSync(join(dir, 'notes.txt'))).toBe(true);
+  });
+});
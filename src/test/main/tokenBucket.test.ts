@@ -0,0 +1,19 @@
+import { describe, expect, it } from 'vitest';
+import { TokenBucket } from '../../main/lib/tokenBucket';
+
+describe('TokenBucket', () => {
+  it('allows a burst up to capacity, then refills over time', () => {
+    const bucket = new TokenBucket(2, 2, 0);
+    expect(bucket.take(1, 0)).toBe(true);
+    expect(bucket.take(1, 0)).toBe(true);
+    expect(bucket.take(1, 0)).toBe(false);
+    expect(bucket.take(1, 500)).toBe(true);
+    expect(bucket.take(1, 500)).toBe(false);
+  });
+
+  it('never refills past capacity and takes all or nothing', () => {
+    const bucket = new TokenBucket(10, 10, 0);
+    expect(bucket.take(11, 60_000)).toBe(false);
+    expect(bucket.take(10, 60_000)).toBe(true);
+  });
+});